@@ -0,0 +1,339 @@
+package encoding
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"reflect"
+	"runtime"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// benchRecords returns a LIST of n MAP records, a representative shape for
+// import results that are decoded repeatedly.
+func benchRecords(b *testing.B, n int) *proto.Value {
+	records := make([]interface{}, n)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"id":     i,
+			"name":   fmt.Sprintf("record-%d", i),
+			"status": "active",
+			"tags":   []string{"a", "b", "c"},
+		}
+	}
+
+	v, err := GoToValue(records)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	return v
+}
+
+func BenchmarkValueToGo_records(b *testing.B) {
+	v := benchRecords(b, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ValueToGo(v, nil); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
+
+func BenchmarkValueToSlice(b *testing.B) {
+	ids := make([]int64, 1000)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+
+	v, err := GoToValue(ids)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	b.Run("ValueToGo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ValueToGo(v, reflect.TypeOf(ids)); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+
+	b.Run("ValueToSlice", func(b *testing.B) {
+		var dst []int64
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := ValueToSlice(v, &dst); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+}
+
+func BenchmarkValueToGo_largeMap(b *testing.B) {
+	m := make(map[string]int, 10000)
+	for i := 0; i < 10000; i++ {
+		m[fmt.Sprintf("key-%d", i)] = i
+	}
+
+	v, err := GoToValue(m)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	typ := reflect.TypeOf(m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ValueToGo(v, typ); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
+
+func BenchmarkEncodeStream(b *testing.B) {
+	records := make([]map[string]interface{}, 1000)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"id":   i,
+			"name": fmt.Sprintf("record-%d", i),
+			"tags": []string{"a", "b", "c"},
+		}
+	}
+
+	b.Run("GoToValue", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v, err := GoToValue(records)
+			if err != nil {
+				b.Fatalf("err: %s", err)
+			}
+
+			if _, err := protobuf.Marshal(v); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+
+	b.Run("EncodeStream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := EncodeStream(ioutil.Discard, records); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+}
+
+// benchInt64s and benchStrings are named slice types, which aren't
+// handled by the scalar list fast path, to benchmark the generic path.
+type benchInt64s []int64
+type benchStrings []string
+
+func BenchmarkValueToGo_scalarList(b *testing.B) {
+	const n = 1000000
+	ints := make([]int64, n)
+	strs := make([]string, n)
+	for i := range ints {
+		ints[i] = int64(i)
+		strs[i] = fmt.Sprintf("id-%d", i)
+	}
+
+	intsV, err := GoToValue(ints)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+	strsV, err := GoToValue(strs)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Name string
+		V    *proto.Value
+		Type reflect.Type
+	}{
+		{"ints/fast", intsV, reflect.TypeOf(ints)},
+		{"ints/generic", intsV, reflect.TypeOf(benchInt64s{})},
+		{"strings/fast", strsV, reflect.TypeOf(strs)},
+		{"strings/generic", strsV, reflect.TypeOf(benchStrings{})},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ValueToGo(tc.V, tc.Type); err != nil {
+					b.Fatalf("err: %s", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkValueToGo_stringInterning decodes records with low-cardinality
+// string fields that were unmarshaled from bytes, so that each string is a
+// separate allocation as it would be for a real import result.
+func BenchmarkValueToGo_bigIntList(b *testing.B) {
+	const n = 10000
+	strs := make([]string, n)
+	for i := range strs {
+		x := new(big.Int).Lsh(big.NewInt(int64(i+1)), 200)
+		strs[i] = x.String()
+	}
+
+	v, err := GoToValue(strs)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	typ := reflect.TypeOf([]*big.Int(nil))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ValueToGo(v, typ); err != nil {
+			b.Fatalf("err: %s", err)
+		}
+	}
+}
+
+func BenchmarkValueToGo_stringInterning(b *testing.B) {
+	statuses := []string{"active", "pending", "deleted"}
+	records := make([]interface{}, 1000)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"status": statuses[i%len(statuses)],
+			"type":   "instance",
+		}
+	}
+
+	v, err := GoToValue(records)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	data, err := protobuf.Marshal(v)
+	if err != nil {
+		b.Fatalf("err: %s", err)
+	}
+
+	for _, bc := range []struct {
+		Name string
+		Opts []DecodeOption
+	}{
+		{"default", nil},
+		{"interned", []DecodeOption{WithStringInterning()}},
+	} {
+		b.Run(bc.Name, func(b *testing.B) {
+			decode := func() interface{} {
+				var v proto.Value
+				if err := protobuf.Unmarshal(data, &v); err != nil {
+					b.Fatalf("err: %s", err)
+				}
+
+				result, err := ValueToGo(&v, nil, bc.Opts...)
+				if err != nil {
+					b.Fatalf("err: %s", err)
+				}
+
+				return result
+			}
+
+			// Report the memory still used by a result once the value is
+			// no longer referenced, which is what interning reduces.
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			result := decode()
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(result)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				decode()
+			}
+
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)), "retained-B")
+		})
+	}
+}
+
+func BenchmarkGoToValue_parallel(b *testing.B) {
+	type record struct {
+		ID     int               `sentinel:"id"`
+		Name   string            `sentinel:"name"`
+		Status string            `sentinel:"status"`
+		Tags   []string          `sentinel:"tags"`
+		Labels map[string]string `sentinel:"labels"`
+	}
+
+	records := make([]record, 100000)
+	for i := range records {
+		records[i] = record{
+			ID:     i,
+			Name:   fmt.Sprintf("record-%d", i),
+			Status: "active",
+			Tags:   []string{"a", "b", "c"},
+			Labels: map[string]string{"env": "prod"},
+		}
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := GoToValue(records); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := GoToValue(records, WithParallelEncoding(0)); err != nil {
+				b.Fatalf("err: %s", err)
+			}
+		}
+	})
+}
+
+// benchBuildList builds a LIST of n INT values with values from newValue.
+func benchBuildList(n int, newValue func() *proto.Value) *proto.Value {
+	elems := make([]*proto.Value, n)
+	for i := range elems {
+		v := newValue()
+		v.Type = proto.Value_INT
+		v.Value = &proto.Value_ValueInt{ValueInt: int64(i)}
+		elems[i] = v
+	}
+
+	v := newValue()
+	v.Type = proto.Value_LIST
+	v.Value = &proto.Value_ValueList{ValueList: &proto.Value_List{Elems: elems}}
+	return v
+}
+
+func BenchmarkValuePool(b *testing.B) {
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchBuildList(1000, func() *proto.Value { return new(proto.Value) })
+		}
+	})
+
+	b.Run("pool", func(b *testing.B) {
+		p := NewValuePool()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Put(benchBuildList(1000, p.Get))
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package encoding
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// errorKey is the key of the MAP returned by ErrorValue.
+const errorKey = "error"
+
+// Undefined returns a new UNDEFINED value. This is the value GoToValue
+// returns for sdk.Undefined.
+func Undefined() *proto.Value {
+	return &proto.Value{Type: proto.Value_UNDEFINED}
+}
+
+// Null returns a new NULL value. This is the value GoToValue returns for
+// sdk.Null and nil.
+func Null() *proto.Value {
+	return &proto.Value{Type: proto.Value_NULL}
+}
+
+// ErrorValue returns a MAP with the single key "error" whose value is the
+// message of err, such as {"error": "connection refused"}, or NULL if err
+// is nil. This is the convention for reporting a failure as part of a
+// result, such as one element of a list that couldn't be fetched, so that
+// a policy can inspect it rather than the whole Get failing.
+func ErrorValue(err error) *proto.Value {
+	if err == nil {
+		return Null()
+	}
+
+	return &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{
+				Elems: []*proto.Value_KV{
+					{Key: toValue_string(errorKey), Value: toValue_string(err.Error())},
+				},
+			},
+		},
+	}
+}
+
+// SoftError wraps an error so that GoToValue converts it with ErrorValue
+// rather than as a struct. Errors themselves aren't converted this way
+// since many error types are structs with fields of their own.
+type SoftError struct {
+	Err error
+}
+
+// Error implements error.
+func (e SoftError) Error() string {
+	if e.Err == nil {
+		return "<nil>"
+	}
+
+	return e.Err.Error()
+}
+
+// NewValue converts the Go value to a value. It is the same as GoToValue,
+// and is provided alongside MustValue for building values.
+func NewValue(g interface{}, opts ...EncodeOption) (*proto.Value, error) {
+	return GoToValue(g, opts...)
+}
+
+// MustValue is like NewValue but panics if the value can't be converted.
+// This is intended for tests and values that are known to be valid, such
+// as MustValue(map[string]interface{}{"a": 1}).
+func MustValue(g interface{}, opts ...EncodeOption) *proto.Value {
+	v, err := GoToValue(g, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("encoding.MustValue: %s", err))
+	}
+
+	return v
+}
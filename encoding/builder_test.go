@@ -0,0 +1,131 @@
+package encoding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestBuilders(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    *proto.Value
+		Expected interface{}
+	}{
+		{"undefined", Undefined(), sdk.Undefined},
+		{"null", Null(), sdk.Null},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			// Round trip through the wire format
+			data, err := protobuf.Marshal(tc.Value)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var v proto.Value
+			if err := protobuf.Unmarshal(data, &v); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !Equal(&v, tc.Value) {
+				t.Fatalf("bad: %#v", v)
+			}
+
+			actual, err := ValueToGo(&v, nil)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if actual != tc.Expected {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// Each call returns a new value that can be modified
+	if Null() == Null() {
+		t.Fatal("should return new values")
+	}
+}
+
+func TestErrorValue(t *testing.T) {
+	v := ErrorValue(errors.New("connection refused"))
+	msg, err := Get(v, "error")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s, err := AsString(msg); err != nil || s != "connection refused" {
+		t.Fatalf("bad: %q %v", s, err)
+	}
+
+	if v := ErrorValue(nil); v.Type != proto.Value_NULL {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestSoftError(t *testing.T) {
+	type result struct {
+		Name  string    `sentinel:"name"`
+		Error SoftError `sentinel:"error"`
+	}
+
+	v, err := GoToValue([]result{
+		{Name: "a"},
+		{Name: "b", Error: SoftError{Err: errors.New("timeout")}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Policies see a NULL or an error MAP
+	msg, err := Get(v, 1, "error", "error")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if s, err := AsString(msg); err != nil || s != "timeout" {
+		t.Fatalf("bad: %q %v", s, err)
+	}
+	if ev, err := Get(v, 0, "error"); err != nil || ev.Type != proto.Value_NULL {
+		t.Fatalf("bad: %#v %v", ev, err)
+	}
+
+	// Round trip
+	var actual []result
+	if err := ValueToSlice(v, &actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 2 || actual[0].Error.Err != nil || actual[1].Error.Error() != "timeout" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Maps without an error key don't decode
+	if _, err := ValueToGo(testMapValue("message", "x"), reflect.TypeOf(SoftError{})); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestMustValue(t *testing.T) {
+	v := MustValue(map[string]interface{}{"a": 1})
+	expected, err := NewValue(map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !Equal(v, expected) {
+		t.Fatalf("bad: %s", v)
+	}
+
+	if _, err := NewValue(make(chan int)); err == nil {
+		t.Fatal("should error")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("should panic")
+		}
+	}()
+	MustValue(make(chan int))
+}
@@ -0,0 +1,153 @@
+package encoding
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Canonical returns a copy of v in canonical form: the entries of every
+// MAP, at any depth, are sorted by the serialized bytes of their canonical
+// key. Values that are Equal have the same canonical form, except that
+// FLOAT 0 and -0 are kept distinct, so proto.Marshal of the canonical form
+// is stable and can be used to cache or hash a value by its contents.
+// Entries with duplicate keys keep their relative order.
+//
+// v isn't modified. An error is returned if v, or any value within it, is
+// nil or has no payload.
+func Canonical(v *proto.Value) (*proto.Value, error) {
+	if v == nil {
+		return nil, errors.New("nil value")
+	}
+	if err := checkPayload(v); err != nil {
+		return nil, err
+	}
+
+	switch v.Type {
+	case proto.Value_BOOL:
+		return &proto.Value{
+			Type:  v.Type,
+			Value: &proto.Value_ValueBool{ValueBool: v.GetValueBool()},
+		}, nil
+
+	case proto.Value_INT:
+		return &proto.Value{
+			Type:  v.Type,
+			Value: &proto.Value_ValueInt{ValueInt: v.GetValueInt()},
+		}, nil
+
+	case proto.Value_FLOAT:
+		return &proto.Value{
+			Type:  v.Type,
+			Value: &proto.Value_ValueFloat{ValueFloat: v.GetValueFloat()},
+		}, nil
+
+	case proto.Value_STRING:
+		return toValue_string(v.GetValueString()), nil
+
+	case proto.Value_LIST:
+		elems := v.GetValueList().Elems
+		result := make([]*proto.Value, len(elems))
+		for i, elem := range elems {
+			c, err := Canonical(elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %s", i, err)
+			}
+
+			result[i] = c
+		}
+
+		return &proto.Value{
+			Type: proto.Value_LIST,
+			Value: &proto.Value_ValueList{
+				ValueList: &proto.Value_List{Elems: result},
+			},
+		}, nil
+
+	case proto.Value_MAP:
+		elems := v.GetValueMap().Elems
+		result := make([]*proto.Value_KV, len(elems))
+		keys := make([][]byte, len(elems))
+		for i, elt := range elems {
+			key, err := Canonical(elt.Key)
+			if err != nil {
+				return nil, fmt.Errorf("key %d: %s", i, err)
+			}
+
+			value, err := Canonical(elt.Value)
+			if err != nil {
+				return nil, fmt.Errorf("element for key %s: %s", key.String(), err)
+			}
+
+			keys[i], err = protobuf.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+
+			result[i] = &proto.Value_KV{Key: key, Value: value}
+		}
+
+		sort.Stable(&canonicalElems{elems: result, keys: keys})
+		return &proto.Value{
+			Type: proto.Value_MAP,
+			Value: &proto.Value_ValueMap{
+				ValueMap: &proto.Value_Map{Elems: result},
+			},
+		}, nil
+
+	default:
+		return &proto.Value{Type: v.Type}, nil
+	}
+}
+
+// HashValue returns a hash of the contents of v, for use as a cache key
+// such as when memoizing the results of a function by its arguments. The
+// hash is of the canonical form of v, so values that differ only in the
+// order of their MAP entries have the same hash, and values that are Equal
+// have the same hash except for FLOAT 0 and -0. The hash is the first 64
+// bits of a SHA-256, so collisions are unlikely enough for a cache but it
+// isn't suitable where an attacker could benefit from a collision.
+//
+// The hash is only guaranteed to be the same within a single build of a
+// program. It depends on the serialized form of the canonical value, which
+// may change with new versions of this package or of protobuf, so hashes
+// shouldn't be stored or compared across processes.
+//
+// An error is returned if v can't be made canonical.
+func HashValue(v *proto.Value) (uint64, error) {
+	c, err := Canonical(v)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := protobuf.Marshal(c)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[:8]), nil
+}
+
+// canonicalElems sorts map entries by their serialized keys for Canonical.
+type canonicalElems struct {
+	elems []*proto.Value_KV
+	keys  [][]byte
+}
+
+func (c *canonicalElems) Len() int { return len(c.elems) }
+
+func (c *canonicalElems) Less(i, j int) bool {
+	return bytes.Compare(c.keys[i], c.keys[j]) < 0
+}
+
+func (c *canonicalElems) Swap(i, j int) {
+	c.elems[i], c.elems[j] = c.elems[j], c.elems[i]
+	c.keys[i], c.keys[j] = c.keys[j], c.keys[i]
+}
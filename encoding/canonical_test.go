@@ -0,0 +1,131 @@
+package encoding
+
+import (
+	"bytes"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestCanonical(t *testing.T) {
+	a := testMapValue("b", "2", "a", "1", "c", "3")
+	b := testMapValue("c", "3", "a", "1", "b", "2")
+
+	// Nest the maps so that sorting must be recursive
+	list := func(v *proto.Value) *proto.Value {
+		return &proto.Value{
+			Type: proto.Value_LIST,
+			Value: &proto.Value_ValueList{
+				ValueList: &proto.Value_List{Elems: []*proto.Value{v}},
+			},
+		}
+	}
+
+	ca, err := Canonical(list(a))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cb, err := Canonical(list(b))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	da, err := protobuf.Marshal(ca)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	db, err := protobuf.Marshal(cb)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(da, db) {
+		t.Fatalf("bad: %s != %s", ca, cb)
+	}
+
+	elems := ca.GetValueList().Elems[0].GetValueMap().Elems
+	for i, expected := range []string{"a", "b", "c"} {
+		if actual := elems[i].Key.GetValueString(); actual != expected {
+			t.Fatalf("bad: %d: %s", i, actual)
+		}
+	}
+
+	// The original isn't modified
+	if actual := a.GetValueMap().Elems[0].Key.GetValueString(); actual != "b" {
+		t.Fatalf("bad: %s", actual)
+	}
+	if !Equal(ca, list(a)) {
+		t.Fatalf("bad: %s", ca)
+	}
+}
+
+func TestCanonical_invalid(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Value *proto.Value
+	}{
+		{"nil", nil},
+		{"no payload", &proto.Value{Type: proto.Value_INT}},
+		{
+			"nested no payload",
+			&proto.Value{
+				Type: proto.Value_LIST,
+				Value: &proto.Value_ValueList{
+					ValueList: &proto.Value_List{
+						Elems: []*proto.Value{{Type: proto.Value_STRING}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if _, err := Canonical(tc.Value); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+func TestHashValue(t *testing.T) {
+	hash := func(v *proto.Value) uint64 {
+		h, err := HashValue(v)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return h
+	}
+
+	// Map order doesn't matter
+	a := testMapValue("b", "2", "a", "1")
+	b := testMapValue("a", "1", "b", "2")
+	if hash(a) != hash(b) {
+		t.Fatal("hashes should be equal")
+	}
+
+	// Different contents have different hashes
+	for _, other := range []*proto.Value{
+		testMapValue("a", "1", "b", "3"),
+		testMapValue("a", "1"),
+		toValue_string("a"),
+	} {
+		if hash(other) == hash(a) {
+			t.Fatalf("hashes should differ: %s", other)
+		}
+	}
+
+	// The type is part of the hash
+	one, err := GoToValue(1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hash(one) == hash(toValue_string("1")) {
+		t.Fatal("hashes should differ")
+	}
+
+	if _, err := HashValue(nil); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,814 @@
+package encoding
+
+import (
+	goencoding "encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Converter converts between a specific Go type and Sentinel values. A
+// converter is registered for a type with RegisterConverter and takes
+// precedence over the default conversion for the kind of the type.
+type Converter struct {
+	// Decode converts a value to the registered type. The returned value
+	// must have exactly the registered type. If this is nil, values are
+	// decoded with the default conversion for the kind of the type.
+	Decode func(*proto.Value) (interface{}, error)
+
+	// Encode converts a Go value of the registered type to a value. If
+	// this is nil, values are encoded with the default conversion for
+	// the kind of the type.
+	Encode func(interface{}) (*proto.Value, error)
+}
+
+var (
+	// converters is the map[reflect.Type]*Converter of registered
+	// converters. It is replaced rather than modified when a converter is
+	// registered so that lookups don't need to lock.
+	converters     atomic.Value
+	convertersLock sync.Mutex
+
+	// defaultImpls is the map[reflect.Type]reflect.Type of interface types
+	// to the concrete type to decode into for them. It is replaced rather
+	// than modified in the same way as converters.
+	defaultImpls     atomic.Value
+	defaultImplsLock sync.Mutex
+
+	// discriminated is the map[string]map[string]reflect.Type of
+	// discriminator keys to the discriminator values and the types to
+	// decode into for them. It is replaced rather than modified in the
+	// same way as converters.
+	discriminated     atomic.Value
+	discriminatedLock sync.Mutex
+
+	// validators is the map[reflect.Type]func(interface{}) error of
+	// registered validators. It is replaced rather than modified in the
+	// same way as converters.
+	validators     atomic.Value
+	validatorsLock sync.Mutex
+)
+
+// RegisterConverter registers the converter for the given type, replacing
+// any converter already registered for it. Converters are used by both
+// ValueToGo (when the target type is t) and GoToValue (when converting a
+// value with type t). This is safe to call concurrently but is usually
+// called from an init function.
+//
+// Types without a registered converter that implement
+// encoding.TextMarshaler are encoded to a STRING with MarshalText, and
+// types that implement encoding.TextUnmarshaler are decoded from a STRING
+// with UnmarshalText. Either method may have a pointer receiver.
+//
+// The following converters are registered by default:
+//
+//   - url.URL and *url.URL decode from a STRING with url.Parse and encode
+//     to a STRING with URL.String.
+//
+//   - *regexp.Regexp decodes from a STRING with regexp.Compile, so that
+//     invalid patterns are an error when decoding, and encodes to a STRING
+//     with Regexp.String.
+//
+//   - time.Weekday decodes from an INT (0 for Sunday through 6 for
+//     Saturday) or a STRING with the English name of the day, such as
+//     "Monday", matched case-insensitively.
+//
+//   - time.Month decodes from an INT (1 for January through 12 for
+//     December) or a STRING with the English name of the month, such as
+//     "January", matched case-insensitively.
+//
+//   - os.FileMode decodes from an INT with the mode bits, or a STRING
+//     with the bits in octal such as "0644" or the permissions in
+//     symbolic form such as "rwxr-xr-x", and encodes to a STRING with the
+//     bits in octal.
+//
+//   - *big.Rat decodes exactly from an INT or a STRING with a fraction
+//     such as "1/3" or a decimal such as "0.25", and encodes to a STRING
+//     with Rat.RatString. FLOAT values are rejected since they are
+//     usually already rounded.
+//
+//   - *sync.Map encodes to a MAP using sync.Map.Range. Keys added or
+//     removed during the conversion may or may not be included.
+//
+//   - SoftError encodes with ErrorValue and decodes from a MAP with an
+//     "error" key, or from NULL to a SoftError with a nil Err.
+//
+//   - json.RawMessage decodes from any value with ValueToJSON, so that a
+//     struct field can keep part of a value as JSON to process later, and
+//     encodes with JSONToValue. An empty RawMessage encodes to NULL. Like
+//     other slices, a NULL struct field is left nil.
+func RegisterConverter(t reflect.Type, c Converter) {
+	convertersLock.Lock()
+	defer convertersLock.Unlock()
+
+	// Copy the current map so concurrent lookups see a consistent view
+	old, _ := converters.Load().(map[reflect.Type]*Converter)
+	m := make(map[reflect.Type]*Converter, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+
+	m[t] = &c
+	converters.Store(m)
+}
+
+// RegisterStringEnum registers a converter for the enum type t, such as an
+// int type with constants and a String method generated by stringer, so
+// that it is converted to and from its name rather than its number. A
+// STRING value is decoded by calling parse, which must return a value of
+// exactly the type t or an error for a name that isn't valid, and a value
+// of type t is encoded to a STRING with its name from str. Other types of
+// value can't be decoded into t.
+//
+// This replaces any converter already registered for t, in the same way as
+// RegisterConverter.
+func RegisterStringEnum(
+	t reflect.Type,
+	parse func(string) (interface{}, error),
+	str func(interface{}) string) {
+	RegisterConverter(t, Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			if v.Type != proto.Value_STRING {
+				return nil, convertErr(v, t.String())
+			}
+
+			result, err := parse(v.Value.(*proto.Value_ValueString).ValueString)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", t, err)
+			}
+			if reflect.TypeOf(result) != t {
+				return nil, fmt.Errorf(
+					"internal error: parse for %s returned %T", t, result)
+			}
+
+			return result, nil
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			return toValue_string(str(v)), nil
+		},
+	})
+}
+
+// lookupConverter returns the converter registered for t, or nil if there
+// is none.
+func lookupConverter(t reflect.Type) *Converter {
+	if t == nil {
+		return nil
+	}
+
+	m, _ := converters.Load().(map[reflect.Type]*Converter)
+	return m[t]
+}
+
+// RegisterNamedInts registers a converter for the integer type t, such as
+// a type with int constants, so that it is converted to and from the names
+// in names. A STRING value is decoded into t by looking up the name, which
+// is an error if it isn't in names, and an INT value is decoded as the
+// number directly. A value of type t is encoded to a STRING with its name,
+// or to an INT if it has no name. If several names have the same value,
+// the first of them in sorted order is used when encoding.
+//
+// This replaces any converter already registered for t, in the same way as
+// RegisterConverter. names is copied, so later changes to it have no
+// effect. An error is returned if t isn't an integer type or if a value in
+// names overflows t.
+func RegisterNamedInts(t reflect.Type, names map[string]int64) error {
+	if t == nil {
+		return fmt.Errorf("named ints must be registered for an integer type, got %s", t)
+	}
+
+	var isUint bool
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		isUint = true
+	default:
+		return fmt.Errorf("named ints must be registered for an integer type, got %s", t)
+	}
+
+	// toT converts n to a value of type t, or returns false if n overflows
+	// the type.
+	toT := func(n int64) (interface{}, bool) {
+		result := reflect.New(t).Elem()
+		if isUint {
+			if n < 0 || result.OverflowUint(uint64(n)) {
+				return nil, false
+			}
+
+			result.SetUint(uint64(n))
+		} else {
+			if result.OverflowInt(n) {
+				return nil, false
+			}
+
+			result.SetInt(n)
+		}
+
+		return result.Interface(), true
+	}
+
+	// Sort the names so the name for a value with several is deterministic
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	byName := make(map[string]interface{}, len(names))
+	byValue := make(map[interface{}]string, len(names))
+	for _, name := range sorted {
+		v, ok := toT(names[name])
+		if !ok {
+			return fmt.Errorf("value %d of %q overflows %s", names[name], name, t)
+		}
+
+		byName[name] = v
+		if _, ok := byValue[v]; !ok {
+			byValue[v] = name
+		}
+	}
+
+	RegisterConverter(t, Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			switch v.Type {
+			case proto.Value_STRING:
+				name := v.Value.(*proto.Value_ValueString).ValueString
+				result, ok := byName[name]
+				if !ok {
+					return nil, fmt.Errorf("unknown enum value %q", name)
+				}
+
+				return result, nil
+
+			case proto.Value_INT:
+				n := v.Value.(*proto.Value_ValueInt).ValueInt
+				result, ok := toT(n)
+				if !ok {
+					return nil, fmt.Errorf("value %d overflows %s", n, t)
+				}
+
+				return result, nil
+
+			default:
+				return nil, convertErr(v, t.String())
+			}
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			if name, ok := byValue[v]; ok {
+				return toValue_string(name), nil
+			}
+
+			rv := reflect.ValueOf(v)
+			var n int64
+			if isUint {
+				n = int64(rv.Uint())
+			} else {
+				n = rv.Int()
+			}
+
+			return &proto.Value{
+				Type:  proto.Value_INT,
+				Value: &proto.Value_ValueInt{ValueInt: n},
+			}, nil
+		},
+	})
+
+	return nil
+}
+
+// RegisterDefaultImpl registers concrete as the type to decode into when
+// ValueToGo targets the interface type iface. For example, registering
+// *MyConfig for an interface Config means values decoded into a Config
+// field are decoded as a *MyConfig. This replaces any type already
+// registered for iface.
+//
+// An error is returned if iface isn't an interface type or if concrete
+// doesn't implement it.
+func RegisterDefaultImpl(iface, concrete reflect.Type) error {
+	if iface == nil || iface.Kind() != reflect.Interface {
+		return fmt.Errorf("default implementation must be registered for an interface type, got %s", iface)
+	}
+	if concrete == nil || concrete.Kind() == reflect.Interface {
+		return fmt.Errorf("default implementation for %s must be a concrete type, got %s", iface, concrete)
+	}
+	if !concrete.Implements(iface) {
+		return fmt.Errorf("type %s does not implement %s", concrete, iface)
+	}
+
+	defaultImplsLock.Lock()
+	defer defaultImplsLock.Unlock()
+
+	old, _ := defaultImpls.Load().(map[reflect.Type]reflect.Type)
+	m := make(map[reflect.Type]reflect.Type, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+
+	m[iface] = concrete
+	defaultImpls.Store(m)
+	return nil
+}
+
+// lookupDefaultImpl returns the concrete type registered for the interface
+// type t, or nil if there is none.
+func lookupDefaultImpl(t reflect.Type) reflect.Type {
+	m, _ := defaultImpls.Load().(map[reflect.Type]reflect.Type)
+	return m[t]
+}
+
+// RegisterDiscriminatedType registers t as the type to decode a MAP into
+// when ValueToGo decodes it without a specific target type (into an
+// interface{} or with a nil type) and the MAP has the STRING key key with
+// the STRING value value. For example, registering "kind", "bucket" and
+// *Bucket makes {"kind": "bucket", ...} decode as a *Bucket rather than a
+// map[string]interface{}. When decoding into a non-empty interface type,
+// t is only used if it implements the interface, and it takes precedence
+// over a type registered with RegisterDefaultImpl.
+//
+// If a MAP has several registered discriminators, the first in the order
+// of its entries is used. This replaces any type already registered for
+// the key and value. An error is returned if t is an interface type.
+func RegisterDiscriminatedType(key, value string, t reflect.Type) error {
+	if t == nil || t.Kind() == reflect.Interface {
+		return fmt.Errorf("discriminated type for %s=%q must be a concrete type, got %s", key, value, t)
+	}
+
+	discriminatedLock.Lock()
+	defer discriminatedLock.Unlock()
+
+	old, _ := discriminated.Load().(map[string]map[string]reflect.Type)
+	m := make(map[string]map[string]reflect.Type, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+
+	values := make(map[string]reflect.Type, len(m[key])+1)
+	for k, v := range m[key] {
+		values[k] = v
+	}
+	values[value] = t
+	m[key] = values
+
+	discriminated.Store(m)
+	return nil
+}
+
+// lookupDiscriminatedType returns the type registered with
+// RegisterDiscriminatedType for the MAP raw, or nil if there is none.
+func lookupDiscriminatedType(raw *proto.Value) reflect.Type {
+	m, _ := discriminated.Load().(map[string]map[string]reflect.Type)
+	if len(m) == 0 {
+		return nil
+	}
+
+	for _, elt := range raw.Value.(*proto.Value_ValueMap).ValueMap.Elems {
+		k, ok := elt.GetKey().GetValue().(*proto.Value_ValueString)
+		if !ok {
+			continue
+		}
+		values, ok := m[k.ValueString]
+		if !ok {
+			continue
+		}
+		v, ok := elt.GetValue().GetValue().(*proto.Value_ValueString)
+		if !ok {
+			continue
+		}
+
+		if t, ok := values[v.ValueString]; ok {
+			return t
+		}
+	}
+
+	return nil
+}
+
+// RegisterValidator registers f to validate every value decoded into the
+// type t by ValueToGo and the other decoding functions, at any depth. f is
+// called with the decoded value, which has exactly the type t, and an
+// error from f is returned as the error for that value, with the same
+// context as any other error decoding it. This is useful for invariants
+// of a type, such as a port number being between 1 and 65535, that would
+// otherwise need checking everywhere the type is decoded.
+//
+// This replaces any validator already registered for t, and a nil f
+// removes it. Like RegisterConverter, this is safe to call concurrently
+// but is usually called from an init function.
+func RegisterValidator(t reflect.Type, f func(interface{}) error) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+
+	old, _ := validators.Load().(map[reflect.Type]func(interface{}) error)
+	m := make(map[reflect.Type]func(interface{}) error, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+
+	if f == nil {
+		delete(m, t)
+	} else {
+		m[t] = f
+	}
+	validators.Store(m)
+}
+
+// lookupValidator returns the validator registered for t, or nil if there
+// is none.
+func lookupValidator(t reflect.Type) func(interface{}) error {
+	m, _ := validators.Load().(map[reflect.Type]func(interface{}) error)
+	if len(m) == 0 || t == nil {
+		return nil
+	}
+
+	return m[t]
+}
+
+func init() {
+	RegisterConverter(reflect.TypeOf(url.URL{}), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			u, err := convertValueURL(v)
+			if err != nil {
+				return nil, err
+			}
+			if u == nil {
+				return nil, convertErr(v, "URL")
+			}
+
+			return *u, nil
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			u := v.(url.URL)
+			return toValue_string(u.String()), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(&url.URL{}), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			return convertValueURL(v)
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			u := v.(*url.URL)
+			if u == nil {
+				return &proto.Value{Type: proto.Value_NULL}, nil
+			}
+
+			return toValue_string(u.String()), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(&regexp.Regexp{}), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			return convertValueRegexp(v)
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			re := v.(*regexp.Regexp)
+			if re == nil {
+				return &proto.Value{Type: proto.Value_NULL}, nil
+			}
+
+			return toValue_string(re.String()), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(time.Sunday), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			n, err := convertValueNamedInt(v, "weekday",
+				int(time.Sunday), int(time.Saturday), weekdayName)
+			return time.Weekday(n), err
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(time.January), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			n, err := convertValueNamedInt(v, "month",
+				int(time.January), int(time.December), monthName)
+			return time.Month(n), err
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(&big.Rat{}), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			return convertValueRat(v)
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			r := v.(*big.Rat)
+			if r == nil {
+				return &proto.Value{Type: proto.Value_NULL}, nil
+			}
+
+			return toValue_string(r.RatString()), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(&sync.Map{}), Converter{
+		Encode: func(v interface{}) (*proto.Value, error) {
+			m := v.(*sync.Map)
+			if m == nil {
+				return &proto.Value{Type: proto.Value_NULL}, nil
+			}
+
+			return toValue_syncMap(m)
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(os.FileMode(0)), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			return convertValueFileMode(v)
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			return toValue_string(fmt.Sprintf("%#o", uint32(v.(os.FileMode)))), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(SoftError{}), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			switch v.Type {
+			case proto.Value_NULL:
+				return SoftError{}, nil
+
+			case proto.Value_MAP:
+				msg, err := Get(v, errorKey)
+				if err != nil {
+					return nil, err
+				}
+
+				s, err := AsString(msg)
+				if err != nil {
+					return nil, fmt.Errorf("key %q: %s", errorKey, err)
+				}
+
+				return SoftError{Err: errors.New(s)}, nil
+
+			default:
+				return nil, convertErr(v, "error")
+			}
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			return ErrorValue(v.(SoftError).Err), nil
+		},
+	})
+
+	RegisterConverter(reflect.TypeOf(json.RawMessage(nil)), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			data, err := ValueToJSON(v)
+			if err != nil {
+				return nil, err
+			}
+
+			return json.RawMessage(data), nil
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			data := v.(json.RawMessage)
+			if len(data) == 0 {
+				return &proto.Value{Type: proto.Value_NULL}, nil
+			}
+
+			return JSONToValue(data)
+		},
+	})
+}
+
+var (
+	textMarshalerTyp   = reflect.TypeOf((*goencoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerTyp = reflect.TypeOf((*goencoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// textConverter encodes values that implement encoding.TextMarshaler.
+// It is used for types that have no registered converter.
+var textConverter = &Converter{
+	Encode: func(v interface{}) (*proto.Value, error) {
+		text, err := v.(goencoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %T as text: %s", v, err)
+		}
+
+		return toValue_string(string(text)), nil
+	},
+}
+
+// textMarshaler returns the value to encode with textConverter if v
+// implements encoding.TextMarshaler, either directly or with a pointer
+// receiver. In the latter case, this is a pointer to a copy of v since v
+// may not be addressable. Nil pointers and interfaces aren't marshaled
+// so that they are converted as usual.
+func textMarshaler(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	if !v.CanInterface() || t.Kind() == reflect.Interface {
+		return v, false
+	}
+
+	if t.Implements(textMarshalerTyp) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			return v, false
+		}
+
+		return v, true
+	}
+
+	if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textMarshalerTyp) {
+		ptr := reflect.New(t)
+		ptr.Elem().Set(v)
+		return ptr, true
+	}
+
+	return v, false
+}
+
+// unmarshalText decodes the STRING raw into the type t with UnmarshalText
+// if t or a pointer to t implements encoding.TextUnmarshaler. It returns
+// false if it doesn't.
+func unmarshalText(raw *proto.Value, t reflect.Type) (interface{}, bool, error) {
+	if t.Kind() == reflect.Interface {
+		return nil, false, nil
+	}
+
+	var ptr reflect.Value
+	switch {
+	case t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerTyp):
+		ptr = reflect.New(t.Elem())
+
+	case t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshalerTyp):
+		ptr = reflect.New(t)
+
+	default:
+		return nil, false, nil
+	}
+
+	s := raw.Value.(*proto.Value_ValueString).ValueString
+	if err := ptr.Interface().(goencoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		return nil, true, fmt.Errorf("invalid %s: %s", t, err)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return ptr.Interface(), true, nil
+	}
+
+	return ptr.Elem().Interface(), true, nil
+}
+
+// convertValueURL converts a STRING to a URL. A NULL value is converted to
+// a nil URL.
+func convertValueURL(raw *proto.Value) (*url.URL, error) {
+	switch raw.Type {
+	case proto.Value_STRING:
+		u, err := url.Parse(raw.Value.(*proto.Value_ValueString).ValueString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL: %s", err)
+		}
+
+		return u, nil
+
+	case proto.Value_NULL:
+		return nil, nil
+
+	default:
+		return nil, convertErr(raw, "URL")
+	}
+}
+
+// convertValueRegexp compiles a STRING to a Regexp. A NULL value is
+// converted to a nil Regexp.
+func convertValueRegexp(raw *proto.Value) (*regexp.Regexp, error) {
+	switch raw.Type {
+	case proto.Value_STRING:
+		re, err := regexp.Compile(raw.Value.(*proto.Value_ValueString).ValueString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp: %s", err)
+		}
+
+		return re, nil
+
+	case proto.Value_NULL:
+		return nil, nil
+
+	default:
+		return nil, convertErr(raw, "regexp")
+	}
+}
+
+// convertValueRat converts an INT or a STRING to a Rat. A NULL value is
+// converted to a nil Rat.
+func convertValueRat(raw *proto.Value) (*big.Rat, error) {
+	switch raw.Type {
+	case proto.Value_INT:
+		return new(big.Rat).SetInt64(raw.Value.(*proto.Value_ValueInt).ValueInt), nil
+
+	case proto.Value_STRING:
+		s := raw.Value.(*proto.Value_ValueString).ValueString
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid rational number %q", s)
+		}
+
+		return r, nil
+
+	case proto.Value_NULL:
+		return nil, nil
+
+	default:
+		return nil, convertErr(raw, "rational number")
+	}
+}
+
+// convertValueNamedInt converts an INT in the range [min, max] or a STRING
+// naming one of the integers in that range to the integer. name is used
+// to return the name of each integer.
+func convertValueNamedInt(
+	raw *proto.Value, kind string, min, max int, name func(int) string) (int, error) {
+	switch raw.Type {
+	case proto.Value_INT:
+		value := raw.Value.(*proto.Value_ValueInt).ValueInt
+		if value < int64(min) || value > int64(max) {
+			return 0, fmt.Errorf(
+				"%s %d out of range, must be between %d and %d", kind, value, min, max)
+		}
+
+		return int(value), nil
+
+	case proto.Value_STRING:
+		s := raw.Value.(*proto.Value_ValueString).ValueString
+		for i := min; i <= max; i++ {
+			if strings.EqualFold(s, name(i)) {
+				return i, nil
+			}
+		}
+
+		return 0, fmt.Errorf("unknown %s %q", kind, s)
+
+	default:
+		return 0, convertErr(raw, kind)
+	}
+}
+
+// convertValueFileMode converts an INT or a STRING in octal or symbolic
+// form to an os.FileMode.
+func convertValueFileMode(raw *proto.Value) (os.FileMode, error) {
+	switch raw.Type {
+	case proto.Value_INT:
+		value := raw.Value.(*proto.Value_ValueInt).ValueInt
+		if value < 0 || value > math.MaxUint32 {
+			return 0, fmt.Errorf("file mode %d out of range", value)
+		}
+
+		return os.FileMode(value), nil
+
+	case proto.Value_STRING:
+		s := raw.Value.(*proto.Value_ValueString).ValueString
+		if mode, ok := parseSymbolicMode(s); ok {
+			return mode, nil
+		}
+
+		value, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf(
+				"invalid file mode %q, must be octal such as \"0644\" or symbolic such as \"rwxr-xr-x\"", s)
+		}
+
+		return os.FileMode(value), nil
+
+	default:
+		return 0, convertErr(raw, "file mode")
+	}
+}
+
+// parseSymbolicMode parses permissions in the form "rwxr-xr-x", optionally
+// with a leading "-" as printed by os.FileMode.String for a regular file.
+func parseSymbolicMode(s string) (os.FileMode, bool) {
+	if len(s) == 10 && s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) != 9 {
+		return 0, false
+	}
+
+	var mode os.FileMode
+	for i := 0; i < 9; i++ {
+		mode <<= 1
+		switch s[i] {
+		case "rwx"[i%3]:
+			mode |= 1
+		case '-':
+		default:
+			return 0, false
+		}
+	}
+
+	return mode, true
+}
+
+func weekdayName(i int) string { return time.Weekday(i).String() }
+func monthName(i int) string   { return time.Month(i).String() }
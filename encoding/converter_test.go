@@ -0,0 +1,1121 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// testUpper is a type with a converter registered in the tests.
+type testUpper string
+
+func init() {
+	RegisterConverter(reflect.TypeOf(testUpper("")), Converter{
+		Decode: func(v *proto.Value) (interface{}, error) {
+			if v.Type != proto.Value_STRING {
+				return nil, errors.New("expected string")
+			}
+
+			return testUpper(strings.ToUpper(v.GetValueString())), nil
+		},
+		Encode: func(v interface{}) (*proto.Value, error) {
+			return toValue_string(strings.ToLower(string(v.(testUpper)))), nil
+		},
+	})
+}
+
+func TestRegisterConverter(t *testing.T) {
+	// Encode uses the converter
+	v, err := GoToValue(map[string]interface{}{"key": testUpper("FOO")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Decode uses the converter, including for nested values
+	actual, err := ValueToGo(v, reflect.TypeOf(map[string]testUpper{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]testUpper{"key": "FOO"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The encoded value went through the encoder
+	str, err := ValueToGo(v, reflect.TypeOf(map[string]string{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(str, map[string]string{"key": "foo"}) {
+		t.Fatalf("bad: %#v", str)
+	}
+
+	// Errors from the converter are returned
+	if _, err := ValueToGo(&proto.Value{
+		Type:  proto.Value_INT,
+		Value: &proto.Value_ValueInt{ValueInt: 42},
+	}, reflect.TypeOf(testUpper(""))); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestGoToValue_syncMap(t *testing.T) {
+	var m sync.Map
+	m.Store("foo", 1)
+	m.Store("bar", 2)
+
+	v, err := GoToValue(&m)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]int64{"foo": 1, "bar": 2}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestGoToValue_syncMapConcurrent(t *testing.T) {
+	var m sync.Map
+	for i := 0; i < 100; i++ {
+		m.Store(i, i)
+	}
+
+	// Modify the map while converting it. This must not crash.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			m.Store(i%200, i)
+			m.Delete((i + 100) % 200)
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		if _, err := GoToValue(&m); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	<-done
+}
+
+func TestValueToSyncMap(t *testing.T) {
+	var m sync.Map
+	m.Store("existing", int64(0))
+
+	v := testMapValue("foo", "1", "bar", "2")
+	if err := ValueToSyncMap(v, reflect.TypeOf(""), reflect.TypeOf(int64(0)), &m); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual := map[interface{}]interface{}{}
+	m.Range(func(k, v interface{}) bool {
+		actual[k] = v
+		return true
+	})
+
+	expected := map[interface{}]interface{}{
+		"existing": int64(0),
+		"foo":      int64(1),
+		"bar":      int64(2),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// A bad entry errors with its key and nothing is stored
+	var bad sync.Map
+	v = testMapValue("foo", "1", "bar", "nope")
+	err := ValueToSyncMap(v, reflect.TypeOf(""), reflect.TypeOf(int64(0)), &bad)
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if !strings.Contains(err.Error(), "bar") {
+		t.Fatalf("bad: %s", err)
+	}
+	bad.Range(func(k, v interface{}) bool {
+		t.Fatalf("bad: stored %#v", k)
+		return false
+	})
+
+	// Values that aren't maps error
+	if err := ValueToSyncMap(toValue_string("foo"), nil, nil, &bad); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestPairsToMap(t *testing.T) {
+	pairs := func(elems ...interface{}) *proto.Value {
+		v, err := GoToValue(elems)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		return v
+	}
+
+	cases := []struct {
+		Name     string
+		Value    *proto.Value
+		KeyTyp   reflect.Type
+		ElemTyp  reflect.Type
+		Expected interface{}
+		Err      string
+	}{
+		{
+			"typed",
+			pairs([]interface{}{"a", 1}, []interface{}{"b", 2}),
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			map[string]int{"a": 1, "b": 2},
+			"",
+		},
+
+		{
+			"nil types",
+			pairs([]interface{}{"a", 1}),
+			nil, nil,
+			map[interface{}]interface{}{"a": int64(1)},
+			"",
+		},
+
+		{
+			"empty",
+			pairs(),
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			map[string]int{},
+			"",
+		},
+
+		{
+			"not a pair",
+			pairs([]interface{}{"a", 1}, []interface{}{"b"}),
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			nil,
+			"element 1: expected a LIST of 2 elements, got 1 elements",
+		},
+
+		{
+			"not a list",
+			pairs([]interface{}{"a", 1}, "b"),
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			nil,
+			"element 1: expected a LIST of 2 elements, got STRING",
+		},
+
+		{
+			"bad value",
+			pairs([]interface{}{"a", true}),
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			nil,
+			"element 0: element for key ",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := PairsToMap(tc.Value, tc.KeyTyp, tc.ElemTyp)
+			if tc.Err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.Err) {
+					t.Fatalf("bad: %v", err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// Values that aren't lists error
+	if _, err := PairsToMap(testMapValue("a", "1"), nil, nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestFileMode(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected os.FileMode
+		Err      bool
+	}{
+		{"int", 0644, 0644, false},
+		{"octal", "0644", 0644, false},
+		{"octal without zero", "755", 0755, false},
+		{"octal prefix", "0o600", 0600, false},
+		{"symbolic", "rwxr-xr-x", 0755, false},
+		{"symbolic regular file", "-rw-r--r--", 0644, false},
+		{"symbolic none", "---------", 0, false},
+		{"negative", -1, 0, true},
+		{"not octal", "0999", 0, true},
+		{"garbage", "rwzr-xr-x", 0, true},
+		{"bool", true, 0, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(os.FileMode(0)))
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err == nil && actual != tc.Expected {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// Encodes to octal and round trips
+	encoded := map[os.FileMode]string{
+		0644:              "0644",
+		0:                 "0",
+		os.ModeDir | 0755: "020000000755",
+	}
+	for mode, expected := range encoded {
+		v, err := GoToValue(mode)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if v.GetValueString() != expected {
+			t.Fatalf("bad: %s", v)
+		}
+
+		actual, err := ValueToGo(v, reflect.TypeOf(mode))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if actual != mode {
+			t.Fatalf("bad: %s", actual)
+		}
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	type event struct {
+		ID      int             `sentinel:"id"`
+		Kind    string          `sentinel:"kind"`
+		Payload json.RawMessage `sentinel:"payload"`
+		Extra   json.RawMessage `sentinel:"extra"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"id":   1,
+		"kind": "push",
+		"payload": map[string]interface{}{
+			"ref":     "main",
+			"commits": []interface{}{1, 2.5, nil},
+		},
+		"extra": nil,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(event{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	e := actual.(event)
+	if e.ID != 1 || e.Kind != "push" || e.Extra != nil {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{
+		"ref":     "main",
+		"commits": []interface{}{1.0, 2.5, nil},
+	}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Fatalf("bad: %s", e.Payload)
+	}
+
+	// Encoding converts the JSON back
+	v2, err := GoToValue(e)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m, err := ValueToGo(v2, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p := m.(map[string]interface{})["payload"]; !reflect.DeepEqual(p, map[string]interface{}{
+		"ref":     "main",
+		"commits": []interface{}{int64(1), 2.5, sdk.Null},
+	}) {
+		t.Fatalf("bad: %#v", p)
+	}
+	if m.(map[string]interface{})["extra"] != sdk.Null {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	// Invalid JSON can't be encoded
+	if _, err := GoToValue(json.RawMessage("{")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// testColor is a stringer-style enum registered with RegisterStringEnum.
+type testColor int
+
+const (
+	testRed testColor = iota
+	testGreen
+)
+
+func (c testColor) String() string {
+	switch c {
+	case testRed:
+		return "red"
+	case testGreen:
+		return "green"
+	default:
+		return fmt.Sprintf("testColor(%d)", int(c))
+	}
+}
+
+func parseTestColor(s string) (testColor, error) {
+	switch s {
+	case "red":
+		return testRed, nil
+	case "green":
+		return testGreen, nil
+	default:
+		return 0, fmt.Errorf("unknown color %q", s)
+	}
+}
+
+func TestRegisterStringEnum(t *testing.T) {
+	typ := reflect.TypeOf(testColor(0))
+	RegisterStringEnum(typ, func(s string) (interface{}, error) {
+		return parseTestColor(s)
+	}, func(v interface{}) string {
+		return v.(testColor).String()
+	})
+	defer RegisterConverter(typ, Converter{})
+
+	type paint struct {
+		Colors []testColor `sentinel:"colors"`
+	}
+
+	v, err := GoToValue(paint{Colors: []testColor{testGreen, testRed}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{"colors": []string{"green", "red"}}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(paint{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, paint{Colors: []testColor{testGreen, testRed}}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Unknown names and values that aren't STRINGs are errors
+	for _, source := range []interface{}{"blue", 1} {
+		v, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := ValueToGo(v, typ); err == nil {
+			t.Fatalf("should error: %v", source)
+		}
+	}
+
+	// parse must return the registered type
+	RegisterStringEnum(typ, func(s string) (interface{}, error) {
+		return 0, nil
+	}, func(v interface{}) string {
+		return v.(testColor).String()
+	})
+
+	_, err = ValueToGo(toValue_string("red"), typ)
+	if err == nil || !strings.Contains(err.Error(), "returned int") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestRegisterNamedInts(t *testing.T) {
+	type level int8
+	typ := reflect.TypeOf(level(0))
+	err := RegisterNamedInts(typ, map[string]int64{
+		"DEBUG": 0,
+		"INFO":  1,
+		"WARN":  2,
+		"ERROR": 3,
+		"FATAL": 3,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer RegisterConverter(typ, Converter{})
+
+	type config struct {
+		Levels []level `sentinel:"levels"`
+	}
+
+	// Names decode by name and numbers decode directly
+	v, err := GoToValue(map[string]interface{}{
+		"levels": []interface{}{"WARN", 1, "DEBUG"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err := ValueToGo(v, reflect.TypeOf(config{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, config{Levels: []level{2, 1, 0}}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Values encode by name if they have one, using the first name in
+	// sorted order for duplicates
+	v, err = GoToValue([]level{1, 3, 42})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	encoded, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(encoded, []interface{}{"INFO", "ERROR", int64(42)}) {
+		t.Fatalf("bad: %#v", encoded)
+	}
+
+	// Unknown names, numbers that overflow and other types are errors
+	for _, source := range []interface{}{"TRACE", 300, true} {
+		v, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := ValueToGo(v, typ); err == nil {
+			t.Fatalf("should error: %v", source)
+		}
+	}
+
+	_, err = ValueToGo(toValue_string("FOO"), typ)
+	if err == nil || err.Error() != `unknown enum value "FOO"` {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Registration errors
+	if err := RegisterNamedInts(reflect.TypeOf(""), nil); err == nil {
+		t.Fatal("should error for non-integer type")
+	}
+	if err := RegisterNamedInts(reflect.TypeOf(uint8(0)), map[string]int64{"A": -1}); err == nil {
+		t.Fatal("should error for overflow")
+	}
+}
+
+func TestBigRat(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected string
+		Err      bool
+	}{
+		{"rat", big.NewRat(1, 4), "1/4", false},
+		{"repeating rat", big.NewRat(1, 3), "1/3", false},
+		{"whole rat", big.NewRat(6, 3), "2", false},
+		{"negative rat", big.NewRat(-2, 7), "-2/7", false},
+		{"int", 42, "42", false},
+		{"fraction string", "1/3", "1/3", false},
+		{"decimal string", "0.25", "1/4", false},
+		{"long decimal string", "0.1000000000000000000001", "1000000000000000000001/10000000000000000000000", false},
+		{"invalid string", "one third", "", true},
+		{"float", 0.25, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(&big.Rat{}))
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			r := actual.(*big.Rat)
+			if r.RatString() != tc.Expected {
+				t.Fatalf("bad: %s", r.RatString())
+			}
+
+			// The decoded value must encode back to the same value
+			v, err = GoToValue(r)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if v.GetValueString() != tc.Expected {
+				t.Fatalf("bad: %#v", v)
+			}
+		})
+	}
+}
+
+func TestBigRat_null(t *testing.T) {
+	v, err := GoToValue((*big.Rat)(nil))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Type != proto.Value_NULL {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(&big.Rat{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.(*big.Rat) != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	type rule struct {
+		Pattern *regexp.Regexp `sentinel:"pattern"`
+		Nil     *regexp.Regexp `sentinel:"nil"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"pattern": `^ami-[0-9a-f]{8}$`,
+		"nil":     nil,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(rule{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := actual.(rule)
+	if r.Nil != nil || !r.Pattern.MatchString("ami-0123abcd") || r.Pattern.MatchString("ami-xyz") {
+		t.Fatalf("bad: %#v", r)
+	}
+
+	// Encoding returns the pattern
+	v, err = GoToValue(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{"pattern": `^ami-[0-9a-f]{8}$`, "nil": sdk.Null}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	// Invalid patterns return the compile error
+	_, err = ValueToGo(toValue_string("a(b"), reflect.TypeOf(&regexp.Regexp{}))
+	if err == nil || !strings.Contains(err.Error(), "invalid regexp: error parsing regexp: missing closing )") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Values that aren't STRINGs are errors
+	v, err = GoToValue(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ValueToGo(v, reflect.TypeOf(&regexp.Regexp{})); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// testShape is an interface with a default implementation registered in
+// the tests.
+type testShape interface {
+	Area() float64
+}
+
+type testSquare struct {
+	Side float64 `sentinel:"side"`
+}
+
+func (s *testSquare) Area() float64 { return s.Side * s.Side }
+
+func init() {
+	if err := RegisterDefaultImpl(
+		reflect.TypeOf((*testShape)(nil)).Elem(),
+		reflect.TypeOf(&testSquare{})); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterDefaultImpl(t *testing.T) {
+	type container struct {
+		Shape  testShape   `sentinel:"shape"`
+		Shapes []testShape `sentinel:"shapes"`
+		Empty  testShape   `sentinel:"empty"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"shape":  map[string]interface{}{"side": 2.0},
+		"shapes": []interface{}{map[string]interface{}{"side": 3.0}},
+		"empty":  nil,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(container{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := container{
+		Shape:  &testSquare{Side: 2},
+		Shapes: []testShape{&testSquare{Side: 3}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestRegisterDefaultImpl_invalid(t *testing.T) {
+	shape := reflect.TypeOf((*testShape)(nil)).Elem()
+	cases := []struct {
+		Name            string
+		Iface, Concrete reflect.Type
+	}{
+		{"not an interface", reflect.TypeOf(""), reflect.TypeOf(&testSquare{})},
+		{"interface concrete", shape, shape},
+		{"not implemented", shape, reflect.TypeOf(testSquare{})},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if err := RegisterDefaultImpl(tc.Iface, tc.Concrete); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+type testCircle struct {
+	Kind   string  `sentinel:"testKind"`
+	Radius float64 `sentinel:"radius"`
+}
+
+func (c *testCircle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type testLabel struct {
+	Text string `sentinel:"text"`
+}
+
+func init() {
+	if err := RegisterDiscriminatedType(
+		"testKind", "circle", reflect.TypeOf(&testCircle{})); err != nil {
+		panic(err)
+	}
+	if err := RegisterDiscriminatedType(
+		"testKind", "label", reflect.TypeOf(testLabel{})); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterDiscriminatedType(t *testing.T) {
+	v, err := GoToValue([]interface{}{
+		map[string]interface{}{"testKind": "circle", "radius": 2.0},
+		map[string]interface{}{"testKind": "label", "text": "hi"},
+		map[string]interface{}{"testKind": "other"},
+		map[string]interface{}{"text": "plain"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []interface{}{
+		&testCircle{Kind: "circle", Radius: 2},
+		testLabel{Text: "hi"},
+		map[string]string{"testKind": "other"},
+		map[string]string{"text": "plain"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestRegisterDiscriminatedType_interface(t *testing.T) {
+	type container struct {
+		Shapes []testShape `sentinel:"shapes"`
+	}
+
+	// The discriminator takes precedence over the default implementation,
+	// but only if the type implements the interface.
+	v, err := GoToValue(map[string]interface{}{
+		"shapes": []interface{}{
+			map[string]interface{}{"testKind": "circle", "radius": 1.0},
+			map[string]interface{}{"testKind": "label", "side": 2.0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(container{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := container{Shapes: []testShape{
+		&testCircle{Kind: "circle", Radius: 1},
+		&testSquare{Side: 2},
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestRegisterDiscriminatedType_invalid(t *testing.T) {
+	shape := reflect.TypeOf((*testShape)(nil)).Elem()
+	if err := RegisterDiscriminatedType("testKind", "shape", shape); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// testPort has a validator registered in TestRegisterValidator.
+type testPort int
+
+func TestRegisterValidator(t *testing.T) {
+	typ := reflect.TypeOf(testPort(0))
+	RegisterValidator(typ, func(v interface{}) error {
+		if p := v.(testPort); p < 1 || p > 65535 {
+			return fmt.Errorf("port %d out of range", p)
+		}
+
+		return nil
+	})
+	defer RegisterValidator(typ, nil)
+
+	type config struct {
+		Ports []testPort `sentinel:"ports"`
+		Admin *testPort  `sentinel:"admin"`
+	}
+
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected interface{}
+		Err      string
+	}{
+		{"valid", 80, testPort(80), ""},
+		{"invalid", 0, testPort(0), "port 0 out of range"},
+		{"list", []int{80, 443}, []testPort{80, 443}, ""},
+		{"list invalid", []int{80, 70000}, []testPort{}, "element 1: port 70000 out of range"},
+		{
+			"struct invalid",
+			map[string]interface{}{"ports": []int{80}, "admin": 0},
+			config{},
+			"field Admin: port 0 out of range",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(tc.Expected))
+			if tc.Err != "" {
+				if err == nil || err.Error() != tc.Err {
+					t.Fatalf("bad: %v", err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// Errors include the path when errors are verbose
+	v, err := GoToValue(map[string]interface{}{"ports": []int{0}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ValueToGo(v, reflect.TypeOf(config{}), WithErrorVerbosity(ErrorsVerbose))
+	if err == nil || err.Error() != "ports[0]: port 0 out of range" {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Removing the validator stops validation
+	RegisterValidator(typ, nil)
+	v, err = GoToValue(0)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := ValueToGo(v, typ); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testCSV implements encoding.TextMarshaler with a value receiver and
+// encoding.TextUnmarshaler with a pointer receiver, like most types.
+type testCSV []string
+
+func (c testCSV) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(c, ",")), nil
+}
+
+func (c *testCSV) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return errors.New("empty")
+	}
+
+	*c = strings.Split(string(text), ",")
+	return nil
+}
+
+// testPtrText implements both methods with pointer receivers.
+type testPtrText struct {
+	Value string
+}
+
+func (p *testPtrText) MarshalText() ([]byte, error) {
+	return []byte("<" + p.Value + ">"), nil
+}
+
+func (p *testPtrText) UnmarshalText(text []byte) error {
+	p.Value = strings.Trim(string(text), "<>")
+	return nil
+}
+
+func TestTextMarshaler(t *testing.T) {
+	type record struct {
+		CSV     testCSV      `sentinel:"csv"`
+		IP      net.IP       `sentinel:"ip"`
+		Time    time.Time    `sentinel:"time"`
+		Text    testPtrText  `sentinel:"text"`
+		PtrText *testPtrText `sentinel:"ptr_text"`
+		Nil     *testPtrText `sentinel:"nil"`
+	}
+
+	source := record{
+		CSV:     testCSV{"a", "b"},
+		IP:      net.ParseIP("10.0.0.1"),
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Text:    testPtrText{"x"},
+		PtrText: &testPtrText{"y"},
+	}
+
+	v, err := GoToValue(source)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Encoded values are STRINGs, except for nil pointers
+	m, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{
+		"csv":      "a,b",
+		"ip":       "10.0.0.1",
+		"time":     "2020-01-02T03:04:05Z",
+		"text":     "<x>",
+		"ptr_text": "<y>",
+		"nil":      sdk.Null,
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(record{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, source) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// testDecimal is a fixed-point decimal like shopspring's decimal.Decimal,
+// which is decoded only through encoding.TextUnmarshaler.
+type testDecimal struct {
+	coef  int64
+	scale int
+}
+
+func (d testDecimal) MarshalText() ([]byte, error) {
+	s := strconv.FormatInt(d.coef, 10)
+	if d.scale == 0 {
+		return []byte(s), nil
+	}
+
+	for len(s) <= d.scale {
+		s = "0" + s
+	}
+
+	return []byte(s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]), nil
+}
+
+func (d *testDecimal) UnmarshalText(text []byte) error {
+	s := string(text)
+	scale := 0
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		scale = len(s) - idx - 1
+		s = s[:idx] + s[idx+1:]
+	}
+
+	coef, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	d.coef, d.scale = coef, scale
+	return nil
+}
+
+func TestTextUnmarshaler_decimal(t *testing.T) {
+	type invoice struct {
+		Total testDecimal   `sentinel:"total"`
+		Lines []testDecimal `sentinel:"lines"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"total": "10.50",
+		"lines": []string{"0.05", "10.45"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(invoice{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := invoice{
+		Total: testDecimal{1050, 2},
+		Lines: []testDecimal{{5, 2}, {1045, 2}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Round trip keeps every digit
+	v, err = GoToValue(actual)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if total := m.(map[string]interface{})["total"]; total != "10.50" {
+		t.Fatalf("bad: %#v", total)
+	}
+
+	actual, err = ValueToGo(v, reflect.TypeOf(invoice{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Invalid strings and numbers that aren't strings are errors
+	for _, source := range []interface{}{"ten", 10.5} {
+		v, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := ValueToGo(v, reflect.TypeOf(testDecimal{})); err == nil {
+			t.Fatalf("should error: %v", source)
+		}
+	}
+}
+
+func TestTextUnmarshaler_error(t *testing.T) {
+	v, err := GoToValue("")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ValueToGo(v, reflect.TypeOf(testCSV{}))
+	if err == nil || !strings.Contains(err.Error(), "invalid encoding.testCSV") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Other types of values are decoded as usual
+	v, err = GoToValue([]string{"a"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(testCSV{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, testCSV{"a"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,158 @@
+package encoding
+
+import (
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// DiffKind is the kind of a difference found by Diff.
+type DiffKind int
+
+const (
+	// DiffAdded is a map key or list element that is only in the new value.
+	DiffAdded DiffKind = iota
+
+	// DiffRemoved is a map key or list element that is only in the old
+	// value.
+	DiffRemoved
+
+	// DiffChanged is a value that is different in the new value, including
+	// a value with a different type.
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// DiffEntry is a single difference found by Diff.
+type DiffEntry struct {
+	// Path is the path to the value from the root, such as "items[0].name",
+	// in the same format as the path given to a FieldHook. It is empty for
+	// the root value.
+	Path string
+
+	Kind DiffKind
+
+	// Old and New are the old and new values. Old is nil for DiffAdded and
+	// New is nil for DiffRemoved.
+	Old, New *proto.Value
+}
+
+// Diff returns the differences between the old value a and the new value b.
+// MAPs are compared by key, regardless of the order of the entries, and
+// LISTs are compared element by element, so an element inserted into a
+// list shows as changes to the elements after it. Values of any other type,
+// and values whose type differs, are compared with Equal and reported as a
+// single DiffChanged entry. No entries are returned if the values are
+// Equal.
+//
+// Entries for map keys are in the order of the entries of a, followed by
+// the keys added in b in the order of its entries. An error is returned if
+// either value is malformed.
+func Diff(a, b *proto.Value) ([]DiffEntry, error) {
+	var result []DiffEntry
+	if err := diffValue(nil, a, b, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func diffValue(path []byte, a, b *proto.Value, result *[]DiffEntry) error {
+	if a == nil || b == nil || a.Type != b.Type {
+		if !Equal(a, b) {
+			*result = append(*result, DiffEntry{
+				Path: string(path), Kind: DiffChanged, Old: a, New: b})
+		}
+
+		return nil
+	}
+
+	if err := checkPayload(a); err != nil {
+		return err
+	}
+	if err := checkPayload(b); err != nil {
+		return err
+	}
+
+	switch a.Type {
+	case proto.Value_LIST:
+		as := a.Value.(*proto.Value_ValueList).ValueList.Elems
+		bs := b.Value.(*proto.Value_ValueList).ValueList.Elems
+		for i := 0; i < len(as) || i < len(bs); i++ {
+			elemPath := appendIndexPath(path, i)
+			switch {
+			case i >= len(bs):
+				*result = append(*result, DiffEntry{
+					Path: string(elemPath), Kind: DiffRemoved, Old: as[i]})
+
+			case i >= len(as):
+				*result = append(*result, DiffEntry{
+					Path: string(elemPath), Kind: DiffAdded, New: bs[i]})
+
+			default:
+				if err := diffValue(elemPath, as[i], bs[i], result); err != nil {
+					return err
+				}
+			}
+		}
+
+	case proto.Value_MAP:
+		as := a.Value.(*proto.Value_ValueMap).ValueMap.Elems
+		bs := b.Value.(*proto.Value_ValueMap).ValueMap.Elems
+
+		// Index the entries of b by the hash of their key, as in Equal
+		index := make(map[uint64][]int, len(bs))
+		for i, elt := range bs {
+			h := hashValue(elt.Key)
+			index[h] = append(index[h], i)
+		}
+
+		matched := make([]bool, len(bs))
+		for _, elt := range as {
+			keyPath := appendKeyPath(path, elt.Key)
+
+			found := -1
+			for _, i := range index[hashValue(elt.Key)] {
+				if Equal(elt.Key, bs[i].Key) {
+					found = i
+					break
+				}
+			}
+			if found < 0 {
+				*result = append(*result, DiffEntry{
+					Path: string(keyPath), Kind: DiffRemoved, Old: elt.Value})
+				continue
+			}
+
+			matched[found] = true
+			if err := diffValue(keyPath, elt.Value, bs[found].Value, result); err != nil {
+				return err
+			}
+		}
+
+		for i, elt := range bs {
+			if !matched[i] {
+				*result = append(*result, DiffEntry{
+					Path: string(appendKeyPath(path, elt.Key)), Kind: DiffAdded, New: elt.Value})
+			}
+		}
+
+	default:
+		if !Equal(a, b) {
+			*result = append(*result, DiffEntry{
+				Path: string(path), Kind: DiffChanged, Old: a, New: b})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package encoding
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestDiff(t *testing.T) {
+	type entry struct {
+		Path     string
+		Kind     DiffKind
+		Old, New interface{}
+	}
+
+	cases := []struct {
+		Name     string
+		A, B     interface{}
+		Expected []entry
+	}{
+		{
+			"equal",
+			map[string]interface{}{"a": []int{1, 2}},
+			map[string]interface{}{"a": []int{1, 2}},
+			nil,
+		},
+
+		{
+			"scalar",
+			1,
+			2,
+			[]entry{{"", DiffChanged, int64(1), int64(2)}},
+		},
+
+		{
+			"type change",
+			map[string]interface{}{"a": 1},
+			map[string]interface{}{"a": "1"},
+			[]entry{{"a", DiffChanged, int64(1), "1"}},
+		},
+
+		{
+			"map keys",
+			map[string]interface{}{"a": 1, "b": 2},
+			map[string]interface{}{"b": 3, "c": 4},
+			[]entry{
+				{"a", DiffRemoved, int64(1), nil},
+				{"b", DiffChanged, int64(2), int64(3)},
+				{"c", DiffAdded, nil, int64(4)},
+			},
+		},
+
+		{
+			"list elements",
+			[]int{1, 2, 3},
+			[]int{1, 5},
+			[]entry{
+				{"[1]", DiffChanged, int64(2), int64(5)},
+				{"[2]", DiffRemoved, int64(3), nil},
+			},
+		},
+
+		{
+			"nested",
+			map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{"name": "a"}},
+			},
+			map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"name": "b"},
+					map[string]interface{}{"name": "c"},
+				},
+			},
+			[]entry{
+				{"items[0].name", DiffChanged, "a", "b"},
+				{"items[1]", DiffAdded, nil, map[string]string{"name": "c"}},
+			},
+		},
+
+		{
+			"non-string keys",
+			map[int]string{1: "a"},
+			map[int]string{1: "b"},
+			[]entry{{"[1]", DiffChanged, "a", "b"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			a, err := GoToValue(tc.A)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			b, err := GoToValue(tc.B)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			diff, err := Diff(a, b)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var actual []entry
+			for _, d := range diff {
+				e := entry{Path: d.Path, Kind: d.Kind}
+				if d.Old != nil {
+					if e.Old, err = ValueToGo(d.Old, nil); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				}
+				if d.New != nil {
+					if e.New, err = ValueToGo(d.New, nil); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				}
+
+				actual = append(actual, e)
+			}
+
+			// GoToValue doesn't order map entries
+			sort.Slice(actual, func(i, j int) bool {
+				return actual[i].Path < actual[j].Path
+			})
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestDiff_order(t *testing.T) {
+	a := testMapValue("b", "1", "a", "1", "c", "1")
+	b := testMapValue("d", "1", "a", "2")
+	diff, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual []string
+	for _, d := range diff {
+		actual = append(actual, d.Kind.String()+" "+d.Path)
+	}
+
+	expected := []string{"removed b", "changed a", "removed c", "added d"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestDiff_malformed(t *testing.T) {
+	a := &proto.Value{Type: proto.Value_MAP}
+	b := &proto.Value{Type: proto.Value_MAP}
+	if _, err := Diff(a, b); err == nil {
+		t.Fatal("should error")
+	}
+}
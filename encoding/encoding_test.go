@@ -1,10 +1,19 @@
 package encoding
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
 )
 
 func TestEncoding(t *testing.T) {
@@ -45,6 +54,192 @@ func TestEncoding(t *testing.T) {
 	}
 }
 
+func TestValueToGo_noPayload(t *testing.T) {
+	cases := []struct {
+		Type   proto.Value_Type
+		Target reflect.Type
+	}{
+		{proto.Value_BOOL, nil},
+		{proto.Value_BOOL, boolTyp},
+		{proto.Value_INT, nil},
+		{proto.Value_INT, intTyp},
+		{proto.Value_FLOAT, nil},
+		{proto.Value_FLOAT, floatTyp},
+		{proto.Value_STRING, nil},
+		{proto.Value_STRING, stringTyp},
+		{proto.Value_LIST, nil},
+		{proto.Value_LIST, reflect.TypeOf([]int{})},
+		{proto.Value_MAP, nil},
+		{proto.Value_MAP, reflect.TypeOf(map[string]int{})},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s to %v", tc.Type, tc.Target), func(t *testing.T) {
+			_, err := ValueToGo(&proto.Value{Type: tc.Type}, tc.Target)
+			if err == nil {
+				t.Fatal("should error")
+			}
+
+			expected := fmt.Sprintf("proto.Value has type %s but no payload", tc.Type)
+			if err.Error() != expected {
+				t.Fatalf("bad: %s", err)
+			}
+		})
+	}
+
+	// Nested values without a payload should error rather than panic
+	t.Run("nested list element", func(t *testing.T) {
+		_, err := ValueToGo(&proto.Value{
+			Type: proto.Value_LIST,
+			Value: &proto.Value_ValueList{
+				ValueList: &proto.Value_List{
+					Elems: []*proto.Value{{Type: proto.Value_INT}},
+				},
+			},
+		}, reflect.TypeOf([]int{}))
+		if err == nil {
+			t.Fatal("should error")
+		}
+	})
+}
+
+func TestValueToGo_nil(t *testing.T) {
+	list := func(elems ...*proto.Value) *proto.Value {
+		return &proto.Value{
+			Type:  proto.Value_LIST,
+			Value: &proto.Value_ValueList{ValueList: &proto.Value_List{Elems: elems}},
+		}
+	}
+	dict := func(elems ...*proto.Value_KV) *proto.Value {
+		return &proto.Value{
+			Type:  proto.Value_MAP,
+			Value: &proto.Value_ValueMap{ValueMap: &proto.Value_Map{Elems: elems}},
+		}
+	}
+	key, err := GoToValue("a")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	type item struct {
+		A int `sentinel:"a"`
+	}
+
+	values := []struct {
+		Name  string
+		Value *proto.Value
+		Err   string
+	}{
+		{"nil", nil, "nil value"},
+		{"nil list", &proto.Value{
+			Type: proto.Value_LIST, Value: &proto.Value_ValueList{},
+		}, "proto.Value has type LIST but no payload"},
+		{"nil map", &proto.Value{
+			Type: proto.Value_MAP, Value: &proto.Value_ValueMap{},
+		}, "proto.Value has type MAP but no payload"},
+		{"nil list element", list(key, nil), "nil list element 1"},
+		{"nil map entry", dict(nil), "nil map entry 0"},
+		{"nil map key", dict(&proto.Value_KV{Value: key}), "nil key for map entry 0"},
+		{"nil map value", dict(&proto.Value_KV{Key: key}), "nil value for map entry 0"},
+	}
+
+	targets := []reflect.Type{
+		nil,
+		reflect.TypeOf([]string{}),
+		reflect.TypeOf([]*string{}),
+		reflect.TypeOf(map[string]string{}),
+		reflect.TypeOf(item{}),
+	}
+
+	for _, v := range values {
+		for _, typ := range targets {
+			t.Run(fmt.Sprintf("%s to %v", v.Name, typ), func(t *testing.T) {
+				_, err := ValueToGo(v.Value, typ)
+				if err == nil || !strings.Contains(err.Error(), v.Err) {
+					t.Fatalf("bad: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestValueToSlice(t *testing.T) {
+	v, err := GoToValue([]int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	t.Run("reuses backing array", func(t *testing.T) {
+		dst := make([]int, 1, 10)
+		backing := &dst[:cap(dst)][0]
+		if err := ValueToSlice(v, &dst); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if !reflect.DeepEqual(dst, []int{1, 2, 3}) {
+			t.Fatalf("bad: %#v", dst)
+		}
+		if &dst[0] != backing {
+			t.Fatal("backing array should be reused")
+		}
+	})
+
+	t.Run("shrinks", func(t *testing.T) {
+		dst := []int{9, 9, 9, 9, 9}
+		if err := ValueToSlice(v, &dst); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if !reflect.DeepEqual(dst, []int{1, 2, 3}) {
+			t.Fatalf("bad: %#v", dst)
+		}
+	})
+
+	t.Run("grows", func(t *testing.T) {
+		var dst []int64
+		if err := ValueToSlice(v, &dst); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if !reflect.DeepEqual(dst, []int64{1, 2, 3}) {
+			t.Fatalf("bad: %#v", dst)
+		}
+	})
+
+	t.Run("element conversion error", func(t *testing.T) {
+		var dst []bool
+		if err := ValueToSlice(v, &dst); err == nil {
+			t.Fatal("should error")
+		}
+	})
+
+	t.Run("not a list", func(t *testing.T) {
+		v, err := GoToValue(42)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var dst []int
+		if err := ValueToSlice(v, &dst); err == nil {
+			t.Fatal("should error")
+		}
+	})
+
+	invalid := []interface{}{
+		nil,
+		[]int{},
+		(*[]int)(nil),
+		new(int),
+	}
+	for _, dst := range invalid {
+		t.Run(fmt.Sprintf("invalid destination %T", dst), func(t *testing.T) {
+			if err := ValueToSlice(v, dst); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
 // targetType is a wrapper struct that can be put around Expected values
 // in the table below to signal that the test should run with the "typ"
 // parameter set to nil. This will force the Go conversion to enforce the type.
@@ -129,6 +324,34 @@ var encodingTests = []struct {
 		false,
 	},
 
+	{
+		"map with struct keys",
+		map[testPoint]string{{1, 2}: "a", {3, 4}: "b"},
+		map[testPoint]string{{1, 2}: "a", {3, 4}: "b"},
+		false,
+	},
+
+	{
+		"map with struct keys to interface{} keys",
+		map[testPoint]string{{1, 2}: "a"},
+		map[interface{}]string{},
+		true,
+	},
+
+	{
+		"map with struct keys to nil type",
+		map[testPoint]string{{1, 2}: "a"},
+		targetType{Expected: map[interface{}]string{}},
+		true,
+	},
+
+	{
+		"map with list keys to struct keys",
+		map[[2]int]string{{1, 2}: "a"},
+		map[testPoint]string{},
+		true,
+	},
+
 	//-----------------------------------------------------------
 	// Slice
 
@@ -177,6 +400,180 @@ var encodingTests = []struct {
 		true,
 	},
 
+	//-----------------------------------------------------------
+	// Array
+
+	{
+		"array to matching array type",
+		[4]string{"a", "b", "c", "d"},
+		[4]string{"a", "b", "c", "d"},
+		false,
+	},
+
+	{
+		"array to slice",
+		[3]int{1, 2, 3},
+		[]int64{1, 2, 3},
+		false,
+	},
+
+	{
+		"slice to array",
+		[]string{"a", "b"},
+		[2]string{"a", "b"},
+		false,
+	},
+
+	{
+		"array to nil type",
+		[2]int{1, 2},
+		targetType{Expected: []int64{1, 2}},
+		false,
+	},
+
+	{
+		"slice to array of wrong length",
+		[]string{"a", "b", "c"},
+		[2]string{},
+		true,
+	},
+
+	{
+		"string to array",
+		"foo",
+		[2]string{},
+		true,
+	},
+
+	//-----------------------------------------------------------
+	// Struct
+
+	{
+		"struct to matching struct type",
+		testStruct{Name: "foo", Count: 2, Tags: []string{"a"}},
+		testStruct{Name: "foo", Count: 2, Tags: []string{"a"}},
+		false,
+	},
+
+	{
+		"map to struct",
+		map[string]interface{}{"name": "foo", "Count": 2, "extra": true},
+		testStruct{Name: "foo", Count: 2},
+		false,
+	},
+
+	{
+		"map with null to struct",
+		map[string]interface{}{"name": "foo", "Tags": nil},
+		testStruct{Name: "foo"},
+		false,
+	},
+
+	{
+		"struct to nil type",
+		testStruct{Name: "foo", Count: 2, Tags: []string{"a"}},
+		targetType{Expected: map[string]interface{}{
+			"name":  "foo",
+			"Count": int64(2),
+			"Tags":  []string{"a"},
+		}},
+		false,
+	},
+
+	{
+		"map with incompatible field to struct",
+		map[string]interface{}{"Count": "two"},
+		testStruct{},
+		true,
+	},
+
+	{
+		"list to struct",
+		[]int{1},
+		testStruct{},
+		true,
+	},
+
+	//-----------------------------------------------------------
+	// Pointer
+
+	{
+		"pointer to int to pointer to int",
+		testIntPtr(42),
+		testIntPtr(42),
+		false,
+	},
+
+	{
+		"pointer to int to int",
+		testIntPtr(42),
+		int(42),
+		false,
+	},
+
+	{
+		"int to pointer to int",
+		42,
+		testIntPtr(42),
+		false,
+	},
+
+	{
+		"pointer to pointer to string",
+		func() **string { s := "foo"; p := &s; return &p }(),
+		"foo",
+		false,
+	},
+
+	{
+		"nil pointer to nil type",
+		(*int)(nil),
+		targetType{Expected: sdk.Null},
+		false,
+	},
+
+	{
+		"nil pointer to pointer to int",
+		(*int)(nil),
+		(*int)(nil),
+		false,
+	},
+
+	{
+		"slice of pointers with nil",
+		[]*int{testIntPtr(1), nil},
+		[]*int{testIntPtr(1), nil},
+		false,
+	},
+
+	{
+		"slice of pointers with undefined",
+		[]interface{}{sdk.Undefined},
+		[]*int{nil},
+		false,
+	},
+
+	{
+		"map of pointers with nil",
+		map[string]*int{"a": nil, "b": testIntPtr(2)},
+		map[string]*int{"a": nil, "b": testIntPtr(2)},
+		false,
+	},
+
+	{
+		"struct with pointer fields",
+		testPtrStruct{Set: testIntPtr(0)},
+		testPtrStruct{Set: testIntPtr(0)},
+		false,
+	},
+
+	{
+		"string to pointer to int",
+		"foo",
+		testIntPtr(0),
+		true,
+	},
+
 	//-----------------------------------------------------------
 	// Bool
 
@@ -436,4 +833,1046 @@ var encodingTests = []struct {
 		targetType{Expected: sdk.Undefined},
 		false,
 	},
+
+	//-----------------------------------------------------------
+	// URL
+
+	{
+		"url to url",
+		testURL("https://example.com/path?a=1&b=two#frag"),
+		testURL("https://example.com/path?a=1&b=two#frag"),
+		false,
+	},
+
+	{
+		"url value to url value",
+		*testURL("https://user@example.com:8080/?q=a+b"),
+		*testURL("https://user@example.com:8080/?q=a+b"),
+		false,
+	},
+
+	{
+		"url to string",
+		testURL("https://example.com/path?a=1#frag"),
+		"https://example.com/path?a=1#frag",
+		false,
+	},
+
+	{
+		"string to url",
+		"/relative/path?a=1",
+		testURL("/relative/path?a=1"),
+		false,
+	},
+
+	{
+		"nil url to url",
+		(*url.URL)(nil),
+		(*url.URL)(nil),
+		false,
+	},
+
+	{
+		"invalid string to url",
+		"http://[::1",
+		testURL("http://localhost"),
+		true,
+	},
+
+	{
+		"int to url",
+		42,
+		testURL("http://localhost"),
+		true,
+	},
+
+	//-----------------------------------------------------------
+	// Weekday and Month
+
+	{
+		"weekday to weekday",
+		time.Tuesday,
+		time.Tuesday,
+		false,
+	},
+
+	{
+		"int to weekday",
+		6,
+		time.Saturday,
+		false,
+	},
+
+	{
+		"string to weekday",
+		"monday",
+		time.Monday,
+		false,
+	},
+
+	{
+		"int out of range to weekday",
+		7,
+		time.Sunday,
+		true,
+	},
+
+	{
+		"unknown string to weekday",
+		"Funday",
+		time.Sunday,
+		true,
+	},
+
+	{
+		"month to month",
+		time.March,
+		time.March,
+		false,
+	},
+
+	{
+		"string to month",
+		"December",
+		time.December,
+		false,
+	},
+
+	{
+		"int out of range to month",
+		0,
+		time.January,
+		true,
+	},
+
+	{
+		"bool to month",
+		true,
+		time.January,
+		true,
+	},
+}
+
+// testStruct is a struct used in the encoding tests.
+type testStruct struct {
+	Name    string `sentinel:"name"`
+	Count   int
+	Tags    []string
+	Ignored string `sentinel:""`
+	private string
+}
+
+// testPoint is a comparable struct used as a map key in the encoding tests.
+type testPoint struct {
+	X, Y int
+}
+
+// testPtrStruct is a struct with optional fields used in the encoding tests.
+type testPtrStruct struct {
+	Set   *int
+	Unset *int
+}
+
+func testIntPtr(v int) *int {
+	return &v
+}
+
+func testURL(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
+func TestValueToGo_structDefault(t *testing.T) {
+	type config struct {
+		Region  string  `sentinel:"region" default:"us-east-1"`
+		Retries int     `default:"3"`
+		Ratio   float64 `default:"0.5"`
+		Enabled bool    `default:"true"`
+		Tags    []string
+	}
+
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected config
+	}{
+		{
+			"missing keys",
+			map[string]interface{}{},
+			config{Region: "us-east-1", Retries: 3, Ratio: 0.5, Enabled: true},
+		},
+
+		{
+			"undefined keys",
+			map[string]interface{}{"region": sdk.Undefined, "Retries": sdk.Undefined},
+			config{Region: "us-east-1", Retries: 3, Ratio: 0.5, Enabled: true},
+		},
+
+		{
+			"set keys",
+			map[string]interface{}{"region": "eu-west-1", "Enabled": false},
+			config{Region: "eu-west-1", Retries: 3, Ratio: 0.5, Enabled: false},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(config{}))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestValueToGo_structDefaultInvalid(t *testing.T) {
+	type config struct {
+		Retries int `default:"three"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := ValueToGo(v, reflect.TypeOf(config{})); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The default isn't used if the key is set
+	v, err = GoToValue(map[string]interface{}{"Retries": 1})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := ValueToGo(v, reflect.TypeOf(config{})); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestDecodeMapByKey(t *testing.T) {
+	types := map[string]reflect.Type{
+		"port":    reflect.TypeOf(uint16(0)),
+		"tags":    reflect.TypeOf([]string{}),
+		"timeout": reflect.TypeOf(float32(0)),
+	}
+
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected map[string]interface{}
+		Err      bool
+	}{
+		{
+			"typed and untyped keys",
+			map[string]interface{}{
+				"port":    8080,
+				"tags":    []interface{}{"a", "b"},
+				"timeout": 2,
+				"name":    "web",
+			},
+			map[string]interface{}{
+				"port":    uint16(8080),
+				"tags":    []string{"a", "b"},
+				"timeout": float32(2),
+				"name":    "web",
+			},
+			false,
+		},
+
+		{
+			"missing typed keys",
+			map[string]interface{}{"name": "web"},
+			map[string]interface{}{"name": "web"},
+			false,
+		},
+
+		{
+			"incompatible value",
+			map[string]interface{}{"port": "http"},
+			nil,
+			true,
+		},
+
+		{
+			"not a map",
+			[]int{1},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := DecodeMapByKey(v, types)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestValueToReflect(t *testing.T) {
+	var dst struct {
+		Name  string
+		Ports []int
+		Ptr   *int
+		Any   interface{}
+	}
+	dstVal := reflect.ValueOf(&dst).Elem()
+
+	// Pre-allocate to check the slice capacity is reused
+	ports := make([]int, 0, 4)
+	dst.Ports = ports
+	dst.Ptr = testIntPtr(1)
+
+	cases := []struct {
+		Field  string
+		Source interface{}
+	}{
+		{"Name", "web"},
+		{"Ports", []int{80, 443}},
+		{"Ptr", nil},
+		{"Any", map[string]int{"a": 1}},
+	}
+	for _, tc := range cases {
+		v, err := GoToValue(tc.Source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if err := ValueToReflect(v, dstVal.FieldByName(tc.Field)); err != nil {
+			t.Fatalf("%s: %s", tc.Field, err)
+		}
+	}
+
+	if dst.Name != "web" {
+		t.Fatalf("bad: %#v", dst)
+	}
+	if !reflect.DeepEqual(dst.Ports, []int{80, 443}) || &dst.Ports[0] != &ports[:1][0] {
+		t.Fatalf("bad: %#v", dst.Ports)
+	}
+	if dst.Ptr != nil {
+		t.Fatalf("bad: %#v", dst.Ptr)
+	}
+	if !reflect.DeepEqual(dst.Any, map[string]int64{"a": 1}) {
+		t.Fatalf("bad: %#v", dst.Any)
+	}
+}
+
+func TestValueToReflect_invalid(t *testing.T) {
+	v, err := GoToValue("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var s string
+	dsts := []reflect.Value{
+		{},
+		reflect.ValueOf(s),
+		reflect.ValueOf(&s),
+	}
+	for _, dst := range dsts {
+		if err := ValueToReflect(v, dst); err == nil {
+			t.Fatalf("%s: should error", dst)
+		}
+	}
+
+	var n int
+	if err := ValueToReflect(v, reflect.ValueOf(&n).Elem()); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestStruct_duplicateKey(t *testing.T) {
+	type dup struct {
+		First  string `sentinel:"name"`
+		Second string `sentinel:"name"`
+	}
+
+	type dupName struct {
+		Name  string
+		Other string `sentinel:"Name"`
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(dup{}), reflect.TypeOf(dupName{})} {
+		t.Run(typ.Name(), func(t *testing.T) {
+			v, err := GoToValue(map[string]interface{}{"name": "foo"})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// Check twice to check the cached result
+			for i := 0; i < 2; i++ {
+				_, err := ValueToGo(v, typ)
+				if err == nil || !strings.Contains(err.Error(), "duplicate sentinel tag") {
+					t.Fatalf("bad: %v", err)
+				}
+			}
+
+			if _, err := GoToValue(reflect.New(typ).Elem().Interface()); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+func TestValueToGo_structJoin(t *testing.T) {
+	type report struct {
+		Tags  string `sentinel:"tags,join=, "`
+		IDs   string `sentinel:",join=-"`
+		Plain string `sentinel:"plain"`
+	}
+
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected report
+		Err      bool
+	}{
+		{
+			"list",
+			map[string]interface{}{"tags": []string{"a", "b", "c"}},
+			report{Tags: "a, b, c"},
+			false,
+		},
+
+		{
+			"ints are formatted",
+			map[string]interface{}{"IDs": []int{1, 2}},
+			report{IDs: "1-2"},
+			false,
+		},
+
+		{
+			"string",
+			map[string]interface{}{"tags": "a"},
+			report{Tags: "a"},
+			false,
+		},
+
+		{
+			"empty list",
+			map[string]interface{}{"tags": []string{}},
+			report{},
+			false,
+		},
+
+		{
+			"non-string element",
+			map[string]interface{}{"tags": []interface{}{"a", true}},
+			report{},
+			true,
+		},
+
+		{
+			"list without join",
+			map[string]interface{}{"plain": []string{"a"}},
+			report{},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(report{}))
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestValueToGo_structJoinInvalid(t *testing.T) {
+	type unknown struct {
+		Tags string `sentinel:"tags,split"`
+	}
+
+	type notString struct {
+		Tags []string `sentinel:"tags,join=,"`
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(unknown{}), reflect.TypeOf(notString{})} {
+		t.Run(typ.Name(), func(t *testing.T) {
+			v, err := GoToValue(map[string]interface{}{"tags": []string{"a"}})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if _, err := ValueToGo(v, typ); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+func TestValueToGo_scalarList(t *testing.T) {
+	// Lists that don't match the fast path for scalar lists fall back to
+	// the generic conversion of each element.
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected interface{}
+	}{
+		{"ints", []interface{}{1, 2}, []int64{1, 2}},
+		{"ints to floats", []interface{}{1.5, 2}, []float64{1.5, 2}},
+		{"ints to strings", []interface{}{"a", 2}, []string{"a", "2"}},
+		{"strings to ints", []interface{}{1, "2"}, []int64{1, 2}},
+		{"bools", []bool{true, false}, []bool{true, false}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(tc.Expected))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestValueToGo_bigIntList(t *testing.T) {
+	huge := "123456789012345678901234567890123456789012345678901234567890"
+	v, err := GoToValue([]string{huge, "-42", "0x10", "0"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf([]*big.Int(nil)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{huge, "-42", "16", "0"}
+	result := actual.([]*big.Int)
+	if len(result) != len(expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	for i, x := range result {
+		if x.String() != expected[i] {
+			t.Fatalf("bad: %d: %s", i, x)
+		}
+	}
+
+	// Lists the fast path doesn't handle fall back to the generic path,
+	// which reports the error.
+	for _, source := range []interface{}{
+		[]interface{}{"1", "nope"},
+		[]interface{}{"1", 2},
+	} {
+		v, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if _, err := ValueToGo(v, reflect.TypeOf([]*big.Int(nil))); err == nil {
+			t.Fatalf("should error: %#v", source)
+		}
+	}
+}
+
+func TestValueToGo_structRemaining(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{
+		"name":  "a",
+		"count": 2,
+		"extra": "b",
+		"tags":  []string{"c"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	type config struct {
+		Name  string                 `sentinel:"name"`
+		Count int                    `sentinel:"count"`
+		Rest  map[string]interface{} `sentinel:",remaining"`
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(config{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Matched keys are decoded into their fields and only the others are
+	// in the catch-all field
+	result := actual.(config)
+	if result.Name != "a" || result.Count != 2 {
+		t.Fatalf("bad: %#v", result)
+	}
+	keys := make([]string, 0, len(result.Rest))
+	for k := range result.Rest {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"extra", "tags"}) {
+		t.Fatalf("bad: %#v", result.Rest)
+	}
+	if result.Rest["extra"] != "b" {
+		t.Fatalf("bad: %#v", result.Rest)
+	}
+
+	// Encoding puts the entries back
+	encoded, err := GoToValue(result)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !Equal(encoded, v) {
+		t.Fatalf("bad: %s", encoded)
+	}
+
+	// The catch-all field is nil if every key matches
+	v, err = GoToValue(map[string]interface{}{"name": "a"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	actual, err = ValueToGo(v, reflect.TypeOf(config{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual.(config).Rest != nil {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// It can't be used with WithDisallowUnknownKeys
+	if _, err := ValueToGo(v, reflect.TypeOf(config{}), WithDisallowUnknownKeys()); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Invalid fields
+	type wrongType struct {
+		Rest map[string]string `sentinel:",remaining"`
+	}
+	type twoFields struct {
+		A map[string]interface{} `sentinel:",remaining"`
+		B map[string]interface{} `sentinel:",remaining"`
+	}
+	for _, typ := range []reflect.Type{reflect.TypeOf(wrongType{}), reflect.TypeOf(twoFields{})} {
+		if _, err := ValueToGo(v, typ); err == nil {
+			t.Fatalf("should error: %s", typ)
+		}
+	}
+}
+
+func TestValueToGo_structUnwrap(t *testing.T) {
+	type reading struct {
+		Count int      `sentinel:"count,unwrap=value"`
+		Tags  []string `sentinel:"tags,unwrap=items"`
+		Name  *string  `sentinel:"name,unwrap=value"`
+	}
+
+	name := "a"
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected reading
+		Err      string
+	}{
+		{
+			"wrapped",
+			map[string]interface{}{
+				"count": map[string]interface{}{"value": 42, "unit": "ms"},
+				"tags":  map[string]interface{}{"items": []string{"x"}},
+				"name":  map[string]interface{}{"value": "a"},
+			},
+			reading{Count: 42, Tags: []string{"x"}, Name: &name},
+			"",
+		},
+
+		{
+			"unwrapped",
+			map[string]interface{}{"count": 42, "tags": []string{"x"}},
+			reading{Count: 42, Tags: []string{"x"}},
+			"",
+		},
+
+		{
+			"wrapped null",
+			map[string]interface{}{"name": map[string]interface{}{"value": nil}},
+			reading{},
+			"",
+		},
+
+		{
+			"missing key",
+			map[string]interface{}{"count": map[string]interface{}{"val": 42}},
+			reading{},
+			`field Count: key "value" not found`,
+		},
+
+		{
+			"wrong type",
+			map[string]interface{}{"count": map[string]interface{}{"value": "x"}},
+			reading{},
+			"field Count",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(reading{}))
+			if err != nil {
+				if tc.Err == "" || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("err: %s", err)
+				}
+
+				return
+			}
+			if tc.Err != "" {
+				t.Fatal("should error")
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// An unwrap option needs a key
+	type invalid struct {
+		Count int `sentinel:"count,unwrap="`
+	}
+	if _, err := GoToValue(invalid{}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestValueToGo_structByteArray(t *testing.T) {
+	type digest struct {
+		SHA [4]byte `sentinel:"sha,hex"`
+		Key [3]byte `sentinel:"key,base64"`
+		Raw [2]byte `sentinel:"raw"`
+	}
+
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected digest
+		Err      string
+	}{
+		{
+			"encoded",
+			map[string]interface{}{"sha": "deadbeef", "key": "AQID"},
+			digest{SHA: [4]byte{0xde, 0xad, 0xbe, 0xef}, Key: [3]byte{1, 2, 3}},
+			"",
+		},
+
+		{
+			"short",
+			map[string]interface{}{"sha": "dead"},
+			digest{},
+			"expected 4 bytes, got 2",
+		},
+
+		{
+			"invalid hex",
+			map[string]interface{}{"sha": "zz"},
+			digest{},
+			"invalid hex",
+		},
+
+		{
+			"invalid base64",
+			map[string]interface{}{"key": "!!!!"},
+			digest{},
+			"invalid base64",
+		},
+
+		{
+			"list",
+			map[string]interface{}{"sha": []int{1, 2, 3, 4}, "raw": []int{5, 6}},
+			digest{SHA: [4]byte{1, 2, 3, 4}, Raw: [2]byte{5, 6}},
+			"",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(digest{}))
+			if err != nil {
+				if tc.Err == "" || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("err: %s", err)
+				}
+
+				return
+			}
+			if tc.Err != "" {
+				t.Fatal("should error")
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+
+	// Encoding uses the same encoding for a round trip
+	source := digest{SHA: [4]byte{0xde, 0xad, 0xbe, 0xef}, Key: [3]byte{1, 2, 3}}
+	v, err := GoToValue(source)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m.(map[string]interface{})["sha"] != "deadbeef" || m.(map[string]interface{})["key"] != "AQID" {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	actual, err := ValueToGo(v, reflect.TypeOf(digest{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, source) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestChanToValue(t *testing.T) {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for _, v := range []interface{}{1, "two", []int{3}} {
+			ch <- v
+		}
+	}()
+
+	v, err := ChanToValue(ch)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []interface{}{int64(1), "two", []int64{3}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// A closed, empty channel is an empty list
+	empty := make(chan int)
+	close(empty)
+	v, err = ChanToValue((<-chan int)(empty))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if elems := v.GetValueList().Elems; elems == nil || len(elems) != 0 {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// Values that can't be converted are errors
+	bad := make(chan interface{}, 2)
+	bad <- 1
+	bad <- func() {}
+	close(bad)
+	if _, err := ChanToValue(bad); err == nil || err.Error() != "element 1: cannot convert func to Sentinel value" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestChanToValue_invalid(t *testing.T) {
+	cases := []struct {
+		Name string
+		Ch   interface{}
+	}{
+		{"not a channel", []int{1}},
+		{"send-only", make(chan<- int)},
+		{"nil", (chan int)(nil)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if _, err := ChanToValue(tc.Ch); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+func TestChanToValueContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan int, 1)
+	ch <- 1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	// The channel is never closed, so this stops when ctx is canceled
+	_, err := ChanToValueContext(ctx, ch)
+	if err != context.Canceled {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestGoToValue_structOmitEmpty(t *testing.T) {
+	type item struct {
+		Name  string     `sentinel:"name,omitempty"`
+		Tags  []string   `sentinel:"tags,omitempty"`
+		Count *int       `sentinel:"count,omitempty"`
+		When  time.Time  `sentinel:"when,omitempty"`
+		At    *time.Time `sentinel:"at,omitempty"`
+		Kept  string     `sentinel:"kept"`
+	}
+
+	zero := 0
+	zeroTime := time.Time{}
+	cases := []struct {
+		Name     string
+		Source   item
+		Expected []string
+	}{
+		{
+			"empty",
+			item{Tags: []string{}},
+			[]string{"kept"},
+		},
+
+		{
+			"set",
+			item{Name: "a", Tags: []string{"b"}, Count: &zero, At: &zeroTime},
+			[]string{"at", "count", "kept", "name", "tags"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var actual []string
+			for _, elt := range v.GetValueMap().Elems {
+				actual = append(actual, elt.Key.GetValueString())
+			}
+
+			sort.Strings(actual)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestStruct_invalidEncodingOption(t *testing.T) {
+	type notArray struct {
+		SHA string `sentinel:"sha,hex"`
+	}
+
+	type conflict struct {
+		SHA [4]byte `sentinel:"sha,hex,base64"`
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(notArray{}), reflect.TypeOf(conflict{})} {
+		t.Run(typ.Name(), func(t *testing.T) {
+			if _, err := GoToValue(reflect.New(typ).Elem().Interface()); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+func TestGoToValue_mixedKeys(t *testing.T) {
+	v, err := GoToValue(map[interface{}]interface{}{
+		1:     "one",
+		"two": 2,
+		true:  nil,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	types := map[proto.Value_Type]bool{}
+	for _, elt := range v.GetValueMap().Elems {
+		types[elt.Key.Type] = true
+	}
+	expected := map[proto.Value_Type]bool{
+		proto.Value_INT:    true,
+		proto.Value_STRING: true,
+		proto.Value_BOOL:   true,
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("bad: %#v", types)
+	}
+
+	// The keys decode back into interface{} keys
+	actual, err := ValueToGo(v, reflect.TypeOf(map[interface{}]interface{}{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m := actual.(map[interface{}]interface{}); m[int64(1)] != "one" || m["two"] != int64(2) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Keys that can't be converted error with the key
+	_, err = GoToValue(map[interface{}]interface{}{complex(1, 2): "a"})
+	if err == nil || err.Error() != "key (1+2i): cannot convert complex number to Sentinel value" {
+		t.Fatalf("bad: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = EncodeStream(&buf, map[interface{}]interface{}{complex(1, 2): "a"})
+	if err == nil || err.Error() != "key (1+2i): cannot convert complex number to Sentinel value" {
+		t.Fatalf("bad: %v", err)
+	}
 }
@@ -0,0 +1,193 @@
+package encoding
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Equal returns true if the two values are structurally equal: they have
+// the same type and equal contents. LISTs are equal if their elements are
+// equal in order. MAPs are equal if they have equal keys with equal
+// values, regardless of the order of the entries. Values of different
+// types are never equal, so the INT 1 is not equal to the FLOAT 1.0. As
+// in Go, a FLOAT NaN isn't equal to anything, including itself.
+//
+// Two nil values are equal. Malformed values with no payload are only
+// equal to values with the same type and no payload, and nil MAP entries
+// are compared as entries with a nil key and value.
+func Equal(a, b *proto.Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Value == nil || b.Value == nil {
+		return a.Value == nil && b.Value == nil
+	}
+
+	switch a.Type {
+	case proto.Value_BOOL:
+		return a.GetValueBool() == b.GetValueBool()
+
+	case proto.Value_INT:
+		return a.GetValueInt() == b.GetValueInt()
+
+	case proto.Value_FLOAT:
+		return a.GetValueFloat() == b.GetValueFloat()
+
+	case proto.Value_STRING:
+		return a.GetValueString() == b.GetValueString()
+
+	case proto.Value_LIST:
+		as := a.GetValueList().GetElems()
+		bs := b.GetValueList().GetElems()
+		if len(as) != len(bs) {
+			return false
+		}
+
+		for i := range as {
+			if !Equal(as[i], bs[i]) {
+				return false
+			}
+		}
+
+		return true
+
+	case proto.Value_MAP:
+		as := a.GetValueMap().GetElems()
+		bs := b.GetValueMap().GetElems()
+		if len(as) != len(bs) {
+			return false
+		}
+
+		// Index the entries of b by the hash of their key so each entry
+		// of a can be found without comparing against every entry.
+		index := make(map[uint64][]*proto.Value_KV, len(bs))
+		for _, elt := range bs {
+			h := hashValue(elt.GetKey())
+			index[h] = append(index[h], elt)
+		}
+
+		for _, elt := range as {
+			found := false
+			for _, other := range index[hashValue(elt.GetKey())] {
+				if Equal(elt.GetKey(), other.GetKey()) {
+					if !Equal(elt.GetValue(), other.GetValue()) {
+						return false
+					}
+
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		// UNDEFINED and NULL have no contents
+		return true
+	}
+}
+
+// Dedup returns a copy of the LIST v with structurally equal elements (as
+// determined by Equal) removed, keeping the first occurrence of each. The
+// order of the remaining elements is unchanged. An error is returned if v
+// isn't a LIST.
+func Dedup(v *proto.Value) (*proto.Value, error) {
+	if v == nil || v.Type != proto.Value_LIST {
+		return nil, fmt.Errorf("can only dedup a list, got %s", v.GetType())
+	}
+	if err := checkPayload(v); err != nil {
+		return nil, err
+	}
+
+	elems := v.Value.(*proto.Value_ValueList).ValueList.Elems
+	seen := make(map[uint64][]*proto.Value, len(elems))
+	result := make([]*proto.Value, 0, len(elems))
+	for _, elt := range elems {
+		h := hashValue(elt)
+		dup := false
+		for _, other := range seen[h] {
+			if Equal(elt, other) {
+				dup = true
+				break
+			}
+		}
+		if dup {
+			continue
+		}
+
+		seen[h] = append(seen[h], elt)
+		result = append(result, elt)
+	}
+
+	return &proto.Value{
+		Type: proto.Value_LIST,
+		Value: &proto.Value_ValueList{
+			ValueList: &proto.Value_List{Elems: result},
+		},
+	}, nil
+}
+
+// hashValue returns a hash of v that is the same for values that are
+// Equal. The hash of a MAP doesn't depend on the order of its entries.
+func hashValue(v *proto.Value) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	writeUint64 := func(x uint64) {
+		for i := range buf {
+			buf[i] = byte(x >> (8 * uint(i)))
+		}
+		h.Write(buf[:])
+	}
+
+	if v == nil {
+		return 0
+	}
+	writeUint64(uint64(v.Type))
+
+	switch x := v.Value.(type) {
+	case *proto.Value_ValueBool:
+		if x.ValueBool {
+			writeUint64(1)
+		}
+
+	case *proto.Value_ValueInt:
+		writeUint64(uint64(x.ValueInt))
+
+	case *proto.Value_ValueFloat:
+		// 0.0 and -0.0 are equal so must have the same hash
+		f := x.ValueFloat
+		if f == 0 {
+			f = 0
+		}
+		writeUint64(math.Float64bits(f))
+
+	case *proto.Value_ValueString:
+		h.Write([]byte(x.ValueString))
+
+	case *proto.Value_ValueList:
+		for _, elt := range x.ValueList.GetElems() {
+			writeUint64(hashValue(elt))
+		}
+
+	case *proto.Value_ValueMap:
+		// Combine the entries with addition so the order doesn't matter
+		var sum uint64
+		for _, elt := range x.ValueMap.GetElems() {
+			sum += hashValue(elt.GetKey())*31 + hashValue(elt.GetValue())
+		}
+		writeUint64(sum)
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,216 @@
+package encoding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		Name     string
+		A, B     interface{}
+		Expected bool
+	}{
+		{"null", nil, nil, true},
+		{"undefined", sdk.Undefined, sdk.Undefined, true},
+		{"null and undefined", nil, sdk.Undefined, false},
+		{"bool", true, true, true},
+		{"different bool", true, false, false},
+		{"int", 42, int64(42), true},
+		{"int and float", 1, 1.0, false},
+		{"float", 1.5, 1.5, true},
+		{"zero floats", 0.0, math.Copysign(0, -1), true},
+		{"nan", math.NaN(), math.NaN(), false},
+		{"string", "a", "a", true},
+		{"different string", "a", "b", false},
+		{"list", []int{1, 2}, []int{1, 2}, true},
+		{"list order", []int{1, 2}, []int{2, 1}, false},
+		{"list length", []int{1, 2}, []int{1}, false},
+		{"map", map[string]int{"a": 1, "b": 2}, map[string]int{"b": 2, "a": 1}, true},
+		{"map value", map[string]int{"a": 1}, map[string]int{"a": 2}, false},
+		{"map key", map[string]int{"a": 1}, map[string]int{"b": 1}, false},
+		{"map length", map[string]int{"a": 1}, map[string]int{}, false},
+
+		{
+			"nested",
+			map[string]interface{}{"a": []interface{}{map[string]int{"x": 1, "y": 2}}},
+			map[string]interface{}{"a": []interface{}{map[string]int{"y": 2, "x": 1}}},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			a, err := GoToValue(tc.A)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			b, err := GoToValue(tc.B)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if actual := Equal(a, b); actual != tc.Expected {
+				t.Fatalf("bad: %v", actual)
+			}
+			if actual := Equal(b, a); actual != tc.Expected {
+				t.Fatalf("bad reversed: %v", actual)
+			}
+			if tc.Expected && hashValue(a) != hashValue(b) {
+				t.Fatal("equal values should have equal hashes")
+			}
+		})
+	}
+
+	// Nil map entries, keys and values don't panic
+	key, err := GoToValue("a")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	dict := func(elems ...*proto.Value_KV) *proto.Value {
+		return &proto.Value{
+			Type:  proto.Value_MAP,
+			Value: &proto.Value_ValueMap{ValueMap: &proto.Value_Map{Elems: elems}},
+		}
+	}
+	malformed := []struct {
+		Name     string
+		A, B     *proto.Value
+		Expected bool
+	}{
+		{"nil entry", dict(nil), dict(nil), true},
+		{"nil entry and entry", dict(nil), dict(&proto.Value_KV{Key: key, Value: key}), false},
+		{"nil key", dict(&proto.Value_KV{Value: key}), dict(&proto.Value_KV{Value: key}), true},
+		{"nil value", dict(&proto.Value_KV{Key: key}), dict(&proto.Value_KV{Key: key, Value: key}), false},
+	}
+	for _, tc := range malformed {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := Equal(tc.A, tc.B); actual != tc.Expected {
+				t.Fatalf("bad: %v", actual)
+			}
+			if actual := Equal(tc.B, tc.A); actual != tc.Expected {
+				t.Fatalf("bad reversed: %v", actual)
+			}
+			if tc.Expected && hashValue(tc.A) != hashValue(tc.B) {
+				t.Fatal("equal values should have equal hashes")
+			}
+		})
+	}
+}
+
+func TestEqual_builders(t *testing.T) {
+	undef, err := GoToValue(sdk.Undefined)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	null, err := GoToValue(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !Equal(Undefined(), undef) || !Equal(Null(), null) {
+		t.Fatal("should be equal")
+	}
+	if Equal(Undefined(), Null()) {
+		t.Fatal("should not be equal")
+	}
+}
+
+func TestEqual_map(t *testing.T) {
+	// Map entry order must not matter, even when the values are built
+	// directly rather than through GoToValue.
+	a := testMapValue("a", "1", "b", "2", "c", "3")
+	b := testMapValue("c", "3", "a", "1", "b", "2")
+	if !Equal(a, b) {
+		t.Fatal("should be equal")
+	}
+
+	if !Equal(nil, nil) || Equal(a, nil) {
+		t.Fatal("bad nil handling")
+	}
+	if Equal(&proto.Value{Type: proto.Value_MAP}, a) {
+		t.Fatal("malformed value should not be equal")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected interface{}
+		Err      bool
+	}{
+		{
+			"scalars",
+			[]interface{}{1, "a", 1, 2, "a", 1.0},
+			[]interface{}{int64(1), "a", int64(2), 1.0},
+			false,
+		},
+
+		{
+			"maps",
+			[]interface{}{
+				map[string]int{"a": 1, "b": 2},
+				map[string]int{"a": 1},
+				map[string]int{"b": 2, "a": 1},
+			},
+			[]interface{}{
+				map[string]int64{"a": 1, "b": 2},
+				map[string]int64{"a": 1},
+			},
+			false,
+		},
+
+		{
+			"no duplicates",
+			[]int{3, 2, 1},
+			[]int64{3, 2, 1},
+			false,
+		},
+
+		{
+			"empty",
+			[]int{},
+			[]interface{}{},
+			false,
+		},
+
+		{
+			"not a list",
+			map[string]int{"a": 1},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			result, err := Dedup(v)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			actual, err := ValueToGo(result, nil)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
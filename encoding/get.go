@@ -0,0 +1,307 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Get returns the value at the given path within v, without decoding v.
+// Each element of the path is either a string, which looks up a STRING
+// key in a MAP, or an integer, which is an index into a LIST or looks up
+// an INT key in a MAP. For example, Get(v, "items", 0, "name") returns
+// the equivalent of v["items"][0]["name"].
+//
+// An error is returned if a key isn't found or an index is out of range,
+// which is a *NotFoundError, or if an element of the path doesn't apply to
+// the value at that point.
+//
+// The As functions such as AsString can be used to read the result.
+func Get(v *proto.Value, path ...interface{}) (*proto.Value, error) {
+	for i, p := range path {
+		if v == nil {
+			return nil, fmt.Errorf("path element %d: nil value", i)
+		}
+		if err := checkPayload(v); err != nil {
+			return nil, err
+		}
+
+		var err error
+		v, err = getElem(v, p)
+		if err != nil {
+			if _, ok := err.(notFoundError); ok {
+				return nil, &NotFoundError{Index: i, Err: err}
+			}
+
+			return nil, fmt.Errorf("path element %d: %s", i, err)
+		}
+	}
+
+	return v, nil
+}
+
+// DecodePath decodes only the value at the given path within v into the
+// type t, without decoding the rest of v. The path is the same as for Get,
+// so DecodePath(v, t, "metadata", "region") decodes the equivalent of
+// v["metadata"]["region"]. The value is decoded the same way as by
+// ValueToGo.
+//
+// If a key or index in the path doesn't exist, the error is a
+// *NotFoundError, so that a missing value can be told apart from one that
+// can't be decoded.
+func DecodePath(v *proto.Value, t reflect.Type, path ...interface{}) (interface{}, error) {
+	sub, err := Get(v, path...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ValueToGo(sub, t)
+}
+
+// NotFoundError is returned by Get and DecodePath when a key in the path
+// isn't found in a MAP or an index is out of range for a LIST. Other
+// errors, such as looking up a key in a value that isn't a MAP, aren't a
+// NotFoundError.
+type NotFoundError struct {
+	Index int   // index of the path element that wasn't found
+	Err   error // description of what wasn't found
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("path element %d: %s", e.Index, e.Err)
+}
+
+// notFoundError is returned by getElem when the element doesn't exist.
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+
+func getElem(v *proto.Value, p interface{}) (*proto.Value, error) {
+	// Any kind of integer can index
+	var index int64
+	isIndex := false
+	switch rv := reflect.ValueOf(p); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		index, isIndex = rv.Int(), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		index, isIndex = int64(rv.Uint()), true
+
+	case reflect.String:
+
+	default:
+		return nil, fmt.Errorf("path element must be a string or integer, got %T", p)
+	}
+
+	switch v.Type {
+	case proto.Value_LIST:
+		if !isIndex {
+			return nil, fmt.Errorf("cannot look up key %q in a list", p)
+		}
+
+		elems := v.Value.(*proto.Value_ValueList).ValueList.Elems
+		if index < 0 || index >= int64(len(elems)) {
+			return nil, notFoundError(fmt.Sprintf(
+				"index %d out of range for list of length %d", index, len(elems)))
+		}
+
+		return elems[index], nil
+
+	case proto.Value_MAP:
+		for _, elt := range v.Value.(*proto.Value_ValueMap).ValueMap.Elems {
+			switch k := elt.Key.Value.(type) {
+			case *proto.Value_ValueString:
+				if !isIndex && k.ValueString == reflect.ValueOf(p).String() {
+					return elt.Value, nil
+				}
+
+			case *proto.Value_ValueInt:
+				if isIndex && k.ValueInt == index {
+					return elt.Value, nil
+				}
+			}
+		}
+
+		if isIndex {
+			return nil, notFoundError(fmt.Sprintf("key %d not found", index))
+		}
+
+		return nil, notFoundError(fmt.Sprintf("key %q not found", p))
+
+	default:
+		return nil, fmt.Errorf("cannot look up %v in a value of type %s", p, v.Type)
+	}
+}
+
+// AsBool returns the bool of a BOOL value, or an error if v isn't a BOOL.
+// The value is returned directly rather than in an interface{}, so this
+// doesn't allocate.
+//
+// With WithEmptyStringAsZero, an empty STRING returns false, as does one of
+// only whitespace with WithTrimSpace. Other options have no effect.
+func AsBool(v *proto.Value, opts ...DecodeOption) (bool, error) {
+	if len(opts) > 0 && v != nil && v.Type == proto.Value_STRING {
+		d := getDecoder(opts)
+		defer putDecoder(d)
+
+		if d.emptyStringAsZero && d.trim(v.GetValueString()) == "" {
+			return false, nil
+		}
+	}
+
+	return ExpectBool(v)
+}
+
+// AsInt64 returns the integer of an INT value, or an error if v isn't an
+// INT. Like AsBool, this doesn't allocate.
+//
+// With WithLenientNumbers, a FLOAT that is a whole number is converted to
+// an integer, and with WithEmptyStringAsZero an empty STRING returns 0.
+// Other options have no effect.
+func AsInt64(v *proto.Value, opts ...DecodeOption) (int64, error) {
+	if len(opts) > 0 && v != nil && v.Value != nil {
+		d := getDecoder(opts)
+		defer putDecoder(d)
+
+		switch {
+		case d.lenientNumbers && v.Type == proto.Value_FLOAT:
+			return floatToInt64(v.GetValueFloat())
+
+		case d.emptyStringAsZero && v.Type == proto.Value_STRING && d.trim(v.GetValueString()) == "":
+			return 0, nil
+		}
+	}
+
+	return ExpectInt64(v)
+}
+
+// AsFloat64 returns the float of a FLOAT value, or an error if v isn't a
+// FLOAT. Like AsBool, this doesn't allocate.
+//
+// With WithLenientNumbers, an INT is converted to a float, which must be
+// exact if WithExactFloats is also given. With WithEmptyStringAsZero an
+// empty STRING returns 0. Other options have no effect.
+func AsFloat64(v *proto.Value, opts ...DecodeOption) (float64, error) {
+	if len(opts) > 0 && v != nil && v.Value != nil {
+		d := getDecoder(opts)
+		defer putDecoder(d)
+
+		switch {
+		case d.lenientNumbers && v.Type == proto.Value_INT:
+			value := v.GetValueInt()
+			if d.exactFloat {
+				if err := checkFloatPrecision(value, 64); err != nil {
+					return 0, err
+				}
+			}
+
+			return float64(value), nil
+
+		case d.emptyStringAsZero && v.Type == proto.Value_STRING && d.trim(v.GetValueString()) == "":
+			return 0, nil
+		}
+	}
+
+	return ExpectFloat64(v)
+}
+
+// AsString returns the string of a STRING value, or an error if v isn't a
+// STRING. Like AsBool, this doesn't allocate.
+//
+// With WithStrictUTF8, a string that isn't valid UTF-8 returns an error.
+// Other options have no effect.
+func AsString(v *proto.Value, opts ...DecodeOption) (string, error) {
+	s, err := ExpectString(v)
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts) > 0 {
+		d := getDecoder(opts)
+		defer putDecoder(d)
+
+		if d.strictUTF8 && !utf8.ValidString(s) {
+			return "", errors.New("invalid UTF-8 in string value")
+		}
+	}
+
+	return s, nil
+}
+
+// ExpectBool returns the bool of a BOOL value, or an error if v isn't a
+// BOOL. Unlike AsBool, it takes no options, so no other type of value is
+// ever accepted. The Expect functions are for validating data at the
+// protocol level, where a value of the wrong type must be rejected rather
+// than converted. Their errors all have the form "expected BOOL, got INT".
+func ExpectBool(v *proto.Value) (bool, error) {
+	if err := checkAs(v, proto.Value_BOOL); err != nil {
+		return false, err
+	}
+
+	return v.Value.(*proto.Value_ValueBool).ValueBool, nil
+}
+
+// ExpectInt64 returns the integer of an INT value, or an error if v isn't
+// an INT. Like ExpectBool, a FLOAT or STRING is never converted.
+func ExpectInt64(v *proto.Value) (int64, error) {
+	if err := checkAs(v, proto.Value_INT); err != nil {
+		return 0, err
+	}
+
+	return v.Value.(*proto.Value_ValueInt).ValueInt, nil
+}
+
+// ExpectFloat64 returns the float of a FLOAT value, or an error if v isn't
+// a FLOAT. Like ExpectBool, an INT or STRING is never converted.
+func ExpectFloat64(v *proto.Value) (float64, error) {
+	if err := checkAs(v, proto.Value_FLOAT); err != nil {
+		return 0, err
+	}
+
+	return v.Value.(*proto.Value_ValueFloat).ValueFloat, nil
+}
+
+// ExpectString returns the string of a STRING value, or an error if v
+// isn't a STRING. Like ExpectBool, no other type is converted to a string.
+func ExpectString(v *proto.Value) (string, error) {
+	if err := checkAs(v, proto.Value_STRING); err != nil {
+		return "", err
+	}
+
+	return v.Value.(*proto.Value_ValueString).ValueString, nil
+}
+
+// AsList returns the elements of a LIST value, or an error if v isn't a
+// LIST.
+func AsList(v *proto.Value) ([]*proto.Value, error) {
+	if err := checkAs(v, proto.Value_LIST); err != nil {
+		return nil, err
+	}
+
+	return v.Value.(*proto.Value_ValueList).ValueList.Elems, nil
+}
+
+// AsMap returns the entries of a MAP value, or an error if v isn't a MAP.
+// The entries are in the order they appear in the value.
+func AsMap(v *proto.Value) ([]*proto.Value_KV, error) {
+	if err := checkAs(v, proto.Value_MAP); err != nil {
+		return nil, err
+	}
+
+	return v.Value.(*proto.Value_ValueMap).ValueMap.Elems, nil
+}
+
+// checkAs returns an error if v doesn't have type t and a payload.
+func checkAs(v *proto.Value, t proto.Value_Type) error {
+	if v == nil {
+		return fmt.Errorf("expected %s, got nil value", t)
+	}
+	if v.Type != t {
+		return fmt.Errorf("expected %s, got %s", t, v.Type)
+	}
+
+	return checkPayload(v)
+}
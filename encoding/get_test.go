@@ -0,0 +1,296 @@
+package encoding
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestGet(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "first", "count": 2},
+			map[string]interface{}{"name": "second", "ratio": 0.5},
+		},
+		"codes":   map[int]string{404: "not found"},
+		"enabled": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Name     string
+		Path     []interface{}
+		Expected interface{}
+		Err      bool
+	}{
+		{"empty path", nil, nil, false},
+		{"string", []interface{}{"items", 1, "name"}, "second", false},
+		{"int", []interface{}{"items", 0, "count"}, int64(2), false},
+		{"float", []interface{}{"items", 1, "ratio"}, 0.5, false},
+		{"bool", []interface{}{"enabled"}, true, false},
+		{"int key", []interface{}{"codes", 404}, "not found", false},
+		{"uint index", []interface{}{"items", uint8(0), "name"}, "first", false},
+		{"missing key", []interface{}{"items", 0, "missing"}, nil, true},
+		{"missing int key", []interface{}{"codes", 500}, nil, true},
+		{"index out of range", []interface{}{"items", 2}, nil, true},
+		{"negative index", []interface{}{"items", -1}, nil, true},
+		{"key in list", []interface{}{"items", "name"}, nil, true},
+		{"key in scalar", []interface{}{"enabled", "name"}, nil, true},
+		{"invalid path element", []interface{}{1.5}, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := Get(v, tc.Path...)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			switch expected := tc.Expected.(type) {
+			case nil:
+				if actual != v {
+					t.Fatalf("bad: %#v", actual)
+				}
+
+			case string:
+				s, err := AsString(actual)
+				if err != nil || s != expected {
+					t.Fatalf("bad: %#v %s", s, err)
+				}
+
+			case int64:
+				n, err := AsInt64(actual)
+				if err != nil || n != expected {
+					t.Fatalf("bad: %#v %s", n, err)
+				}
+
+			case float64:
+				f, err := AsFloat64(actual)
+				if err != nil || f != expected {
+					t.Fatalf("bad: %#v %s", f, err)
+				}
+
+			case bool:
+				b, err := AsBool(actual)
+				if err != nil || b != expected {
+					t.Fatalf("bad: %#v %s", b, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAs_wrongType(t *testing.T) {
+	v, err := GoToValue(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := AsString(v); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsFloat64(v); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsBool(v); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsList(v); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsMap(v); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsMap(&proto.Value{Type: proto.Value_MAP}); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsInt64(nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestExpect(t *testing.T) {
+	values := map[proto.Value_Type]*proto.Value{}
+	for _, source := range []interface{}{true, 42, 1.5, "a"} {
+		v, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		values[v.Type] = v
+	}
+
+	expect := map[proto.Value_Type]func(*proto.Value) (interface{}, error){
+		proto.Value_BOOL:   func(v *proto.Value) (interface{}, error) { return ExpectBool(v) },
+		proto.Value_INT:    func(v *proto.Value) (interface{}, error) { return ExpectInt64(v) },
+		proto.Value_FLOAT:  func(v *proto.Value) (interface{}, error) { return ExpectFloat64(v) },
+		proto.Value_STRING: func(v *proto.Value) (interface{}, error) { return ExpectString(v) },
+	}
+
+	expected := map[proto.Value_Type]interface{}{
+		proto.Value_BOOL:   true,
+		proto.Value_INT:    int64(42),
+		proto.Value_FLOAT:  1.5,
+		proto.Value_STRING: "a",
+	}
+
+	for typ, f := range expect {
+		for vtyp, v := range values {
+			actual, err := f(v)
+			if vtyp != typ {
+				expectedErr := fmt.Sprintf("expected %s, got %s", typ, vtyp)
+				if err == nil || err.Error() != expectedErr {
+					t.Fatalf("bad: %s from %s: %v", typ, vtyp, err)
+				}
+
+				continue
+			}
+
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if actual != expected[typ] {
+				t.Fatalf("bad: %#v", actual)
+			}
+		}
+
+		if _, err := f(nil); err == nil || err.Error() != fmt.Sprintf("expected %s, got nil value", typ) {
+			t.Fatalf("bad: %v", err)
+		}
+	}
+}
+
+func TestAsMap(t *testing.T) {
+	v := testMapValue("a", "1", "b", "2")
+	entries, err := AsMap(v)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(entries) != 2 ||
+		entries[0].Key.GetValueString() != "a" ||
+		entries[1].Value.GetValueString() != "2" {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
+
+func TestDecodePath(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"region": "us-east-1",
+			"zones":  []string{"a", "b"},
+		},
+		"items": []int{1, 2},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := DecodePath(v, reflect.TypeOf(""), "metadata", "region")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual != "us-east-1" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	actual, err = DecodePath(v, reflect.TypeOf([]string{}), "metadata", "zones")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"a", "b"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Missing keys and indexes are a NotFoundError
+	for _, path := range [][]interface{}{{"metadata", "missing"}, {"items", 2}} {
+		_, err := DecodePath(v, reflect.TypeOf(""), path...)
+		nf, ok := err.(*NotFoundError)
+		if !ok {
+			t.Fatalf("bad: %#v", err)
+		}
+		if nf.Index != 1 {
+			t.Fatalf("bad: %d", nf.Index)
+		}
+	}
+
+	// Values that can't be decoded, or paths that don't apply, aren't
+	_, err = DecodePath(v, reflect.TypeOf(0), "metadata", "region")
+	if _, ok := err.(*NotFoundError); err == nil || ok {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	_, err = DecodePath(v, reflect.TypeOf(""), "items", "name")
+	if _, ok := err.(*NotFoundError); err == nil || ok {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestAs_options(t *testing.T) {
+	float := &proto.Value{Type: proto.Value_FLOAT, Value: &proto.Value_ValueFloat{ValueFloat: 3}}
+	fraction := &proto.Value{Type: proto.Value_FLOAT, Value: &proto.Value_ValueFloat{ValueFloat: 3.5}}
+	integer := &proto.Value{Type: proto.Value_INT, Value: &proto.Value_ValueInt{ValueInt: 1<<53 + 1}}
+	empty := toValue_string("")
+	invalid := toValue_string("\xff")
+
+	if n, err := AsInt64(float, WithLenientNumbers()); err != nil || n != 3 {
+		t.Fatalf("bad: %d %v", n, err)
+	}
+	if _, err := AsInt64(fraction, WithLenientNumbers()); err == nil {
+		t.Fatal("should error")
+	}
+	if _, err := AsInt64(float); err == nil {
+		t.Fatal("should error")
+	}
+	if n, err := AsInt64(empty, WithEmptyStringAsZero()); err != nil || n != 0 {
+		t.Fatalf("bad: %d %v", n, err)
+	}
+
+	if f, err := AsFloat64(integer, WithLenientNumbers()); err != nil || f != 1<<53 {
+		t.Fatalf("bad: %v %v", f, err)
+	}
+	if _, err := AsFloat64(integer, WithLenientNumbers(), WithExactFloats()); err == nil {
+		t.Fatal("should error")
+	}
+	if f, err := AsFloat64(empty, WithEmptyStringAsZero()); err != nil || f != 0 {
+		t.Fatalf("bad: %v %v", f, err)
+	}
+
+	if b, err := AsBool(empty, WithEmptyStringAsZero()); err != nil || b {
+		t.Fatalf("bad: %v %v", b, err)
+	}
+	if _, err := AsBool(empty); err == nil {
+		t.Fatal("should error")
+	}
+
+	if s, err := AsString(invalid); err != nil || s != "\xff" {
+		t.Fatalf("bad: %q %v", s, err)
+	}
+	if _, err := AsString(invalid, WithStrictUTF8()); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestAs_allocs(t *testing.T) {
+	integer := &proto.Value{Type: proto.Value_INT, Value: &proto.Value_ValueInt{ValueInt: 1000}}
+	float := &proto.Value{Type: proto.Value_FLOAT, Value: &proto.Value_ValueFloat{ValueFloat: 1000}}
+	str := toValue_string("foo")
+	lenient := WithLenientNumbers()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		AsInt64(integer)
+		AsInt64(float, lenient)
+		AsFloat64(float)
+		AsString(str)
+	})
+	if allocs != 0 {
+		t.Fatalf("bad: %v allocs", allocs)
+	}
+}
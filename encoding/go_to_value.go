@@ -1,9 +1,11 @@
 package encoding
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 
 	"github.com/hashicorp/sentinel-sdk"
 	"github.com/hashicorp/sentinel-sdk/proto/go"
@@ -17,22 +19,127 @@ import (
 //
 // The primitive types byte and rune are aliases to integer types (as
 // defined by the Go spec) and are treated as integers in conversion.
-func GoToValue(raw interface{}) (*proto.Value, error) {
-	return toValue_reflect(reflect.ValueOf(raw))
+//
+// Types with a Converter registered with RegisterConverter are converted
+// with that converter, and types that implement encoding.TextMarshaler are
+// converted to a STRING. See RegisterConverter for the built-in converters.
+//
+// Maps are iterated without any locking, so the caller must ensure that no
+// map within the value is modified concurrently while it is converted.
+// Concurrent modification isn't a recoverable error: the Go runtime aborts
+// the process with "concurrent map iteration and map write". Copying the
+// map first doesn't help unless the copy is made while holding the lock
+// that guards the map. Maps that are shared between goroutines without
+// such a lock should be stored in a *sync.Map, which is converted to a map
+// using its Range method and is safe to convert while being modified.
+//
+// Options can be given to change how values are converted. Options don't
+// apply to values converted by a Converter.
+func GoToValue(raw interface{}, opts ...EncodeOption) (*proto.Value, error) {
+	e := &encoder{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e.toValue_reflect(reflect.ValueOf(raw))
+}
+
+// ChanToValue receives from the channel ch until it is closed and converts
+// the values received to a LIST, in the order they were received, for
+// imports that collect results from producer goroutines. Each value is
+// converted the same way as by GoToValue.
+//
+// ch must be a channel that can be received from, which GoToValue doesn't
+// accept. If a value can't be converted, the error is returned right away
+// and the rest of the channel isn't drained, so producers blocked on
+// sending must be stopped some other way, such as with the context for
+// ChanToValueContext.
+func ChanToValue(ch interface{}, opts ...EncodeOption) (*proto.Value, error) {
+	return ChanToValueContext(context.Background(), ch, opts...)
+}
+
+// ChanToValueContext is like ChanToValue, but stops receiving and returns
+// the error of ctx if it is done before the channel is closed.
+func ChanToValueContext(ctx context.Context, ch interface{}, opts ...EncodeOption) (*proto.Value, error) {
+	v := reflect.ValueOf(ch)
+	if v.Kind() != reflect.Chan {
+		return nil, fmt.Errorf("expected a channel, got %T", ch)
+	}
+	if v.Type().ChanDir()&reflect.RecvDir == 0 {
+		return nil, fmt.Errorf("cannot receive from channel of type %s", v.Type())
+	}
+	if v.IsNil() {
+		return nil, errors.New("cannot receive from nil channel")
+	}
+
+	e := &encoder{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: v},
+	}
+
+	var vs []*proto.Value
+	for {
+		chosen, elem, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return nil, ctx.Err()
+		}
+		if !ok {
+			break
+		}
+
+		value, err := e.toValue_reflect(elem)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %s", len(vs), err)
+		}
+
+		vs = append(vs, value)
+	}
+
+	if vs == nil {
+		vs = []*proto.Value{}
+	}
+
+	return &proto.Value{
+		Type: proto.Value_LIST,
+		Value: &proto.Value_ValueList{
+			ValueList: &proto.Value_List{
+				Elems: vs,
+			},
+		},
+	}, nil
 }
 
+// toValue_reflect converts v with the default options.
 func toValue_reflect(v reflect.Value) (*proto.Value, error) {
+	return (&encoder{}).toValue_reflect(v)
+}
+
+func (e *encoder) toValue_reflect(v reflect.Value) (*proto.Value, error) {
 	// Null pointer
 	if !v.IsValid() {
 		return &proto.Value{Type: proto.Value_NULL}, nil
 	}
 
+	// A registered converter takes precedence over the conversion for
+	// the kind of the type.
+	if c := lookupConverter(v.Type()); c != nil && c.Encode != nil && v.CanInterface() {
+		return c.Encode(v.Interface())
+	}
+	if mv, ok := textMarshaler(v); ok {
+		return textConverter.Encode(mv.Interface())
+	}
+
 	// Decode depending on the type. We need to redo all of the primitives
 	// above unfortunately since they may fall to this point if they're
 	// wrapped in an interface type.
 	switch v.Kind() {
 	case reflect.Interface:
-		return toValue_reflect(v.Elem())
+		return e.toValue_reflect(v.Elem())
 
 	case reflect.Ptr:
 		switch v.Interface() {
@@ -43,7 +150,7 @@ func toValue_reflect(v reflect.Value) (*proto.Value, error) {
 			return &proto.Value{Type: proto.Value_UNDEFINED}, nil
 		}
 
-		return toValue_reflect(v.Elem())
+		return e.toValue_reflect(v.Elem())
 
 	case reflect.Bool:
 		return &proto.Value{
@@ -64,28 +171,33 @@ func toValue_reflect(v reflect.Value) (*proto.Value, error) {
 		}, nil
 
 	case reflect.Float32, reflect.Float64:
+		if e.floatFormat != "" {
+			return toValue_string(fmt.Sprintf(e.floatFormat, v.Float())), nil
+		}
+
 		return &proto.Value{
 			Type:  proto.Value_FLOAT,
 			Value: &proto.Value_ValueFloat{ValueFloat: v.Float()},
 		}, nil
 
 	case reflect.Complex64, reflect.Complex128:
+		if e.complexShape != 0 {
+			return toValue_complex(v.Complex(), e.complexShape), nil
+		}
+
 		return nil, errors.New("cannot convert complex number to Sentinel value")
 
 	case reflect.String:
-		return &proto.Value{
-			Type:  proto.Value_STRING,
-			Value: &proto.Value_ValueString{ValueString: v.String()},
-		}, nil
+		return toValue_string(v.String()), nil
 
 	case reflect.Array, reflect.Slice:
-		return toValue_array(v)
+		return e.toValue_array(v)
 
 	case reflect.Map:
-		return toValue_map(v)
+		return e.toValue_map(v)
 
 	case reflect.Struct:
-		return toValue_struct(v)
+		return e.toValue_struct(v)
 
 	case reflect.Chan:
 		return nil, errors.New("cannot convert channel to Sentinel value")
@@ -97,15 +209,60 @@ func toValue_reflect(v reflect.Value) (*proto.Value, error) {
 	return nil, fmt.Errorf("cannot convert type %s to Sentinel value", v.Kind())
 }
 
-func toValue_array(v reflect.Value) (*proto.Value, error) {
+func toValue_string(s string) *proto.Value {
+	return &proto.Value{
+		Type:  proto.Value_STRING,
+		Value: &proto.Value_ValueString{ValueString: s},
+	}
+}
+
+func toValue_float(f float64) *proto.Value {
+	return &proto.Value{
+		Type:  proto.Value_FLOAT,
+		Value: &proto.Value_ValueFloat{ValueFloat: f},
+	}
+}
+
+func toValue_complex(c complex128, shape ComplexShape) *proto.Value {
+	if shape == ComplexMap {
+		return &proto.Value{
+			Type: proto.Value_MAP,
+			Value: &proto.Value_ValueMap{
+				ValueMap: &proto.Value_Map{
+					Elems: []*proto.Value_KV{
+						{Key: toValue_string("re"), Value: toValue_float(real(c))},
+						{Key: toValue_string("im"), Value: toValue_float(imag(c))},
+					},
+				},
+			},
+		}
+	}
+
+	return &proto.Value{
+		Type: proto.Value_LIST,
+		Value: &proto.Value_ValueList{
+			ValueList: &proto.Value_List{
+				Elems: []*proto.Value{toValue_float(real(c)), toValue_float(imag(c))},
+			},
+		},
+	}
+}
+
+func (e *encoder) toValue_array(v reflect.Value) (*proto.Value, error) {
 	vs := make([]*proto.Value, v.Len())
-	for i := range vs {
-		elem, err := toValue_reflect(v.Index(i))
-		if err != nil {
+	if e.workers > 1 && len(vs) >= parallelMinLen {
+		if err := e.toValue_elemsParallel(v, vs); err != nil {
 			return nil, err
 		}
+	} else {
+		for i := range vs {
+			elem, err := e.toValue_reflect(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
 
-		vs[i] = elem
+			vs[i] = elem
+		}
 	}
 
 	return &proto.Value{
@@ -118,17 +275,67 @@ func toValue_array(v reflect.Value) (*proto.Value, error) {
 	}, nil
 }
 
-func toValue_map(v reflect.Value) (*proto.Value, error) {
+// toValue_elemsParallel converts the elements of the slice or array v into
+// vs, splitting them into contiguous chunks for each worker. The error for
+// the first element that fails is returned, so the result doesn't depend on
+// scheduling.
+func (e *encoder) toValue_elemsParallel(v reflect.Value, vs []*proto.Value) error {
+	// Nested slices are converted sequentially by each worker
+	elemE := *e
+	elemE.workers = 0
+
+	workers := e.workers
+	if workers > len(vs) {
+		workers = len(vs)
+	}
+	chunk := (len(vs) + workers - 1) / workers
+
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > len(vs) {
+			end = len(vs)
+		}
+
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				elem, err := elemE.toValue_reflect(v.Index(i))
+				if err != nil {
+					errs[w] = err
+					return
+				}
+
+				vs[i] = elem
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) toValue_map(v reflect.Value) (*proto.Value, error) {
 	vs := make([]*proto.Value_KV, v.Len())
 	for i, keyV := range v.MapKeys() {
-		key, err := toValue_reflect(keyV)
+		// Keys are converted like any other value, so the keys of a map
+		// with interface{} keys may have different types.
+		key, err := e.toValue_reflect(keyV)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("key %v: %s", keyV, err)
 		}
 
-		value, err := toValue_reflect(v.MapIndex(keyV))
+		value, err := e.toValue_reflect(v.MapIndex(keyV))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("element for key %v: %s", keyV, err)
 		}
 
 		vs[i] = &proto.Value_KV{
@@ -147,43 +354,87 @@ func toValue_map(v reflect.Value) (*proto.Value, error) {
 	}, nil
 }
 
-func toValue_struct(v reflect.Value) (*proto.Value, error) {
+func toValue_syncMap(m *sync.Map) (*proto.Value, error) {
+	var vs []*proto.Value_KV
+	var err error
+	m.Range(func(k, v interface{}) bool {
+		var key, value *proto.Value
+		key, err = toValue_reflect(reflect.ValueOf(k))
+		if err != nil {
+			return false
+		}
+
+		value, err = toValue_reflect(reflect.ValueOf(v))
+		if err != nil {
+			return false
+		}
+
+		vs = append(vs, &proto.Value_KV{
+			Key:   key,
+			Value: value,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{
+				Elems: vs,
+			},
+		},
+	}, nil
+}
+
+func (e *encoder) toValue_struct(v reflect.Value) (*proto.Value, error) {
 	// Get the type since we need this to determine what is exported,
 	// field tags, etc.
 	t := v.Type()
 
-	vs := make([]*proto.Value_KV, v.NumField())
-	for i := range vs {
-		field := t.Field(i)
+	info, err := cachedStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
 
-		// If PkgPath is non-empty, this is unexported and can be ignored
-		if field.PkgPath != "" {
+	vs := make([]*proto.Value_KV, 0, len(info.Fields))
+	for _, field := range info.Fields {
+		if field.OmitEmpty && IsZeroValue(v.Field(field.Index)) {
 			continue
 		}
 
-		// Determine the map key
-		key := field.Name
-		if v, ok := field.Tag.Lookup("sentinel"); ok {
-			// A blank value means to not export this value
-			if v == "" {
-				continue
-			}
-
-			key = v
-		}
-
 		// Convert the value
-		value, err := toValue_reflect(v.Field(i))
-		if err != nil {
-			return nil, err
+		var value *proto.Value
+		if field.Encoding != "" {
+			value = toValue_string(encodeByteArray(v.Field(field.Index), field.Encoding))
+		} else {
+			var err error
+			value, err = e.toValue_reflect(v.Field(field.Index))
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		vs[i] = &proto.Value_KV{
+		vs = append(vs, &proto.Value_KV{
 			Value: value,
-			Key: &proto.Value{
-				Type:  proto.Value_STRING,
-				Value: &proto.Value_ValueString{ValueString: key},
-			},
+			Key:   toValue_string(field.Key),
+		})
+	}
+
+	if info.Remaining != nil {
+		m := v.Field(info.Remaining.Index)
+		for _, key := range info.remainingKeys(v) {
+			value, err := e.toValue_reflect(m.MapIndex(reflect.ValueOf(key)))
+			if err != nil {
+				return nil, fmt.Errorf("element for key %q: %s", key, err)
+			}
+
+			vs = append(vs, &proto.Value_KV{
+				Value: value,
+				Key:   toValue_string(key),
+			})
 		}
 	}
 
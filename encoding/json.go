@@ -0,0 +1,254 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// ValueToJSON converts a protobuf Value structure directly to JSON.
+//
+// JSON has no representation for undefined, so UNDEFINED values are omitted
+// when they are the value of a map entry and are otherwise encoded as JSON
+// null, the same as NULL. JSON object keys must be strings, so map keys
+// that are BOOL, INT or FLOAT are formatted as strings. Maps with LIST or
+// MAP keys cannot be converted and return an error.
+func ValueToJSON(v *proto.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// JSONToValue converts JSON directly to a protobuf Value structure.
+//
+// JSON numbers that are integers representable as an int64 become INT
+// values and all other numbers become FLOAT values. Object keys become
+// STRING keys and are kept in the order they appear in the document.
+// The result never contains UNDEFINED values.
+func JSONToValue(data []byte) (*proto.Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	// There must be only a single JSON value in the data
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after top-level value")
+	}
+
+	return v, nil
+}
+
+func writeJSON(buf *bytes.Buffer, v *proto.Value) error {
+	if err := checkPayload(v); err != nil {
+		return err
+	}
+
+	switch v.Type {
+	case proto.Value_UNDEFINED, proto.Value_NULL:
+		buf.WriteString("null")
+
+	case proto.Value_BOOL:
+		buf.WriteString(strconv.FormatBool(v.Value.(*proto.Value_ValueBool).ValueBool))
+
+	case proto.Value_INT:
+		buf.WriteString(strconv.FormatInt(v.Value.(*proto.Value_ValueInt).ValueInt, 10))
+
+	case proto.Value_FLOAT:
+		f := v.Value.(*proto.Value_ValueFloat).ValueFloat
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("cannot convert float %v to JSON", f)
+		}
+
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+
+	case proto.Value_STRING:
+		writeJSONString(buf, v.Value.(*proto.Value_ValueString).ValueString)
+
+	case proto.Value_LIST:
+		buf.WriteByte('[')
+		for i, elem := range v.Value.(*proto.Value_ValueList).ValueList.Elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSON(buf, elem); err != nil {
+				return fmt.Errorf("element %d: %s", i, err)
+			}
+		}
+		buf.WriteByte(']')
+
+	case proto.Value_MAP:
+		buf.WriteByte('{')
+		first := true
+		for _, elem := range v.Value.(*proto.Value_ValueMap).ValueMap.Elems {
+			// Undefined values are omitted from objects entirely
+			if elem.Value.Type == proto.Value_UNDEFINED {
+				continue
+			}
+
+			key, err := jsonKey(elem.Key)
+			if err != nil {
+				return err
+			}
+
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, elem.Value); err != nil {
+				return fmt.Errorf("element for key %s: %s", elem.Key.String(), err)
+			}
+		}
+		buf.WriteByte('}')
+
+	default:
+		return fmt.Errorf("cannot convert to JSON: %s", v.Type)
+	}
+
+	return nil
+}
+
+// jsonKey returns the string form of a map key for use as a JSON object key.
+func jsonKey(v *proto.Value) (string, error) {
+	if err := checkPayload(v); err != nil {
+		return "", err
+	}
+
+	switch v.Type {
+	case proto.Value_BOOL:
+		return strconv.FormatBool(v.Value.(*proto.Value_ValueBool).ValueBool), nil
+
+	case proto.Value_INT:
+		return strconv.FormatInt(v.Value.(*proto.Value_ValueInt).ValueInt, 10), nil
+
+	case proto.Value_FLOAT:
+		return strconv.FormatFloat(v.Value.(*proto.Value_ValueFloat).ValueFloat, 'g', -1, 64), nil
+
+	case proto.Value_STRING:
+		return v.Value.(*proto.Value_ValueString).ValueString, nil
+
+	default:
+		return "", fmt.Errorf("cannot convert map key to JSON: %s", v.Type)
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	// Marshaling a string can't fail, so the error is ignored.
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func readJSON(dec *json.Decoder) (*proto.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %s", err)
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return &proto.Value{Type: proto.Value_NULL}, nil
+
+	case bool:
+		return &proto.Value{
+			Type:  proto.Value_BOOL,
+			Value: &proto.Value_ValueBool{ValueBool: tok},
+		}, nil
+
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return &proto.Value{
+				Type:  proto.Value_INT,
+				Value: &proto.Value_ValueInt{ValueInt: i},
+			}, nil
+		}
+
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON number %q: %s", tok, err)
+		}
+
+		return &proto.Value{
+			Type:  proto.Value_FLOAT,
+			Value: &proto.Value_ValueFloat{ValueFloat: f},
+		}, nil
+
+	case string:
+		return &proto.Value{
+			Type:  proto.Value_STRING,
+			Value: &proto.Value_ValueString{ValueString: tok},
+		}, nil
+
+	case json.Delim:
+		switch tok {
+		case '[':
+			var elems []*proto.Value
+			for dec.More() {
+				elem, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				elems = append(elems, elem)
+			}
+
+			// Consume the closing delimiter
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %s", err)
+			}
+
+			return &proto.Value{
+				Type: proto.Value_LIST,
+				Value: &proto.Value_ValueList{
+					ValueList: &proto.Value_List{Elems: elems},
+				},
+			}, nil
+
+		case '{':
+			var elems []*proto.Value_KV
+			for dec.More() {
+				// Object keys are always strings
+				key, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				elems = append(elems, &proto.Value_KV{Key: key, Value: value})
+			}
+
+			// Consume the closing delimiter
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %s", err)
+			}
+
+			return &proto.Value{
+				Type: proto.Value_MAP,
+				Value: &proto.Value_ValueMap{
+					ValueMap: &proto.Value_Map{Elems: elems},
+				},
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid JSON: unexpected token %v", tok)
+}
@@ -0,0 +1,249 @@
+package encoding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestValueToJSON(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Expected string
+		Err      bool
+	}{
+		{
+			"null",
+			sdk.Null,
+			`null`,
+			false,
+		},
+
+		{
+			"undefined",
+			sdk.Undefined,
+			`null`,
+			false,
+		},
+
+		{
+			"scalars",
+			[]interface{}{true, 42, 1.5, "foo"},
+			`[true,42,1.5,"foo"]`,
+			false,
+		},
+
+		{
+			"string escaping",
+			"a \"quoted\"\nvalue",
+			`"a \"quoted\"\nvalue"`,
+			false,
+		},
+
+		{
+			"map with undefined value",
+			map[string]interface{}{"foo": sdk.Undefined},
+			`{}`,
+			false,
+		},
+
+		{
+			"map with null value",
+			map[string]interface{}{"foo": sdk.Null},
+			`{"foo":null}`,
+			false,
+		},
+
+		{
+			"map with int key",
+			map[int]string{42: "foo"},
+			`{"42":"foo"}`,
+			false,
+		},
+
+		{
+			"list with undefined element",
+			[]interface{}{1, sdk.Undefined},
+			`[1,null]`,
+			false,
+		},
+
+		{
+			"nested",
+			map[string]interface{}{"foo": []interface{}{map[string]interface{}{"bar": 1}}},
+			`{"foo":[{"bar":1}]}`,
+			false,
+		},
+
+		{
+			"NaN",
+			math.NaN(),
+			``,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("GoToValue err: %s", err)
+			}
+
+			actual, err := ValueToJSON(v)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			if string(actual) != tc.Expected {
+				t.Fatalf("bad: %s", actual)
+			}
+		})
+	}
+}
+
+func TestValueToJSON_listKey(t *testing.T) {
+	v := &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{
+				Elems: []*proto.Value_KV{
+					{
+						Key: &proto.Value{
+							Type: proto.Value_LIST,
+							Value: &proto.Value_ValueList{
+								ValueList: &proto.Value_List{},
+							},
+						},
+						Value: &proto.Value{Type: proto.Value_NULL},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := ValueToJSON(v); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestJSONToValue(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   string
+		Expected interface{}
+		Err      bool
+	}{
+		{
+			"null",
+			`null`,
+			sdk.Null,
+			false,
+		},
+
+		{
+			"int",
+			`42`,
+			int64(42),
+			false,
+		},
+
+		{
+			"float",
+			`1.5`,
+			float64(1.5),
+			false,
+		},
+
+		{
+			"large int is float",
+			`1e100`,
+			float64(1e100),
+			false,
+		},
+
+		{
+			"string",
+			`"foo"`,
+			"foo",
+			false,
+		},
+
+		{
+			"list",
+			`[1, "foo", true, null]`,
+			[]interface{}{int64(1), "foo", true, sdk.Null},
+			false,
+		},
+
+		{
+			"object",
+			`{"foo": {"bar": [1, 2]}}`,
+			map[string]interface{}{
+				"foo": map[string]interface{}{
+					"bar": []int64{1, 2},
+				},
+			},
+			false,
+		},
+
+		{
+			"invalid",
+			`{"foo":`,
+			nil,
+			true,
+		},
+
+		{
+			"trailing data",
+			`1 2`,
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := JSONToValue([]byte(tc.Source))
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			actual, err := ValueToGo(v, interfaceTyp)
+			if err != nil {
+				t.Fatalf("ValueToGo err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestJSON_roundTrip(t *testing.T) {
+	src := `{"a":1,"b":[true,null,"x",2.5],"c":{"d":{}}}`
+	v, err := JSONToValue([]byte(src))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToJSON(v)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(actual) != src {
+		t.Fatalf("bad: %s", actual)
+	}
+}
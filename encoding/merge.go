@@ -0,0 +1,159 @@
+package encoding
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// MergeConflict specifies how Merge resolves two different values for the
+// same key.
+type MergeConflict int
+
+const (
+	// MergeRightWins uses the value from the right (second) value.
+	MergeRightWins MergeConflict = iota
+
+	// MergeLeftWins keeps the value from the left (first) value.
+	MergeLeftWins
+
+	// MergeError returns an error.
+	MergeError
+)
+
+// MergePolicy configures Merge. The zero value replaces conflicting values
+// and lists with the value from the right.
+type MergePolicy struct {
+	// Conflict is how conflicting values are resolved. Values conflict
+	// if they aren't both MAPs (or both LISTs with ConcatLists set) and
+	// aren't equal.
+	Conflict MergeConflict
+
+	// ConcatLists, if set, merges two LISTs by appending the elements of
+	// the right list to the left list. Otherwise LISTs are treated like
+	// any other conflicting value.
+	ConcatLists bool
+}
+
+// Merge deep-merges two values. If both a and b are MAPs, the result has
+// the keys of both: keys only in one map keep their value, and the values
+// for keys in both maps are merged recursively. Keys are ordered as in a,
+// followed by the keys only in b. All other values are merged according to
+// the policy. If either value is nil, the other is returned.
+//
+// Neither a nor b is modified, but the result may share values with them.
+func Merge(a, b *proto.Value, policy MergePolicy) (*proto.Value, error) {
+	return mergeValue(a, b, policy, "")
+}
+
+func mergeValue(a, b *proto.Value, policy MergePolicy, path string) (*proto.Value, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+
+	for _, v := range []*proto.Value{a, b} {
+		if err := checkPayload(v); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case a.Type == proto.Value_MAP && b.Type == proto.Value_MAP:
+		return mergeMap(a, b, policy, path)
+
+	case a.Type == proto.Value_LIST && b.Type == proto.Value_LIST && policy.ConcatLists:
+		as := a.Value.(*proto.Value_ValueList).ValueList.Elems
+		bs := b.Value.(*proto.Value_ValueList).ValueList.Elems
+		elems := make([]*proto.Value, 0, len(as)+len(bs))
+		elems = append(elems, as...)
+		elems = append(elems, bs...)
+		return &proto.Value{
+			Type: proto.Value_LIST,
+			Value: &proto.Value_ValueList{
+				ValueList: &proto.Value_List{Elems: elems},
+			},
+		}, nil
+	}
+
+	// Equal values don't conflict, regardless of the policy
+	if Equal(a, b) {
+		return a, nil
+	}
+
+	switch policy.Conflict {
+	case MergeRightWins:
+		return b, nil
+
+	case MergeLeftWins:
+		return a, nil
+
+	case MergeError:
+		if path == "" {
+			return nil, fmt.Errorf("conflicting values: %s and %s", a.Type, b.Type)
+		}
+
+		return nil, fmt.Errorf("conflicting values for key %s", path)
+
+	default:
+		return nil, fmt.Errorf("unknown merge conflict policy %d", policy.Conflict)
+	}
+}
+
+func mergeMap(a, b *proto.Value, policy MergePolicy, path string) (*proto.Value, error) {
+	as := a.Value.(*proto.Value_ValueMap).ValueMap.Elems
+	bs := b.Value.(*proto.Value_ValueMap).ValueMap.Elems
+
+	// Index the keys of b so each key of a can be matched
+	index := make(map[string]int, len(bs))
+	for i, elt := range bs {
+		index[elt.Key.String()] = i
+	}
+
+	merged := make([]bool, len(bs))
+	elems := make([]*proto.Value_KV, 0, len(as)+len(bs))
+	for _, elt := range as {
+		i, ok := index[elt.Key.String()]
+		if !ok {
+			elems = append(elems, elt)
+			continue
+		}
+
+		merged[i] = true
+		value, err := mergeValue(elt.Value, bs[i].Value, policy, mergePath(path, elt.Key))
+		if err != nil {
+			return nil, err
+		}
+
+		elems = append(elems, &proto.Value_KV{Key: elt.Key, Value: value})
+	}
+
+	for i, elt := range bs {
+		if !merged[i] {
+			elems = append(elems, elt)
+		}
+	}
+
+	return &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{Elems: elems},
+		},
+	}, nil
+}
+
+// mergePath returns the path to key within the map at path, for errors.
+func mergePath(path string, key *proto.Value) string {
+	k := key.String()
+	if s, ok := key.Value.(*proto.Value_ValueString); ok {
+		k = fmt.Sprintf("%q", s.ValueString)
+	}
+
+	if path == "" {
+		return k
+	}
+
+	return path + "." + k
+}
@@ -0,0 +1,165 @@
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		Name     string
+		A, B     interface{}
+		Policy   MergePolicy
+		Expected interface{}
+		Err      bool
+	}{
+		{
+			"disjoint keys",
+			map[string]interface{}{"a": 1},
+			map[string]interface{}{"b": "two"},
+			MergePolicy{},
+			map[string]interface{}{"a": int64(1), "b": "two"},
+			false,
+		},
+
+		{
+			"nested maps",
+			map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+			map[string]interface{}{"a": map[string]interface{}{"y": 2}},
+			MergePolicy{},
+			map[string]interface{}{"a": map[string]int64{"x": 1, "y": 2}},
+			false,
+		},
+
+		//-------------------------------------------------------------
+		// Conflicts
+
+		{
+			"right wins",
+			map[string]interface{}{"a": 1},
+			map[string]interface{}{"a": 2},
+			MergePolicy{Conflict: MergeRightWins},
+			map[string]int64{"a": 2},
+			false,
+		},
+
+		{
+			"left wins",
+			map[string]interface{}{"a": 1},
+			map[string]interface{}{"a": 2},
+			MergePolicy{Conflict: MergeLeftWins},
+			map[string]int64{"a": 1},
+			false,
+		},
+
+		{
+			"error",
+			map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			map[string]interface{}{"a": map[string]interface{}{"b": 2}},
+			MergePolicy{Conflict: MergeError},
+			nil,
+			true,
+		},
+
+		{
+			"error with equal values",
+			map[string]interface{}{"a": 1},
+			map[string]interface{}{"a": 1},
+			MergePolicy{Conflict: MergeError},
+			map[string]int64{"a": 1},
+			false,
+		},
+
+		{
+			"type conflict",
+			map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			map[string]interface{}{"a": "replaced"},
+			MergePolicy{},
+			map[string]string{"a": "replaced"},
+			false,
+		},
+
+		//-------------------------------------------------------------
+		// Lists
+
+		{
+			"replace lists",
+			map[string]interface{}{"a": []int{1, 2}},
+			map[string]interface{}{"a": []int{3}},
+			MergePolicy{},
+			map[string]interface{}{"a": []int64{3}},
+			false,
+		},
+
+		{
+			"concat lists",
+			map[string]interface{}{"a": []int{1, 2}},
+			map[string]interface{}{"a": []int{3}},
+			MergePolicy{ConcatLists: true},
+			map[string]interface{}{"a": []int64{1, 2, 3}},
+			false,
+		},
+
+		{
+			"concat lists does not conflict",
+			map[string]interface{}{"a": []int{1, 2}},
+			map[string]interface{}{"a": []int{3}},
+			MergePolicy{Conflict: MergeError, ConcatLists: true},
+			map[string]interface{}{"a": []int64{1, 2, 3}},
+			false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			a, err := GoToValue(tc.A)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			b, err := GoToValue(tc.B)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			v, err := Merge(a, b, tc.Policy)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			actual, err := ValueToGo(v, nil)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestMerge_order(t *testing.T) {
+	a, err := GoToValue(map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b, err := GoToValue(map[string]interface{}{"b": 2, "a": 3})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	v, err := Merge(a, b, MergePolicy{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	elems := v.GetValueMap().Elems
+	if len(elems) != 2 || elems[0].Key.GetValueString() != "a" || elems[1].Key.GetValueString() != "b" {
+		t.Fatalf("bad: %#v", elems)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build go1.18
+
+package encoding
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestNetip(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Source interface{}
+		String string
+	}{
+		{"ipv4 addr", netip.MustParseAddr("192.0.2.1"), "192.0.2.1"},
+		{"ipv6 addr", netip.MustParseAddr("2001:db8::1"), "2001:db8::1"},
+		{"ipv6 addr with zone", netip.MustParseAddr("fe80::1%eth0"), "fe80::1%eth0"},
+		{"ipv4-mapped addr", netip.MustParseAddr("::ffff:192.0.2.1"), "::ffff:192.0.2.1"},
+		{"zero addr", netip.Addr{}, ""},
+		{"ipv4 prefix", netip.MustParsePrefix("10.0.0.0/8"), "10.0.0.0/8"},
+		{"ipv6 prefix", netip.MustParsePrefix("2001:db8::/32"), "2001:db8::/32"},
+		{"addr port", netip.MustParseAddrPort("[2001:db8::1]:443"), "[2001:db8::1]:443"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if s, err := AsString(v); err != nil || s != tc.String {
+				t.Fatalf("bad: %q (err: %v)", s, err)
+			}
+
+			actual, err := ValueToGo(v, reflect.TypeOf(tc.Source))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if actual != tc.Source {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestNetip_invalid(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Source string
+		Type   reflect.Type
+	}{
+		{"addr", "192.0.2.256", reflect.TypeOf(netip.Addr{})},
+		{"addr with prefix", "192.0.2.0/24", reflect.TypeOf(netip.Addr{})},
+		{"prefix without bits", "192.0.2.0", reflect.TypeOf(netip.Prefix{})},
+		{"prefix with zone", "fe80::%eth0/64", reflect.TypeOf(netip.Prefix{})},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if _, err := ValueToGo(v, tc.Type); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
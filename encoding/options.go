@@ -0,0 +1,760 @@
+package encoding
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// DecodeOption is an option that can be given to ValueToGo to change how
+// values are converted to Go values.
+type DecodeOption func(*decoder)
+
+// decoder holds the configuration for converting values to Go values.
+type decoder struct {
+	// exactFloat, if set, errors when an integer can't be represented
+	// exactly by the target float type.
+	exactFloat bool
+
+	// lenientNumbers, if set, allows FLOAT values with no fractional part
+	// to be decoded into integer targets.
+	lenientNumbers bool
+
+	// emptyStringAsZero, if set, decodes empty STRING values into numeric
+	// and bool targets as the zero value.
+	emptyStringAsZero bool
+
+	// trimSpace, if set, trims leading and trailing whitespace from STRING
+	// values decoded into numeric and bool targets.
+	trimSpace bool
+
+	// strictUTF8, if set, errors for STRING values that aren't valid
+	// UTF-8 when decoding into a string.
+	strictUTF8 bool
+
+	// scalarToList, if set, decodes non-LIST values into slice and array
+	// targets as a single-element list.
+	scalarToList bool
+
+	// listToScalar, if set, decodes single-element LIST values into
+	// scalar targets as the element.
+	listToScalar bool
+
+	// skipNullElements, if set, drops NULL and UNDEFINED elements of a
+	// LIST decoded into a slice whose elements can't be nil.
+	skipNullElements bool
+
+	// caseInsensitiveKeys, if set, matches map keys to struct fields
+	// ignoring case.
+	caseInsensitiveKeys bool
+
+	// disallowUnknownKeys, if set, errors for map keys that don't match
+	// a field when decoding into a struct.
+	disallowUnknownKeys bool
+
+	// expandDottedKeys, if set, expands dotted map keys into nested maps
+	// when decoding into a struct.
+	expandDottedKeys bool
+
+	// keyTransform, if set, is applied to STRING map keys before they are
+	// decoded or matched to struct fields.
+	keyTransform func(string) string
+
+	// maxListLen and maxMapLen, if non-zero, are the maximum number of
+	// elements in any single LIST or MAP.
+	maxListLen int
+	maxMapLen  int
+
+	// internStrings, if set, deduplicates equal strings decoded into a
+	// string or interface{} target. interned holds the strings decoded so
+	// far, boxed so that an interned string in an interface{} doesn't
+	// allocate again.
+	internStrings bool
+	interned      map[string]interface{}
+
+	// complexShape, if set, is the shape of values decoded into complex
+	// targets.
+	complexShape ComplexShape
+
+	// timeUnit, if non-zero, is the unit of INT values decoded into a
+	// time.Time. The default is seconds.
+	timeUnit time.Duration
+
+	// timeFromMap, if set, is the location of time.Time values assembled
+	// from MAP values of date and time components.
+	timeFromMap *time.Location
+
+	// decimalSep and groupSep, if decimalSep is non-zero, are the decimal
+	// and grouping separators of numbers in STRING values.
+	decimalSep rune
+	groupSep   rune
+
+	// duplicateKeys is how MAP entries with the same key are handled.
+	duplicateKeys DuplicateKeys
+
+	// errVerbosity controls how much context is included in errors.
+	errVerbosity ErrorVerbosity
+
+	// timeout, if non-zero, is the maximum time a decode may take. The
+	// deadline is checked every deadlineInterval values, counted by
+	// deadlineCount.
+	timeout       time.Duration
+	deadline      time.Time
+	deadlineCount int
+
+	// presence, if set, records the paths of struct fields with a key in
+	// the MAP decoded. See ValueToGoPresence.
+	presence map[string]bool
+
+	// fieldHook, if set, is called for every scalar value decoded. path
+	// is the path to the value currently being decoded, which is only
+	// tracked if fieldHook or presence is set or errors are verbose.
+	fieldHook FieldHook
+	path      []byte
+
+	// scratch is a reusable buffer for intermediate values. It is kept
+	// across uses of a pooled decoder to reduce allocations.
+	scratch []*proto.Value
+}
+
+// decoderPool is a pool of decoders so that their scratch buffers can
+// be reused across decodes, reducing GC pressure for imports that decode
+// many values.
+var decoderPool = sync.Pool{
+	New: func() interface{} { return new(decoder) },
+}
+
+// getDecoder returns a decoder from the pool with the given options
+// applied. The decoder should be returned with putDecoder when done.
+func getDecoder(opts []DecodeOption) *decoder {
+	d := decoderPool.Get().(*decoder)
+	*d = decoder{scratch: d.scratch, path: d.path[:0]}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.timeout > 0 {
+		d.deadline = time.Now().Add(d.timeout)
+	}
+
+	return d
+}
+
+// deadlineInterval is the number of values decoded between checks of the
+// deadline set with WithTimeout, so that the time isn't read for every
+// value.
+const deadlineInterval = 1024
+
+// errTimeout is the error returned when a decode exceeds its deadline.
+var errTimeout = errors.New("decode timed out")
+
+// checkDeadline returns errTimeout if the deadline set with WithTimeout
+// has passed. The time is only checked every deadlineInterval calls.
+func (d *decoder) checkDeadline() error {
+	if d.timeout <= 0 {
+		return nil
+	}
+
+	d.deadlineCount++
+	if d.deadlineCount%deadlineInterval == 0 && time.Now().After(d.deadline) {
+		return errTimeout
+	}
+
+	return nil
+}
+
+// tracksPath returns true if the path to the value being decoded is needed.
+func (d *decoder) tracksPath() bool {
+	return d.fieldHook != nil || d.presence != nil || d.errVerbosity == ErrorsVerbose
+}
+
+// pushIndex appends a list index to the path if it is tracked. It returns
+// the length of the path to restore with popPath.
+func (d *decoder) pushIndex(i int) int {
+	n := len(d.path)
+	if d.tracksPath() {
+		d.path = appendIndexPath(d.path, i)
+	}
+
+	return n
+}
+
+// appendIndexPath appends a list index to a path.
+func appendIndexPath(path []byte, i int) []byte {
+	path = append(path, '[')
+	path = strconv.AppendInt(path, int64(i), 10)
+	return append(path, ']')
+}
+
+// pushKey appends a map key to the path if it is tracked. STRING keys
+// are appended as ".key" (or "key" at the root) and other keys as "[key]".
+// It returns the length of the path to restore with popPath.
+func (d *decoder) pushKey(key *proto.Value) int {
+	n := len(d.path)
+	if d.tracksPath() {
+		d.path = appendKeyPath(d.path, key)
+	}
+
+	return n
+}
+
+// appendKeyPath appends a map key to a path, as described for pushKey.
+func appendKeyPath(path []byte, key *proto.Value) []byte {
+	if s, ok := key.GetValue().(*proto.Value_ValueString); ok {
+		if len(path) > 0 {
+			path = append(path, '.')
+		}
+		return append(path, s.ValueString...)
+	}
+
+	path = append(path, '[')
+	path = append(path, Sprint(key)...)
+	return append(path, ']')
+}
+
+// popPath restores the path to the length returned by pushIndex or pushKey.
+func (d *decoder) popPath(n int) {
+	d.path = d.path[:n]
+}
+
+// putDecoder returns a decoder to the pool. Interned strings and presence
+// are dropped so that the pool doesn't keep them alive.
+func putDecoder(d *decoder) {
+	d.interned = nil
+	d.presence = nil
+	decoderPool.Put(d)
+}
+
+// intern returns the interned copy of s, boxed in an interface{}, if
+// strings are interned. Otherwise it returns s.
+func (d *decoder) intern(s string) interface{} {
+	if !d.internStrings {
+		return s
+	}
+
+	if v, ok := d.interned[s]; ok {
+		return v
+	}
+	if d.interned == nil {
+		d.interned = make(map[string]interface{})
+	}
+
+	var v interface{} = s
+	d.interned[s] = v
+	return v
+}
+
+// WithExactFloats makes decoding an INT value (or a STRING containing an
+// integer) into a float target return an error if the integer can't be
+// represented exactly by the float, rather than silently rounding it. For
+// float64 this is any integer with a magnitude greater than 2^53, and for
+// float32 it is 2^24.
+//
+// By default, integers are rounded to the nearest representable float.
+func WithExactFloats() DecodeOption {
+	return func(d *decoder) {
+		d.exactFloat = true
+	}
+}
+
+// WithLenientNumbers allows a FLOAT value to be decoded into an int or uint
+// target if it is a whole number within the range of the target, which is
+// common for data that came from JSON. Fractional, infinite and NaN values,
+// or negative values for a uint target, still return an error.
+//
+// For AsFloat64, this also allows INT values.
+//
+// By default, only INT values (and STRING values containing an integer)
+// can be decoded into integer targets.
+func WithLenientNumbers() DecodeOption {
+	return func(d *decoder) {
+		d.lenientNumbers = true
+	}
+}
+
+// WithEmptyStringAsZero makes decoding an empty STRING value into a
+// numeric or bool target return the zero value rather than an error. This
+// is common for data that came from CSV, where missing numbers are empty.
+// Non-empty strings that aren't valid numbers still return an error, and
+// strings other than "" still can't be decoded into bool targets.
+//
+// By default, empty strings return an error for these targets.
+func WithEmptyStringAsZero() DecodeOption {
+	return func(d *decoder) {
+		d.emptyStringAsZero = true
+	}
+}
+
+// WithTrimSpace makes decoding a STRING value into a numeric or bool
+// target ignore leading and trailing whitespace, as defined by Unicode, so
+// "  42 " decodes into an int as 42. This handles data from CSV and form
+// backends that pad values with spaces. With WithEmptyStringAsZero, a
+// string of only whitespace decodes as the zero value. Strings decoded
+// into string and interface{} targets are unchanged.
+//
+// By default, whitespace in a string that is parsed as a number is an
+// error.
+func WithTrimSpace() DecodeOption {
+	return func(d *decoder) {
+		d.trimSpace = true
+	}
+}
+
+// trim trims whitespace from s if WithTrimSpace is set.
+func (d *decoder) trim(s string) string {
+	if !d.trimSpace {
+		return s
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// WithTimeUnit sets the unit of INT values decoded into a time.Time, which
+// are the number of units since the Unix epoch, January 1, 1970 UTC. For
+// example, WithTimeUnit(time.Millisecond) decodes 1136214245000 as
+// 2006-01-02T15:04:05Z. The time is in UTC. A unit of zero or less is
+// ignored.
+//
+// STRING values are always decoded into a time.Time as RFC 3339 with
+// time.Time.UnmarshalText, and a time.Time is always encoded as an RFC 3339
+// STRING, so both kinds of input can be decoded into the same target.
+//
+// By default, INT values are seconds.
+func WithTimeUnit(unit time.Duration) DecodeOption {
+	return func(d *decoder) {
+		if unit > 0 {
+			d.timeUnit = unit
+		}
+	}
+}
+
+// WithTimeFromMap allows decoding a MAP into a time.Time by assembling the
+// time from its components, in loc, such as {"year": 2024, "month": 1,
+// "day": 15}. The keys are "year", "month", "day", "hour", "minute",
+// "second" and "nanosecond". Only "year" is required; a missing month or
+// day is 1, and the others are 0. Each component must be an integer in
+// the range for its unit, and the day must exist in the month, so month 13
+// or February 30 returns an error, as do other keys. A nil loc is UTC.
+//
+// By default, a MAP is decoded into a time.Time like any other struct, and
+// since time.Time has no exported fields, the result is the zero time.
+func WithTimeFromMap(loc *time.Location) DecodeOption {
+	return func(d *decoder) {
+		if loc == nil {
+			loc = time.UTC
+		}
+
+		d.timeFromMap = loc
+	}
+}
+
+// WithNumberSeparators makes decoding a STRING value into a numeric target
+// parse the string with the given decimal and grouping separators rather
+// than Go syntax. For example, WithNumberSeparators(',', '.') decodes
+// "1.234,56" into a float64 as 1234.56, as is common for data in European
+// formats. Grouping separators are removed wherever they appear, and a
+// grouping separator of 0 means numbers aren't grouped. A '.' that isn't
+// one of the separators is an error rather than a decimal point, since
+// the number is ambiguous. Strings are otherwise parsed as usual, so a
+// decimal separator in a string decoded into an integer target is an
+// error.
+//
+// If decimal is 0 or the separators are the same, this has no effect. By
+// default, strings are parsed with strconv, which accepts '.' as the
+// decimal separator and '_' only between digits of Go integer literals.
+func WithNumberSeparators(decimal, grouping rune) DecodeOption {
+	return func(d *decoder) {
+		if decimal != 0 && decimal != grouping {
+			d.decimalSep, d.groupSep = decimal, grouping
+		}
+	}
+}
+
+// normalizeNumber converts a number in a STRING value to Go syntax if
+// separators are set with WithNumberSeparators, after trimming it if
+// WithTrimSpace is set.
+func (d *decoder) normalizeNumber(s string) string {
+	s = d.trim(s)
+	if d.decimalSep == 0 {
+		return s
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case d.groupSep:
+			return -1
+		case d.decimalSep:
+			return '.'
+		case '.':
+			// A '.' that isn't the decimal separator can't be parsed as one
+			return d.decimalSep
+		}
+
+		return r
+	}, s)
+}
+
+// WithStrictUTF8 makes decoding a STRING value into a string target return
+// an error if the string isn't valid UTF-8. This catches corrupt data
+// before it breaks something later on, such as conversion to JSON.
+//
+// By default, strings are passed through unchanged.
+func WithStrictUTF8() DecodeOption {
+	return func(d *decoder) {
+		d.strictUTF8 = true
+	}
+}
+
+// WithScalarToList makes decoding a value that isn't a LIST into a slice or
+// array target treat the value as a list with that single element. This
+// handles data from APIs that return either a single object or a list of
+// them depending on the count. NULL and UNDEFINED values aren't wrapped.
+// See WithListToScalar for the inverse.
+//
+// By default, only LIST values can be decoded into slices and arrays.
+func WithScalarToList() DecodeOption {
+	return func(d *decoder) {
+		d.scalarToList = true
+	}
+}
+
+// WithListToScalar makes decoding a LIST into a bool, integer, float or
+// string target decode its only element instead, so ["value"] decodes into
+// a string as "value". This is the inverse of WithScalarToList, for APIs
+// that return a single-element list where a scalar was expected. The
+// element is decoded like a list element, with its own path. A LIST with no
+// elements or more than one, or whose element is itself a LIST, returns an
+// error.
+//
+// By default, LIST values can't be decoded into these targets.
+func WithListToScalar() DecodeOption {
+	return func(d *decoder) {
+		d.listToScalar = true
+	}
+}
+
+// WithSkipNullElements makes decoding a LIST into a slice drop NULL and
+// UNDEFINED elements when the slice's element type isn't a pointer or an
+// interface, so ["a", null, "b"] decodes into a []string as ["a", "b"].
+// This is for lists where absent entries should simply be omitted. Slices
+// of pointers and interfaces keep every element, and arrays are unchanged
+// since their length is fixed.
+//
+// By default, NULL and UNDEFINED elements return an error for these
+// targets.
+func WithSkipNullElements() DecodeOption {
+	return func(d *decoder) {
+		d.skipNullElements = true
+	}
+}
+
+// WithCaseInsensitiveKeys makes decoding a MAP into a struct match map keys
+// to fields ignoring case, like encoding/json. A key that exactly matches
+// the field's key (its "sentinel" tag or name) is always preferred. If
+// there is no exact match and several keys match ignoring case, the first
+// of them in the order of the map entries is used.
+//
+// By default, keys must match exactly.
+func WithCaseInsensitiveKeys() DecodeOption {
+	return func(d *decoder) {
+		d.caseInsensitiveKeys = true
+	}
+}
+
+// WithDisallowUnknownKeys makes decoding a MAP into a struct return an
+// error if the MAP has keys that don't match any field, such as
+// "unknown keys: [reigon]", like encoding/json's DisallowUnknownFields.
+// This catches typos in configuration that would otherwise be silently
+// ignored. Keys are matched as with the other options, so keys that match
+// ignoring case are known with WithCaseInsensitiveKeys, and dotted keys
+// are known if they expand into a known key with WithExpandDottedKeys.
+// Keys for fields that weren't set because their value is UNDEFINED are
+// still known.
+//
+// By default, keys that don't match a field are ignored.
+func WithDisallowUnknownKeys() DecodeOption {
+	return func(d *decoder) {
+		d.disallowUnknownKeys = true
+	}
+}
+
+// WithExpandDottedKeys makes decoding a MAP into a struct treat keys
+// containing dots as paths into nested maps, so a flat map such as
+// {"db.host": "localhost", "db.port": 5432} decodes into a struct with a
+// "db" field that is itself a struct with "host" and "port" fields. This
+// handles configuration stores that flatten nested values.
+//
+// A key is split at its first dot only if it doesn't match a field key
+// exactly, so a field tagged `sentinel:"db.host"` still matches the flat
+// key. Expanded keys are merged into an existing MAP value for the prefix,
+// such as {"db": {"host": "localhost"}, "db.port": 5432}. It is an error
+// if the existing value for the prefix isn't a MAP.
+//
+// By default, keys are matched to fields as-is.
+func WithExpandDottedKeys() DecodeOption {
+	return func(d *decoder) {
+		d.expandDottedKeys = true
+	}
+}
+
+// WithKeyTransform makes decoding apply f to every STRING key of a MAP
+// before the key is matched to a struct field or decoded into a map key, at
+// any depth. This handles a naming convention that differs from the Go
+// one, such as keys in SCREAMING_SNAKE_CASE for fields tagged in camelCase,
+// without a tag for every field. The other key options apply to the
+// transformed keys, so keys that transform to the same key are duplicates
+// for WithDuplicateKeys, and WithDisallowUnknownKeys reports the
+// transformed keys. Keys of other types aren't changed.
+//
+// By default, keys are used as they are.
+func WithKeyTransform(f func(string) string) DecodeOption {
+	return func(d *decoder) {
+		d.keyTransform = f
+	}
+}
+
+// transformKey returns the STRING key with the transform from
+// WithKeyTransform applied, or key itself if there is no transform or the
+// key isn't a STRING.
+func (d *decoder) transformKey(key *proto.Value) *proto.Value {
+	if d.keyTransform == nil {
+		return key
+	}
+
+	k, ok := key.Value.(*proto.Value_ValueString)
+	if !ok {
+		return key
+	}
+
+	return toValue_string(d.keyTransform(k.ValueString))
+}
+
+// WithStringInterning makes decoding keep a single copy of each distinct
+// string decoded into a string or interface{} target, including map keys,
+// for the duration of the decode. Each STRING in a value is a separate
+// allocation, so results with many repeated strings, such as status or
+// type fields in a list of records, use much less memory once the value
+// itself is no longer referenced. Repeated strings decoded into an
+// interface{} also don't allocate again.
+//
+// By default, strings aren't interned, since the map of interned strings
+// is overhead for values with few repeated strings.
+func WithStringInterning() DecodeOption {
+	return func(d *decoder) {
+		d.internStrings = true
+	}
+}
+
+// WithMaxListLen makes decoding return an error for any LIST, at any depth,
+// with more than n elements. The length is checked before anything is
+// allocated for the elements, so this guards against values built to
+// exhaust memory. n <= 0 means no limit.
+//
+// By default, lists of any length are decoded.
+func WithMaxListLen(n int) DecodeOption {
+	return func(d *decoder) {
+		d.maxListLen = n
+	}
+}
+
+// WithMaxMapLen is like WithMaxListLen but limits the number of entries in
+// any single MAP, including MAPs decoded into structs.
+func WithMaxMapLen(n int) DecodeOption {
+	return func(d *decoder) {
+		d.maxMapLen = n
+	}
+}
+
+// DuplicateKeys specifies how decoding handles a MAP with several entries
+// for the same key. Nothing in the protobuf encoding of a MAP prevents this.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeysLastWins uses the value of the last entry for the key.
+	DuplicateKeysLastWins DuplicateKeys = iota
+
+	// DuplicateKeysFirstWins uses the value of the first entry for the
+	// key.
+	DuplicateKeysFirstWins
+
+	// DuplicateKeysError returns an error.
+	DuplicateKeysError
+)
+
+// WithDuplicateKeys sets how MAP entries with the same key are handled when
+// decoding into a map, a struct or a sync.Map. Keys are the same if they
+// decode to equal Go values, so for a map[string]int target the STRING "1"
+// and the INT 1 are the same key. For a struct, only STRING keys are
+// checked since other keys can't match a field.
+//
+// By default, the value of the last entry for a key wins.
+func WithDuplicateKeys(policy DuplicateKeys) DecodeOption {
+	return func(d *decoder) {
+		d.duplicateKeys = policy
+	}
+}
+
+// ComplexShape is the shape of the value used to represent a complex
+// number, since Sentinel has no complex number type.
+type ComplexShape int
+
+const (
+	// ComplexList represents a complex number as a two-element LIST of its
+	// real and imaginary parts, such as [1.5, -2].
+	ComplexList ComplexShape = iota + 1
+
+	// ComplexMap represents a complex number as a MAP with the keys "re"
+	// and "im" for its real and imaginary parts, such as {"re": 1.5,
+	// "im": -2}.
+	ComplexMap
+)
+
+// WithComplexShape allows values of the given shape to be decoded into
+// complex64 and complex128 targets. Each part must be an INT or FLOAT.
+// Values of any other shape, including the other ComplexShape, return an
+// error.
+//
+// By default, complex targets can't be decoded into.
+func WithComplexShape(shape ComplexShape) DecodeOption {
+	return func(d *decoder) {
+		d.complexShape = shape
+	}
+}
+
+// ErrorVerbosity controls how much context is included in the errors
+// returned by ValueToGo and the other decoding functions.
+type ErrorVerbosity int
+
+const (
+	// ErrorsDefault prefixes the error for a value with each element, key
+	// and field that contains it, such as
+	// "field Items: element 0: cannot convert to int: STRING".
+	ErrorsDefault ErrorVerbosity = iota
+
+	// ErrorsTerse returns only the error for the value itself, such as
+	// "cannot convert to int: STRING", which is compact enough for logs.
+	ErrorsTerse
+
+	// ErrorsVerbose prefixes the error for a value with the path to it, in
+	// the same format as the path given to a FieldHook. Errors for values
+	// of the wrong type name the value type and the Go type being decoded
+	// into, such as "items[0].count: cannot convert STRING value to Go
+	// type int64".
+	ErrorsVerbose
+)
+
+// WithErrorVerbosity sets how much context is included in errors. The
+// verbosity doesn't change whether an error is returned, only its message,
+// so it can be tuned for production or development without changing code.
+//
+// By default, errors are formatted as described for ErrorsDefault.
+func WithErrorVerbosity(v ErrorVerbosity) DecodeOption {
+	return func(d *decoder) {
+		d.errVerbosity = v
+	}
+}
+
+// FieldHook is a function called by ValueToGo for every scalar value that
+// is decoded, such as for auditing. path is the path to the value from the
+// root value, such as "items[0].name", v is the value and decoded is the
+// Go value it was decoded to. Map keys aren't reported. For STRING keys,
+// the path contains the key as-is; other keys are printed with Sprint.
+type FieldHook func(path string, v *proto.Value, decoded interface{})
+
+// WithTimeout limits the time a single decode may take, returning the
+// error "decode timed out" once it has taken longer than d. This bounds
+// the time spent on untrusted input where a context can't be used. The
+// time is only checked every so many values, so a decode may run a little
+// past the timeout, and values decoded without visiting each element,
+// such as lists of scalars decoded into a slice of the same type, aren't
+// interrupted. A d of zero or less means no limit.
+//
+// By default, decoding isn't limited.
+func WithTimeout(d time.Duration) DecodeOption {
+	return func(dec *decoder) {
+		dec.timeout = d
+	}
+}
+
+// WithFieldHook sets a function to call for every scalar value decoded.
+// The path to each value is only tracked when a hook is set, so decoding
+// without a hook has no additional cost.
+func WithFieldHook(f FieldHook) DecodeOption {
+	return func(d *decoder) {
+		d.fieldHook = f
+	}
+}
+
+// EncodeOption is an option that can be given to GoToValue to change how
+// Go values are converted to values.
+type EncodeOption func(*encoder)
+
+// encoder holds the configuration for converting Go values to values.
+type encoder struct {
+	// floatFormat, if set, is the fmt format used to convert floats to
+	// STRING values.
+	floatFormat string
+
+	// complexShape, if set, is the shape complex numbers are converted to.
+	complexShape ComplexShape
+
+	// workers, if greater than 1, is the number of goroutines that convert
+	// the elements of a large slice or array.
+	workers int
+}
+
+// WithFloatFormat makes GoToValue convert float32 and float64 values to
+// STRING values formatted with the given fmt verb, such as "%.2f", rather
+// than FLOAT values. This is useful for imports that display numbers and
+// need control over how they are presented, such as keeping 1.5 as "1.50".
+//
+// By default, floats are converted to FLOAT values.
+func WithFloatFormat(format string) EncodeOption {
+	return func(e *encoder) {
+		e.floatFormat = format
+	}
+}
+
+// WithComplexEncoding makes GoToValue convert complex64 and complex128
+// values to the given shape, with each part as a FLOAT, so that they can be
+// decoded with WithComplexShape.
+//
+// By default, complex numbers can't be converted and return an error.
+func WithComplexEncoding(shape ComplexShape) EncodeOption {
+	return func(e *encoder) {
+		e.complexShape = shape
+	}
+}
+
+// parallelMinLen is the minimum number of elements in a slice or array for
+// it to be converted in parallel with WithParallelEncoding. Below this, the
+// cost of starting goroutines outweighs the gain.
+const parallelMinLen = 1024
+
+// WithParallelEncoding makes GoToValue convert the elements of a slice or
+// array with at least 1024 elements using the given number of goroutines,
+// such as a large slice of result structs. The resulting LIST is the same
+// as without the option, with elements in order. If several elements
+// can't be converted, the error for the first of them is returned, just
+// as when converting sequentially. Only the outermost such slice or array
+// is split up, so nested ones don't start more goroutines.
+//
+// A workers value of 0 or less uses runtime.GOMAXPROCS(0). Converters
+// registered with RegisterConverter and MarshalText methods may be called
+// concurrently for different elements, so they must be safe for
+// concurrent use.
+//
+// By default, elements are converted sequentially.
+func WithParallelEncoding(workers int) EncodeOption {
+	return func(e *encoder) {
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		e.workers = workers
+	}
+}
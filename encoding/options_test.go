@@ -0,0 +1,1180 @@
+package encoding
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// decodeOptionTest is a test case for decoding with options. Source is
+// converted with GoToValue and then decoded into the type of Expected with
+// the given options.
+type decodeOptionTest struct {
+	Name     string
+	Source   interface{}
+	Expected interface{}
+	Opts     []DecodeOption
+	Err      bool
+}
+
+func testDecodeOptions(t *testing.T, cases []decodeOptionTest) {
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			value, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("GoToValue err: %s", err)
+			}
+
+			actual, err := ValueToGo(value, reflect.TypeOf(tc.Expected), tc.Opts...)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestWithExactFloats(t *testing.T) {
+	testDecodeOptions(t, []decodeOptionTest{
+		{
+			"int within range",
+			int64(1 << 53),
+			float64(1 << 53),
+			[]DecodeOption{WithExactFloats()},
+			false,
+		},
+
+		{
+			"negative int within range",
+			int64(-1 << 53),
+			float64(-1 << 53),
+			[]DecodeOption{WithExactFloats()},
+			false,
+		},
+
+		{
+			"int out of range",
+			int64(1<<53 + 1),
+			float64(0),
+			[]DecodeOption{WithExactFloats()},
+			true,
+		},
+
+		{
+			"int out of range without option",
+			int64(1<<53 + 1),
+			float64(1 << 53),
+			nil,
+			false,
+		},
+
+		{
+			"int out of range for float32",
+			int64(1<<24 + 1),
+			float32(0),
+			[]DecodeOption{WithExactFloats()},
+			true,
+		},
+
+		{
+			"string int out of range",
+			"1234567890123456789",
+			float64(0),
+			[]DecodeOption{WithExactFloats()},
+			true,
+		},
+
+		{
+			"string float",
+			"1.5",
+			float64(1.5),
+			[]DecodeOption{WithExactFloats()},
+			false,
+		},
+	})
+}
+
+func TestWithLenientNumbers(t *testing.T) {
+	lenient := []DecodeOption{WithLenientNumbers()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"float to int", 42.0, int(42), lenient, false},
+		{"negative float to int", -42.0, int64(-42), lenient, false},
+		{"float to int without option", 42.0, int(0), nil, true},
+		{"fractional float to int", 42.5, int(0), lenient, true},
+		{"float out of range for int", 1e19, int64(0), lenient, true},
+		{"largest float to int", float64(1 << 62), int64(1 << 62), lenient, false},
+
+		{"float to uint", 42.0, uint(42), lenient, false},
+		{"large float to uint", 1e19, uint64(1e19), lenient, false},
+		{"float to uint without option", 42.0, uint(0), nil, true},
+		{"fractional float to uint", 0.5, uint(0), lenient, true},
+		{"negative float to uint", -1.0, uint(0), lenient, true},
+		{"float out of range for uint", 1.9e19, uint64(0), lenient, true},
+		{"infinite float to uint", math.Inf(1), uint64(0), lenient, true},
+		{"nan float to uint", math.NaN(), uint64(0), lenient, true},
+	})
+}
+
+func TestWithEmptyStringAsZero(t *testing.T) {
+	zero := []DecodeOption{WithEmptyStringAsZero()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"int", "", 0, zero, false},
+		{"uint8", "", uint8(0), zero, false},
+		{"float", "", 0.0, zero, false},
+		{"bool", "", false, zero, false},
+		{"string", "", "", zero, false},
+		{"int without option", "", 0, nil, true},
+		{"malformed int", "abc", 0, zero, true},
+		{"non-empty bool", "true", false, zero, true},
+		{"valid int", "42", 42, zero, false},
+		{"list", []string{"1", ""}, []int{1, 0}, zero, false},
+	})
+}
+
+func TestWithTrimSpace(t *testing.T) {
+	trim := []DecodeOption{WithTrimSpace()}
+	trimZero := []DecodeOption{WithTrimSpace(), WithEmptyStringAsZero()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"int", "  42 ", 42, trim, false},
+		{"uint", "\t7\n", uint(7), trim, false},
+		{"float", " 3.5", 3.5, trim, false},
+		{"hex int", " 0x10 ", int64(16), trim, false},
+		{"separators", " 1.234,5 ", 1234.5, append(trim, WithNumberSeparators(',', '.')), false},
+		{"int without option", "  42 ", 0, nil, true},
+		{"inner space", "4 2", 0, trim, true},
+		{"string unchanged", "  a ", "  a ", trim, false},
+		{"blank int", "   ", 0, trimZero, false},
+		{"blank bool", " \t ", false, trimZero, false},
+		{"blank bool without trim", " ", false, []DecodeOption{WithEmptyStringAsZero()}, true},
+		{"padded bool string", " true ", false, trimZero, true},
+		{"list", []string{" 1", "2 "}, []int{1, 2}, trim, false},
+	})
+}
+
+func TestWithStrictUTF8(t *testing.T) {
+	strict := []DecodeOption{WithStrictUTF8()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"valid string", "héllo", "héllo", strict, false},
+		{"invalid string", "h\xffllo", "", strict, true},
+		{"invalid string without option", "h\xffllo", "h\xffllo", nil, false},
+		{"invalid string in list", []string{"ok", "\xff"}, []string{}, strict, true},
+		{"invalid map key", map[string]int{"\xff": 1}, map[string]int{}, strict, true},
+	})
+}
+
+func TestWithScalarToList(t *testing.T) {
+	wrap := []DecodeOption{WithScalarToList()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"scalar to slice", "a", []string{"a"}, wrap, false},
+		{"scalar to array", 42, [1]int{42}, wrap, false},
+		{"scalar to array of wrong length", 42, [2]int{}, wrap, true},
+		{"scalar to slice without option", "a", []string{}, nil, true},
+		{"incompatible scalar to slice", "a", []int{}, wrap, true},
+		{"null to slice", nil, []string{}, wrap, true},
+
+		{
+			"map to slice",
+			map[string]interface{}{"name": "a"},
+			[]map[string]string{{"name": "a"}},
+			wrap,
+			false,
+		},
+
+		{
+			"list to slice",
+			[]string{"a", "b"},
+			[]string{"a", "b"},
+			wrap,
+			false,
+		},
+
+		{
+			"list of lists to slice of slices",
+			[]interface{}{"a", []string{"b", "c"}},
+			[][]string{{"a"}, {"b", "c"}},
+			wrap,
+			false,
+		},
+
+		{
+			"struct field",
+			map[string]interface{}{"Tags": "a"},
+			testStruct{Tags: []string{"a"}},
+			wrap,
+			false,
+		},
+	})
+}
+
+func TestValueToSlice_scalarToList(t *testing.T) {
+	v, err := GoToValue(int64(42))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var dst []int64
+	if err := ValueToSlice(v, &dst, WithScalarToList()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(dst, []int64{42}) {
+		t.Fatalf("bad: %#v", dst)
+	}
+}
+
+func TestWithFieldHook(t *testing.T) {
+	type item struct {
+		Name string `sentinel:"name"`
+		Port *int
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "Port": 80},
+		},
+		"codes": map[int]string{404: "not found"},
+		"flag":  true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	type fieldRecord struct {
+		Path    string
+		Decoded interface{}
+	}
+
+	var actual []fieldRecord
+	hook := WithFieldHook(func(path string, v *proto.Value, decoded interface{}) {
+		actual = append(actual, fieldRecord{path, decoded})
+	})
+
+	typ := reflect.TypeOf(struct {
+		Items []item         `sentinel:"items"`
+		Codes map[int]string `sentinel:"codes"`
+		Flag  interface{}    `sentinel:"flag"`
+	}{})
+	if _, err := ValueToGo(v, typ, hook); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	port := 80
+	expected := []fieldRecord{
+		{"items[0].name", "a"},
+		{"items[0].Port", &port},
+		{"codes[404]", "not found"},
+		{"flag", true},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The pooled decoder must not keep the hook
+	actual = nil
+	if _, err := ValueToGo(v, typ); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestWithListToScalar(t *testing.T) {
+	unwrap := []DecodeOption{WithListToScalar()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"string", []string{"a"}, "a", unwrap, false},
+		{"int", []int{42}, int8(42), unwrap, false},
+		{"float", []float64{1.5}, 1.5, unwrap, false},
+		{"bool", []bool{true}, true, unwrap, false},
+		{"scalar", "a", "a", unwrap, false},
+		{"converter", []string{"Monday"}, time.Monday, unwrap, false},
+		{"empty list", []string{}, "", unwrap, true},
+		{"two elements", []string{"a", "b"}, "", unwrap, true},
+		{"incompatible element", []bool{true}, 0, unwrap, true},
+		{"nested list", [][]string{{"a"}}, "", unwrap, true},
+		{"without option", []string{"a"}, "", nil, true},
+		{"slice target", []string{"a"}, []string{"a"}, unwrap, false},
+
+		{
+			"struct field",
+			map[string]interface{}{"name": []string{"a"}, "Count": []int{2}},
+			testStruct{Name: "a", Count: 2},
+			unwrap,
+			false,
+		},
+	})
+}
+
+func TestWithListToScalar_path(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{"name": []string{"a"}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var paths []string
+	hook := WithFieldHook(func(path string, v *proto.Value, decoded interface{}) {
+		paths = append(paths, path)
+	})
+	if _, err := ValueToGo(v, reflect.TypeOf(testStruct{}), WithListToScalar(), hook); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"name[0]"}) {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	// Errors for the element include its path
+	v, err = GoToValue(map[string]interface{}{"name": []bool{true}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = ValueToGo(v, reflect.TypeOf(testStruct{}),
+		WithListToScalar(), WithErrorVerbosity(ErrorsVerbose))
+	if err == nil || !strings.HasPrefix(err.Error(), "name[0]: ") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestWithSkipNullElements(t *testing.T) {
+	skip := []DecodeOption{WithSkipNullElements()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"no nulls", []string{"a", "b"}, []string{"a", "b"}, skip, false},
+		{"nulls", []interface{}{nil, "a", nil, "b", nil}, []string{"a", "b"}, skip, false},
+		{"only nulls", []interface{}{nil, nil}, []string{}, skip, false},
+		{"ints", []interface{}{1, nil, 2}, []int{1, 2}, skip, false},
+		{"nulls without option", []interface{}{"a", nil}, []string{}, nil, true},
+		{"array", []interface{}{"a", nil}, [2]string{}, skip, true},
+		{"wrong type", []interface{}{"a", nil, 1}, []bool{}, skip, true},
+
+		{
+			"struct field",
+			map[string]interface{}{"Tags": []interface{}{"a", nil, "b"}},
+			testStruct{Tags: []string{"a", "b"}},
+			skip,
+			false,
+		},
+	})
+
+	// Undefined elements are dropped too
+	v := &proto.Value{
+		Type: proto.Value_LIST,
+		Value: &proto.Value_ValueList{
+			ValueList: &proto.Value_List{
+				Elems: []*proto.Value{
+					{Type: proto.Value_UNDEFINED},
+					toValue_string("a"),
+				},
+			},
+		},
+	}
+	actual, err := ValueToGo(v, reflect.TypeOf([]string(nil)), skip...)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"a"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Slices of interfaces keep their nulls
+	actual, err = ValueToGo(v, reflect.TypeOf([]interface{}(nil)), skip...)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual.([]interface{})) != 2 {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Decoding into an existing slice
+	var dst []string
+	elems, err := GoToValue([]interface{}{"a", nil, "b"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ValueToSlice(elems, &dst, skip...); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(dst, []string{"a", "b"}) {
+		t.Fatalf("bad: %#v", dst)
+	}
+}
+
+func TestWithCaseInsensitiveKeys(t *testing.T) {
+	type config struct {
+		Region string `sentinel:"region"`
+		Zone   string
+	}
+
+	insensitive := []DecodeOption{WithCaseInsensitiveKeys()}
+	cases := []struct {
+		Name     string
+		Source   *proto.Value
+		Expected config
+		Opts     []DecodeOption
+	}{
+		{
+			"exact keys",
+			testMapValue("region", "a", "Zone", "b"),
+			config{Region: "a", Zone: "b"},
+			insensitive,
+		},
+
+		{
+			"mixed case keys",
+			testMapValue("Region", "a", "ZONE", "b"),
+			config{Region: "a", Zone: "b"},
+			insensitive,
+		},
+
+		{
+			"mixed case keys without option",
+			testMapValue("Region", "a", "ZONE", "b"),
+			config{},
+			nil,
+		},
+
+		{
+			"exact match preferred",
+			testMapValue("REGION", "a", "region", "b", "Region", "c"),
+			config{Region: "b"},
+			insensitive,
+		},
+
+		{
+			"first case-insensitive match",
+			testMapValue("REGION", "a", "Region", "b"),
+			config{Region: "a"},
+			insensitive,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := ValueToGo(tc.Source, reflect.TypeOf(config{}), tc.Opts...)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+// testMapValue returns a MAP with the given alternating string keys and
+// values, in order.
+func testMapValue(kvs ...string) *proto.Value {
+	elems := make([]*proto.Value_KV, 0, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		elems = append(elems, &proto.Value_KV{
+			Key:   toValue_string(kvs[i]),
+			Value: toValue_string(kvs[i+1]),
+		})
+	}
+
+	return &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{Elems: elems},
+		},
+	}
+}
+
+func TestWithFloatFormat(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{
+		"price": 1.5,
+		"count": 3,
+		"ratio": []float32{0.25},
+	}, WithFloatFormat("%.2f"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ValueToGo(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"price": "1.50",
+		"count": int64(3),
+		"ratio": []string{"0.25"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Without the option floats are unchanged
+	v, err = GoToValue(1.5)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Type != proto.Value_FLOAT {
+		t.Fatalf("bad: %s", v.Type)
+	}
+}
+
+func TestWithExpandDottedKeys(t *testing.T) {
+	type db struct {
+		Host string `sentinel:"host"`
+		Port int    `sentinel:"port"`
+	}
+	type config struct {
+		DB   db     `sentinel:"db"`
+		Flat string `sentinel:"log.level"`
+	}
+
+	expand := []DecodeOption{WithExpandDottedKeys()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{
+			"dotted keys",
+			map[string]interface{}{"db.host": "localhost", "db.port": 5432},
+			config{DB: db{Host: "localhost", Port: 5432}},
+			expand,
+			false,
+		},
+
+		{
+			"merged with nested map",
+			map[string]interface{}{
+				"db":      map[string]interface{}{"host": "localhost"},
+				"db.port": 5432,
+			},
+			config{DB: db{Host: "localhost", Port: 5432}},
+			expand,
+			false,
+		},
+
+		{
+			"exact field key",
+			map[string]interface{}{"log.level": "debug"},
+			config{Flat: "debug"},
+			expand,
+			false,
+		},
+
+		{
+			"without option",
+			map[string]interface{}{"db.host": "localhost"},
+			config{},
+			nil,
+			false,
+		},
+
+		{
+			"conflict with non-map",
+			map[string]interface{}{"db": "localhost", "db.port": 5432},
+			config{},
+			expand,
+			true,
+		},
+	})
+}
+
+func TestWithMaxLen(t *testing.T) {
+	type config struct {
+		Name string `sentinel:"name"`
+	}
+
+	list := []DecodeOption{WithMaxListLen(2)}
+	maps := []DecodeOption{WithMaxMapLen(1)}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"list at limit", []int{1, 2}, []int{1, 2}, list, false},
+		{"list over limit", []int{1, 2, 3}, []int{}, list, true},
+		{"nested list over limit", [][]int{{1, 2, 3}}, [][]int{}, list, true},
+		{"list without limit", []int{1, 2, 3}, []int{1, 2, 3}, nil, false},
+		{"map at limit", map[string]int{"a": 1}, map[string]int{"a": 1}, maps, false},
+		{"map over limit", map[string]int{"a": 1, "b": 2}, map[string]int{}, maps, true},
+		{"struct over limit", map[string]string{"name": "a", "b": "c"}, config{}, maps, true},
+		{"map limit ignores lists", []int{1, 2, 3}, []int{1, 2, 3}, maps, false},
+	})
+
+	// The limit also applies when decoding in place
+	v, err := GoToValue([]int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var dst []int
+	err = ValueToSlice(v, &dst, WithMaxListLen(2))
+	if err == nil || err.Error() != "list exceeds maximum length of 2 with 3 elements" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestWithErrorVerbosity(t *testing.T) {
+	type item struct {
+		Count int64 `sentinel:"count"`
+	}
+
+	type config struct {
+		Items []item `sentinel:"items"`
+	}
+
+	cases := []struct {
+		Name      string
+		Source    interface{}
+		Target    interface{}
+		Verbosity ErrorVerbosity
+		Expected  string
+	}{
+		{
+			"default",
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"count": "a"}}},
+			config{},
+			ErrorsDefault,
+			`field Items: element 0: field Count: strconv.ParseInt: parsing "a": invalid syntax`,
+		},
+
+		{
+			"default type",
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"count": true}}},
+			config{},
+			ErrorsDefault,
+			"field Items: element 0: field Count: cannot convert to int: BOOL",
+		},
+
+		{
+			"terse",
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"count": true}}},
+			config{},
+			ErrorsTerse,
+			"cannot convert to int: BOOL",
+		},
+
+		{
+			"verbose",
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"count": true}}},
+			config{},
+			ErrorsVerbose,
+			"items[0].count: cannot convert BOOL value to Go type int64",
+		},
+
+		{
+			"verbose other error",
+			map[string]interface{}{"items": []interface{}{map[string]interface{}{"count": "a"}}},
+			config{},
+			ErrorsVerbose,
+			`items[0].count: strconv.ParseInt: parsing "a": invalid syntax`,
+		},
+
+		{
+			"verbose root",
+			true,
+			int64(0),
+			ErrorsVerbose,
+			"cannot convert BOOL value to Go type int64",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			_, err = ValueToGo(v, reflect.TypeOf(tc.Target), WithErrorVerbosity(tc.Verbosity))
+			if err == nil {
+				t.Fatal("should error")
+			}
+			if err.Error() != tc.Expected {
+				t.Fatalf("bad: %s", err)
+			}
+		})
+	}
+
+	// Keys have no path, so errors for them are nested below the map
+	v, err := GoToValue(map[string]interface{}{"m": map[bool]int{true: 1}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ValueToGo(v, reflect.TypeOf(map[string]map[int64]int{}), WithErrorVerbosity(ErrorsVerbose))
+	if err == nil || !strings.HasPrefix(err.Error(), "m: key ") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestWithStringInterning(t *testing.T) {
+	type record struct {
+		Status string `sentinel:"status"`
+	}
+
+	testDecodeOptions(t, []decodeOptionTest{
+		{"interface", []string{"a", "b", "a"}, []interface{}{"a", "b", "a"}, []DecodeOption{WithStringInterning()}, false},
+		{"strings", []string{"a", "b", "a"}, []string{"a", "b", "a"}, []DecodeOption{WithStringInterning()}, false},
+		{"struct", map[string]string{"status": "a"}, record{Status: "a"}, []DecodeOption{WithStringInterning()}, false},
+	})
+}
+
+func TestWithComplexShape(t *testing.T) {
+	list := []DecodeOption{WithComplexShape(ComplexList)}
+	maps := []DecodeOption{WithComplexShape(ComplexMap)}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"list", []interface{}{1.5, -2}, complex(1.5, -2), list, false},
+		{"list complex64", []interface{}{1.5, -2}, complex64(complex(1.5, -2)), list, false},
+		{"list too short", []interface{}{1.5}, complex128(0), list, true},
+		{"list too long", []interface{}{1, 2, 3}, complex128(0), list, true},
+		{"list not numeric", []interface{}{1, "2"}, complex128(0), list, true},
+		{"map", map[string]interface{}{"re": 1.5, "im": -2}, complex(1.5, -2), maps, false},
+		{"map missing key", map[string]interface{}{"re": 1.5}, complex128(0), maps, true},
+		{"map extra key", map[string]interface{}{"re": 1, "im": 2, "x": 3}, complex128(0), maps, true},
+		{"map not numeric", map[string]interface{}{"re": true, "im": 2}, complex128(0), maps, true},
+		{"wrong shape", map[string]interface{}{"re": 1, "im": 2}, complex128(0), list, true},
+		{"no shape", []interface{}{1, 2}, complex128(0), nil, true},
+		{"slice", []interface{}{[]int{1, 2}}, []complex128{complex(1, 2)}, list, false},
+	})
+}
+
+func TestWithComplexEncoding(t *testing.T) {
+	for _, shape := range []ComplexShape{ComplexList, ComplexMap} {
+		v, err := GoToValue(complex(1.5, -2), WithComplexEncoding(shape))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual, err := ValueToGo(v, reflect.TypeOf(complex128(0)), WithComplexShape(shape))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if actual != complex(1.5, -2) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+
+	// Without the option, complex numbers still error
+	if _, err := GoToValue(complex(1.5, -2)); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestWithParallelEncoding(t *testing.T) {
+	type record struct {
+		ID   int      `sentinel:"id"`
+		Tags []string `sentinel:"tags"`
+	}
+
+	for _, n := range []int{10, parallelMinLen, parallelMinLen*3 + 1} {
+		source := make([]record, n)
+		for i := range source {
+			source[i] = record{ID: i, Tags: []string{fmt.Sprint(i)}}
+		}
+
+		expected, err := GoToValue(source)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		for _, workers := range []int{0, 1, 4, 7} {
+			actual, err := GoToValue(source, WithParallelEncoding(workers))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !protobuf.Equal(actual, expected) {
+				t.Fatalf("bad: %d elements with %d workers", n, workers)
+			}
+		}
+	}
+
+	// The error for the first element that fails is returned
+	source := make([]interface{}, parallelMinLen*2)
+	for i := range source {
+		source[i] = i
+	}
+	source[10] = func() {}
+	source[len(source)-1] = make(chan int)
+
+	_, err := GoToValue(source, WithParallelEncoding(4))
+	if err == nil || err.Error() != "cannot convert func to Sentinel value" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// testCamelCase converts SCREAMING_SNAKE_CASE or snake_case to camelCase.
+func testCamelCase(s string) string {
+	parts := strings.Split(strings.ToLower(s), "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+func TestWithKeyTransform(t *testing.T) {
+	type limits struct {
+		MaxCount int `sentinel:"maxCount"`
+	}
+	type config struct {
+		InstanceType string            `sentinel:"instanceType"`
+		Limits       limits            `sentinel:"limits"`
+		Tags         map[string]string `sentinel:"tags"`
+	}
+
+	camel := []DecodeOption{WithKeyTransform(testCamelCase)}
+	testDecodeOptions(t, []decodeOptionTest{
+		{
+			"struct",
+			map[string]interface{}{
+				"INSTANCE_TYPE": "t2.micro",
+				"LIMITS":        map[string]interface{}{"MAX_COUNT": 3},
+				"TAGS":          map[string]string{"COST_CENTER": "eng"},
+			},
+			config{
+				InstanceType: "t2.micro",
+				Limits:       limits{MaxCount: 3},
+				Tags:         map[string]string{"costCenter": "eng"},
+			},
+			camel,
+			false,
+		},
+		{
+			"map",
+			map[string]int{"snake_case_key": 1},
+			map[string]int{"snakeCaseKey": 1},
+			camel,
+			false,
+		},
+		{
+			"interface",
+			map[string]interface{}{"A_B": map[string]interface{}{"C_D": true}},
+			map[string]interface{}{"aB": map[string]bool{"cD": true}},
+			camel,
+			false,
+		},
+		{
+			"int keys",
+			map[int]string{1: "a"},
+			map[int]string{1: "a"},
+			camel,
+			false,
+		},
+		{
+			"without option",
+			map[string]interface{}{"INSTANCE_TYPE": "t2.micro"},
+			config{},
+			nil,
+			false,
+		},
+		{
+			"duplicates after transform",
+			testMapValue("A_B", "1", "a_b", "2"),
+			map[string]string{},
+			append(camel, WithDuplicateKeys(DuplicateKeysError)),
+			true,
+		},
+		{
+			"unknown keys after transform",
+			map[string]interface{}{"INSTANCE_TYP": "t2.micro"},
+			config{},
+			append(camel, WithDisallowUnknownKeys()),
+			true,
+		},
+	})
+}
+
+func TestWithDuplicateKeys(t *testing.T) {
+	type config struct {
+		X string `sentinel:"x"`
+	}
+
+	v := testMapValue("x", "1", "y", "2", "x", "3")
+	cases := []struct {
+		Name   string
+		Policy DuplicateKeys
+		Map    map[string]string
+		Struct config
+		Err    bool
+	}{
+		{"last wins", DuplicateKeysLastWins, map[string]string{"x": "3", "y": "2"}, config{X: "3"}, false},
+		{"first wins", DuplicateKeysFirstWins, map[string]string{"x": "1", "y": "2"}, config{X: "1"}, false},
+		{"error", DuplicateKeysError, nil, config{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opt := WithDuplicateKeys(tc.Policy)
+
+			actual, err := ValueToGo(v, reflect.TypeOf(map[string]string{}), opt)
+			if tc.Err {
+				if err == nil || err.Error() != `duplicate map key "x"` {
+					t.Fatalf("bad: %v", err)
+				}
+			} else if err != nil || !reflect.DeepEqual(actual, tc.Map) {
+				t.Fatalf("bad: %#v %v", actual, err)
+			}
+
+			actual, err = ValueToGo(v, reflect.TypeOf(config{}), opt)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err == nil && !reflect.DeepEqual(actual, tc.Struct) {
+				t.Fatalf("bad: %#v", actual)
+			}
+
+			byKey, err := DecodeMapByKey(v, nil, opt)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err == nil && byKey["x"] != tc.Map["x"] {
+				t.Fatalf("bad: %#v", byKey)
+			}
+
+			var m sync.Map
+			err = ValueToSyncMap(v, stringTyp, stringTyp, &m, opt)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if x, _ := m.Load("x"); err == nil && x != tc.Map["x"] {
+				t.Fatalf("bad: %#v", x)
+			}
+		})
+	}
+
+	// Keys are the same if they decode to the same Go value
+	mixed := &proto.Value{
+		Type: proto.Value_MAP,
+		Value: &proto.Value_ValueMap{
+			ValueMap: &proto.Value_Map{
+				Elems: []*proto.Value_KV{
+					{Key: toValue_string("1"), Value: toValue_string("a")},
+					{
+						Key:   &proto.Value{Type: proto.Value_INT, Value: &proto.Value_ValueInt{ValueInt: 1}},
+						Value: toValue_string("b"),
+					},
+				},
+			},
+		},
+	}
+	_, err := ValueToGo(mixed, reflect.TypeOf(map[string]string{}), WithDuplicateKeys(DuplicateKeysError))
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestWithNumberSeparators(t *testing.T) {
+	european := []DecodeOption{WithNumberSeparators(',', '.')}
+	ungrouped := []DecodeOption{WithNumberSeparators(',', 0)}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"float", "1.234,56", 1234.56, european, false},
+		{"float32", "3,5", float32(3.5), european, false},
+		{"int", "1.234.567", int64(1234567), european, false},
+		{"uint", "1.234", uint(1234), european, false},
+		{"int with decimal", "1,5", int64(0), european, true},
+		{"ungrouped", "3,14", 3.14, ungrouped, false},
+		{"ungrouped dot", "3.14", 0.0, ungrouped, true},
+		{"default", "1.234,56", 0.0, nil, true},
+		{"same separators", "3.5", 3.5, []DecodeOption{WithNumberSeparators('.', '.')}, false},
+	})
+}
+
+func TestWithDisallowUnknownKeys(t *testing.T) {
+	type db struct {
+		Host string `sentinel:"host"`
+	}
+	type config struct {
+		Region string `sentinel:"region"`
+		DB     db     `sentinel:"db"`
+	}
+
+	disallow := []DecodeOption{WithDisallowUnknownKeys()}
+	testDecodeOptions(t, []decodeOptionTest{
+		{"known keys", map[string]string{"region": "eu"}, config{Region: "eu"}, disallow, false},
+		{"unknown key", map[string]string{"reigon": "eu"}, config{}, disallow, true},
+		{
+			"nested unknown key",
+			map[string]interface{}{"db": map[string]string{"hots": "x"}},
+			config{},
+			disallow,
+			true,
+		},
+		{"without option", map[string]string{"reigon": "eu"}, config{}, nil, false},
+		{
+			"case insensitive",
+			map[string]string{"Region": "eu"},
+			config{Region: "eu"},
+			[]DecodeOption{WithDisallowUnknownKeys(), WithCaseInsensitiveKeys()},
+			false,
+		},
+		{
+			"dotted keys",
+			map[string]string{"db.host": "x"},
+			config{DB: db{Host: "x"}},
+			[]DecodeOption{WithDisallowUnknownKeys(), WithExpandDottedKeys()},
+			false,
+		},
+	})
+
+	v := testMapValue("region", "eu", "reigon", "eu", "zone", "a")
+	_, err := ValueToGo(v, reflect.TypeOf(config{}), WithDisallowUnknownKeys())
+	if err == nil || err.Error() != "unknown keys: [reigon zone]" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestWithTimeUnit(t *testing.T) {
+	expected := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	testDecodeOptions(t, []decodeOptionTest{
+		{"seconds", int64(1136214245), expected, nil, false},
+		{"milliseconds", int64(1136214245000), expected, []DecodeOption{WithTimeUnit(time.Millisecond)}, false},
+		{"microseconds", int64(1136214245000000), expected, []DecodeOption{WithTimeUnit(time.Microsecond)}, false},
+		{"nanoseconds", int64(1136214245000000000), expected, []DecodeOption{WithTimeUnit(time.Nanosecond)}, false},
+		{"minutes", int64(1136214245 / 60), expected.Truncate(time.Minute), []DecodeOption{WithTimeUnit(time.Minute)}, false},
+		{"sub-second", int64(1136214245500), expected.Add(500 * time.Millisecond), []DecodeOption{WithTimeUnit(time.Millisecond)}, false},
+		{"pointer", int64(1136214245), &expected, nil, false},
+		{"string", "2006-01-02T15:04:05Z", expected, []DecodeOption{WithTimeUnit(time.Millisecond)}, false},
+		{"bool", true, expected, nil, true},
+	})
+}
+
+func TestWithTimeFromMap(t *testing.T) {
+	utc := []DecodeOption{WithTimeFromMap(nil)}
+	est := time.FixedZone("EST", -5*60*60)
+	testDecodeOptions(t, []decodeOptionTest{
+		{
+			"date",
+			map[string]interface{}{"year": 2024, "month": 1, "day": 15},
+			time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			utc,
+			false,
+		},
+
+		{
+			"date and time",
+			map[string]interface{}{
+				"year": 2024, "month": 2, "day": 29,
+				"hour": 23, "minute": 59, "second": 30, "nanosecond": 500,
+			},
+			time.Date(2024, 2, 29, 23, 59, 30, 500, time.UTC),
+			utc,
+			false,
+		},
+
+		{
+			"defaults",
+			map[string]interface{}{"year": 2024},
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			utc,
+			false,
+		},
+
+		{
+			"location",
+			map[string]interface{}{"year": 2024, "hour": 9},
+			time.Date(2024, 1, 1, 9, 0, 0, 0, est),
+			[]DecodeOption{WithTimeFromMap(est)},
+			false,
+		},
+
+		{
+			"pointer",
+			map[string]interface{}{"year": 2024},
+			func() *time.Time {
+				t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				return &t
+			}(),
+			utc,
+			false,
+		},
+
+		{"missing year", map[string]interface{}{"month": 1}, time.Time{}, utc, true},
+		{"month 13", map[string]interface{}{"year": 2024, "month": 13}, time.Time{}, utc, true},
+		{"month 0", map[string]interface{}{"year": 2024, "month": 0}, time.Time{}, utc, true},
+		{"hour 24", map[string]interface{}{"year": 2024, "hour": 24}, time.Time{}, utc, true},
+		{"february 30", map[string]interface{}{"year": 2024, "month": 2, "day": 30}, time.Time{}, utc, true},
+		{"not a leap year", map[string]interface{}{"year": 2023, "month": 2, "day": 29}, time.Time{}, utc, true},
+		{"unknown key", map[string]interface{}{"year": 2024, "tz": "UTC"}, time.Time{}, utc, true},
+		{"not an integer", map[string]interface{}{"year": 2024, "day": true}, time.Time{}, utc, true},
+		{"without option", map[string]interface{}{"year": 2024}, time.Time{}, nil, false},
+	})
+
+	// Errors name the component
+	v, err := GoToValue(map[string]interface{}{"year": 2024, "month": 13})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = ValueToGo(v, reflect.TypeOf(time.Time{}), utc...)
+	if err == nil || !strings.Contains(err.Error(), "month: 13 out of range") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestValueToGoPresence(t *testing.T) {
+	type db struct {
+		Host string `sentinel:"host"`
+		Port int    `sentinel:"port"`
+	}
+	type config struct {
+		Name  string  `sentinel:"name"`
+		Debug bool    `sentinel:"debug"`
+		Label *string `sentinel:"label"`
+		DB    db      `sentinel:"db"`
+		Extra string  `sentinel:"extra"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"name":  "",
+		"debug": false,
+		"label": nil,
+		"db":    map[string]interface{}{"port": 0},
+		"extra": sdk.Undefined,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, present, err := ValueToGoPresence(v, reflect.TypeOf(config{}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, config{}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	expected := map[string]bool{
+		"name":    true,
+		"debug":   true,
+		"label":   true,
+		"db":      true,
+		"db.port": true,
+	}
+	if !reflect.DeepEqual(present, expected) {
+		t.Fatalf("bad: %#v", present)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	list := make([]interface{}, 5000)
+	for i := range list {
+		list[i] = map[string]interface{}{"id": i}
+	}
+
+	v, err := GoToValue(list)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = ValueToGo(v, nil, WithTimeout(time.Nanosecond))
+	if err == nil || err.Error() != "decode timed out" {
+		t.Fatalf("bad: %v", err)
+	}
+
+	if _, err := ValueToGo(v, nil, WithTimeout(time.Minute)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
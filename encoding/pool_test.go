@@ -0,0 +1,48 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestValuePool(t *testing.T) {
+	p := NewValuePool()
+
+	key := p.Get()
+	key.Type = proto.Value_STRING
+	key.Value = &proto.Value_ValueString{ValueString: "a"}
+
+	elem := p.Get()
+	elem.Type = proto.Value_INT
+	elem.Value = &proto.Value_ValueInt{ValueInt: 42}
+
+	list := p.Get()
+	list.Type = proto.Value_LIST
+	list.Value = &proto.Value_ValueList{ValueList: &proto.Value_List{
+		Elems: []*proto.Value{elem, nil},
+	}}
+
+	v := p.Get()
+	v.Type = proto.Value_MAP
+	v.Value = &proto.Value_ValueMap{ValueMap: &proto.Value_Map{
+		Elems: []*proto.Value_KV{{Key: key, Value: list}, nil},
+	}}
+
+	p.Put(v)
+	p.Put(nil)
+
+	// Every value in the tree is zeroed
+	for _, v := range []*proto.Value{v, key, list, elem} {
+		if v.Type != proto.Value_INVALID || v.Value != nil {
+			t.Fatalf("bad: %#v", v)
+		}
+	}
+
+	// Values from the pool are always zero
+	for i := 0; i < 10; i++ {
+		if v := p.Get(); v.Type != proto.Value_INVALID || v.Value != nil {
+			t.Fatalf("bad: %#v", v)
+		}
+	}
+}
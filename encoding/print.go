@@ -0,0 +1,229 @@
+package encoding
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// PrintOptions configures Fprint.
+type PrintOptions struct {
+	// Indent is the number of spaces to indent each level of nested lists
+	// and maps. If this is zero, the value is printed on a single line.
+	Indent int
+
+	// Color, if set, colors scalar values by type with ANSI escape codes.
+	// This should only be set when printing to a terminal.
+	Color bool
+
+	// MaxDepth is the maximum depth of nested lists and maps to print.
+	// Lists and maps nested more deeply are printed as "…". If this is
+	// zero, there is no maximum depth.
+	MaxDepth int
+}
+
+// ANSI escape codes used to color values by type.
+const (
+	colorReset  = "\x1b[0m"
+	colorBool   = "\x1b[33m" // yellow
+	colorNumber = "\x1b[36m" // cyan
+	colorString = "\x1b[32m" // green
+	colorNull   = "\x1b[90m" // gray
+)
+
+// Fprint writes a human-readable representation of v to w. Values are
+// printed with Sentinel syntax, such as {"a": [1, 2.5, "b"]}. Map keys are
+// sorted so the output is the same every time for the same value,
+// regardless of the order of the map entries. An error is returned if v or
+// a value within it is nil or malformed, in which case the output may be
+// incomplete.
+func Fprint(w io.Writer, v *proto.Value, opts PrintOptions) error {
+	p := &printer{w: w, opts: opts}
+	p.print(v, 0)
+	return p.err
+}
+
+// Sprint returns the single-line representation of v printed by Fprint with
+// the default options. Malformed values are printed as "<invalid: ...>".
+func Sprint(v *proto.Value) string {
+	var buf bytes.Buffer
+	if err := Fprint(&buf, v, PrintOptions{}); err != nil {
+		return "<invalid: " + err.Error() + ">"
+	}
+
+	return buf.String()
+}
+
+// printer holds the state for Fprint. The first error writing to w is
+// kept in err and stops any further output.
+type printer struct {
+	w    io.Writer
+	opts PrintOptions
+	err  error
+}
+
+func (p *printer) write(s string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, s)
+	}
+}
+
+func (p *printer) scalar(s, color string) {
+	if p.opts.Color {
+		s = color + s + colorReset
+	}
+
+	p.write(s)
+}
+
+// newline starts a new line indented for the given depth, or does nothing
+// when printing on a single line.
+func (p *printer) newline(depth int) {
+	if p.opts.Indent > 0 {
+		p.write("\n" + strings.Repeat(" ", depth*p.opts.Indent))
+	}
+}
+
+func (p *printer) print(v *proto.Value, depth int) {
+	if p.err != nil {
+		return
+	}
+	if p.err = checkPayload(v); p.err != nil {
+		return
+	}
+
+	switch v.Type {
+	case proto.Value_UNDEFINED:
+		p.scalar("undefined", colorNull)
+
+	case proto.Value_NULL:
+		p.scalar("null", colorNull)
+
+	case proto.Value_BOOL:
+		p.scalar(strconv.FormatBool(v.Value.(*proto.Value_ValueBool).ValueBool), colorBool)
+
+	case proto.Value_INT:
+		p.scalar(strconv.FormatInt(v.Value.(*proto.Value_ValueInt).ValueInt, 10), colorNumber)
+
+	case proto.Value_FLOAT:
+		p.scalar(formatFloat(v.Value.(*proto.Value_ValueFloat).ValueFloat), colorNumber)
+
+	case proto.Value_STRING:
+		p.scalar(strconv.Quote(v.Value.(*proto.Value_ValueString).ValueString), colorString)
+
+	case proto.Value_LIST:
+		elems := v.Value.(*proto.Value_ValueList).ValueList.Elems
+		if len(elems) == 0 {
+			p.write("[]")
+			return
+		}
+		if p.opts.MaxDepth > 0 && depth >= p.opts.MaxDepth {
+			p.write("[…]")
+			return
+		}
+
+		p.write("[")
+		for i, elt := range elems {
+			if i > 0 {
+				p.write(",")
+				if p.opts.Indent == 0 {
+					p.write(" ")
+				}
+			}
+
+			p.newline(depth + 1)
+			p.print(elt, depth+1)
+		}
+		p.newline(depth)
+		p.write("]")
+
+	case proto.Value_MAP:
+		elems := v.Value.(*proto.Value_ValueMap).ValueMap.Elems
+		if len(elems) == 0 {
+			p.write("{}")
+			return
+		}
+		if p.opts.MaxDepth > 0 && depth >= p.opts.MaxDepth {
+			p.write("{…}")
+			return
+		}
+
+		p.write("{")
+		for i, elt := range sortedMapElems(elems) {
+			if i > 0 {
+				p.write(",")
+				if p.opts.Indent == 0 {
+					p.write(" ")
+				}
+			}
+
+			p.newline(depth + 1)
+			p.print(elt.Key, depth+1)
+			p.write(": ")
+			p.print(elt.Value, depth+1)
+		}
+		p.newline(depth)
+		p.write("}")
+
+	default:
+		p.err = convertErr(v, "printable value")
+	}
+}
+
+// formatFloat formats a float so that it can't be mistaken for an int.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+// sortedMapElems returns a copy of the map entries sorted by key. Keys are
+// ordered by type and then by value, with numbers compared numerically.
+// The order of the entries is otherwise unspecified, so this gives them a
+// stable order for output.
+func sortedMapElems(elems []*proto.Value_KV) []*proto.Value_KV {
+	result := make([]*proto.Value_KV, len(elems))
+	copy(result, elems)
+	sort.SliceStable(result, func(i, j int) bool {
+		return valueLess(result[i].Key, result[j].Key)
+	})
+
+	return result
+}
+
+// valueLess orders values for sortedMapElems. Values of different types
+// are ordered by type. LIST and MAP values, which are rarely keys, are
+// ordered by their protobuf text representation.
+func valueLess(a, b *proto.Value) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+
+	switch a.Type {
+	case proto.Value_BOOL:
+		return !a.GetValueBool() && b.GetValueBool()
+
+	case proto.Value_INT:
+		return a.GetValueInt() < b.GetValueInt()
+
+	case proto.Value_FLOAT:
+		return a.GetValueFloat() < b.GetValueFloat()
+
+	case proto.Value_STRING:
+		return a.GetValueString() < b.GetValueString()
+
+	case proto.Value_LIST, proto.Value_MAP:
+		return a.String() < b.String()
+
+	default:
+		return false
+	}
+}
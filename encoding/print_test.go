@@ -0,0 +1,147 @@
+package encoding
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+func TestFprint(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   interface{}
+		Opts     PrintOptions
+		Expected string
+	}{
+		{"null", nil, PrintOptions{}, "null"},
+		{"undefined", sdk.Undefined, PrintOptions{}, "undefined"},
+		{"bool", true, PrintOptions{}, "true"},
+		{"int", 42, PrintOptions{}, "42"},
+		{"float", 1.5, PrintOptions{}, "1.5"},
+		{"whole float", 2.0, PrintOptions{}, "2.0"},
+		{"infinite float", math.Inf(-1), PrintOptions{}, "-Inf"},
+		{"string", "a \"b\"\n", PrintOptions{}, `"a \"b\"\n"`},
+		{"empty list", []int{}, PrintOptions{}, "[]"},
+		{"empty map", map[string]int{}, PrintOptions{}, "{}"},
+
+		{
+			"list",
+			[]interface{}{1, "two", nil},
+			PrintOptions{},
+			`[1, "two", null]`,
+		},
+
+		{
+			"map with sorted keys",
+			map[string]interface{}{"b": 1, "a": []int{1, 2}, "c": "three"},
+			PrintOptions{},
+			`{"a": [1, 2], "b": 1, "c": "three"}`,
+		},
+
+		{
+			"map with int keys",
+			map[int]string{10: "ten", 9: "nine", -1: "minus one"},
+			PrintOptions{},
+			`{-1: "minus one", 9: "nine", 10: "ten"}`,
+		},
+
+		{
+			"indent",
+			map[string]interface{}{"a": []int{1, 2}, "b": map[string]int{}},
+			PrintOptions{Indent: 2},
+			"{\n" +
+				"  \"a\": [\n" +
+				"    1,\n" +
+				"    2\n" +
+				"  ],\n" +
+				"  \"b\": {}\n" +
+				"}",
+		},
+
+		{
+			"max depth",
+			map[string]interface{}{"a": []interface{}{1, []int{2}}, "b": 3},
+			PrintOptions{MaxDepth: 1},
+			`{"a": […], "b": 3}`,
+		},
+
+		{
+			"color",
+			[]interface{}{true, 1, "a", nil},
+			PrintOptions{Color: true},
+			"[" + colorBool + "true" + colorReset + ", " +
+				colorNumber + "1" + colorReset + ", " +
+				colorString + `"a"` + colorReset + ", " +
+				colorNull + "null" + colorReset + "]",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			var buf bytes.Buffer
+			if err := Fprint(&buf, v, tc.Opts); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if actual := buf.String(); actual != tc.Expected {
+				t.Fatalf("bad: %s", actual)
+			}
+		})
+	}
+}
+
+func TestSprint(t *testing.T) {
+	v, err := GoToValue(map[string]interface{}{"b": 2, "a": 1})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The output must be stable regardless of map ordering
+	for i := 0; i < 10; i++ {
+		if actual := Sprint(v); actual != `{"a": 1, "b": 2}` {
+			t.Fatalf("bad: %s", actual)
+		}
+	}
+}
+
+func TestSprint_malformed(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    *proto.Value
+		Expected string
+	}{
+		{"nil", nil, "<invalid: nil value>"},
+		{"no payload", &proto.Value{Type: proto.Value_INT}, "<invalid: proto.Value has type INT but no payload>"},
+		{
+			"nil element",
+			&proto.Value{
+				Type: proto.Value_LIST,
+				Value: &proto.Value_ValueList{
+					ValueList: &proto.Value_List{Elems: []*proto.Value{nil}},
+				},
+			},
+			"<invalid: nil list element 0>",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := Sprint(tc.Value); actual != tc.Expected {
+				t.Fatalf("bad: %s", actual)
+			}
+
+			var buf bytes.Buffer
+			if err := Fprint(&buf, tc.Value, PrintOptions{Indent: 2}); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+//go:build go1.23
+
+package encoding
+
+import (
+	"iter"
+	"reflect"
+
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Seq returns an iterator over the elements of the LIST v, decoding each
+// element into a T as the loop advances rather than decoding the whole list
+// into a slice first. Elements are decoded the same way as by ValueToSlice
+// into a []T.
+//
+// If an element can't be decoded, or v is nil or isn't a LIST, the
+// iterator yields the zero T with the error and stops. Elements before it
+// have already been yielded, so a loop should check the error on every
+// iteration:
+//
+//	for name, err := range encoding.Seq[string](v) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// This requires Go 1.23 or later, for range-over-func.
+func Seq[T any](v *proto.Value, opts ...DecodeOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if err := checkPayload(v); err != nil {
+			yield(zero, err)
+			return
+		}
+
+		d := getDecoder(opts)
+		defer putDecoder(d)
+
+		if err := d.checkLen(v); err != nil {
+			yield(zero, err)
+			return
+		}
+
+		elems, ok := d.listElems(v)
+		if !ok {
+			yield(zero, convertErr(v, "list"))
+			return
+		}
+
+		elemTyp := reflect.TypeOf((*T)(nil)).Elem()
+		for i, elt := range elems {
+			n := d.pushIndex(i)
+			raw, err := d.valueToGo(elt, elemTyp)
+			d.popPath(n)
+			if err != nil {
+				yield(zero, d.nestErr(err, "element %d", i))
+				return
+			}
+
+			// raw is nil for an interface T when the element is nil
+			elem, _ := raw.(T)
+			if !yield(elem, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+//go:build go1.23
+
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeq(t *testing.T) {
+	v, err := GoToValue([]interface{}{1, 2, 3})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual []int
+	for elem, err := range Seq[int](v) {
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual = append(actual, elem)
+	}
+
+	if expected := []int{1, 2, 3}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Breaking out of the loop stops decoding
+	actual = nil
+	for elem := range Seq[int](v) {
+		actual = append(actual, elem)
+		break
+	}
+
+	if expected := []int{1}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestSeq_error(t *testing.T) {
+	v, err := GoToValue([]interface{}{"a", true, "c"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual []string
+	var lastErr error
+	for elem, err := range Seq[string](v) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		actual = append(actual, elem)
+	}
+
+	if expected := []string{"a"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if lastErr == nil || lastErr.Error() != "element 1: cannot convert to string: BOOL" {
+		t.Fatalf("bad: %v", lastErr)
+	}
+
+	// Values that aren't lists error
+	v, err = GoToValue(42)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	n := 0
+	for _, err := range Seq[string](v) {
+		n++
+		if err == nil {
+			t.Fatal("should error")
+		}
+	}
+	if n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
+
+func TestSeq_nil(t *testing.T) {
+	n := 0
+	for elem, err := range Seq[string](nil) {
+		n++
+		if err == nil || err.Error() != "nil value" || elem != "" {
+			t.Fatalf("bad: %q %v", elem, err)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
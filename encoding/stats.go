@@ -0,0 +1,123 @@
+package encoding
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// typeStatsEnabled is non-zero if decode timings are recorded. This should
+// be modified with sync/atomic.
+var typeStatsEnabled int32
+
+// EnableTypeStats enables or disables recording how long ValueToGo takes
+// to decode each struct type and each of its fields, which can be
+// retrieved with TypeStats. This is disabled by default since timing every
+// struct adds overhead to decoding.
+func EnableTypeStats(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+
+	atomic.StoreInt32(&typeStatsEnabled, v)
+}
+
+// TypeStat is the decode timing for a struct type.
+type TypeStat struct {
+	// Count is the number of values decoded into the type and Total is
+	// the time spent decoding them. The time for a struct includes the
+	// time to decode its fields, including nested structs.
+	Count uint64
+	Total time.Duration
+
+	// Fields is the timing for each field of the struct that was decoded,
+	// keyed by the field name.
+	Fields map[string]FieldStat
+}
+
+// FieldStat is the decode timing for a struct field.
+type FieldStat struct {
+	Count uint64
+	Total time.Duration
+}
+
+// TypeStats returns the decode timings recorded for each struct type
+// since stats were enabled with EnableTypeStats or last reset with
+// ResetTypeStats. Types that haven't been decoded aren't included.
+func TypeStats() map[reflect.Type]TypeStat {
+	result := make(map[reflect.Type]TypeStat)
+	structCache.Range(func(k, v interface{}) bool {
+		info := v.(*structInfo)
+		count, total := info.stats.load()
+		if count == 0 {
+			return true
+		}
+
+		stat := TypeStat{
+			Count:  count,
+			Total:  total,
+			Fields: make(map[string]FieldStat, len(info.Fields)),
+		}
+		for _, field := range info.Fields {
+			count, total := field.stats.load()
+			if count > 0 {
+				stat.Fields[field.Name] = FieldStat{Count: count, Total: total}
+			}
+		}
+
+		result[k.(reflect.Type)] = stat
+		return true
+	})
+
+	return result
+}
+
+// ResetTypeStats clears the decode timings returned by TypeStats.
+func ResetTypeStats() {
+	structCache.Range(func(k, v interface{}) bool {
+		info := v.(*structInfo)
+		info.stats.reset()
+		for _, field := range info.Fields {
+			field.stats.reset()
+		}
+
+		return true
+	})
+}
+
+// decodeStats accumulates decode timings. The fields should be modified
+// with sync/atomic.
+type decodeStats struct {
+	count uint64
+	nanos uint64
+}
+
+// start returns the time to pass to record, or the zero time if stats
+// aren't enabled.
+func (s *decodeStats) start() time.Time {
+	if atomic.LoadInt32(&typeStatsEnabled) == 0 {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// record adds the time since start to the stats, unless start is zero.
+func (s *decodeStats) record(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+
+	atomic.AddUint64(&s.count, 1)
+	atomic.AddUint64(&s.nanos, uint64(time.Since(start)))
+}
+
+func (s *decodeStats) load() (uint64, time.Duration) {
+	return atomic.LoadUint64(&s.count), time.Duration(atomic.LoadUint64(&s.nanos))
+}
+
+func (s *decodeStats) reset() {
+	atomic.StoreUint64(&s.count, 0)
+	atomic.StoreUint64(&s.nanos, 0)
+}
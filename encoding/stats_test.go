@@ -0,0 +1,62 @@
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypeStats(t *testing.T) {
+	type inner struct {
+		Name string `sentinel:"name"`
+	}
+	type outer struct {
+		Inner inner `sentinel:"inner"`
+		Count int   `sentinel:"count"`
+	}
+
+	v, err := GoToValue(map[string]interface{}{
+		"inner": map[string]interface{}{"name": "foo"},
+		"count": 3,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing is recorded while disabled
+	if _, err := ValueToGo(v, reflect.TypeOf(outer{})); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := TypeStats()[reflect.TypeOf(outer{})]; ok {
+		t.Fatal("should not record stats while disabled")
+	}
+
+	EnableTypeStats(true)
+	defer EnableTypeStats(false)
+	defer ResetTypeStats()
+
+	for i := 0; i < 2; i++ {
+		if _, err := ValueToGo(v, reflect.TypeOf(outer{})); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	stats := TypeStats()
+	outerStat := stats[reflect.TypeOf(outer{})]
+	if outerStat.Count != 2 {
+		t.Fatalf("bad: %#v", outerStat)
+	}
+	if outerStat.Fields["Inner"].Count != 2 || outerStat.Fields["Count"].Count != 2 {
+		t.Fatalf("bad: %#v", outerStat.Fields)
+	}
+	if outerStat.Total < outerStat.Fields["Inner"].Total {
+		t.Fatalf("struct total should include fields: %#v", outerStat)
+	}
+	if stats[reflect.TypeOf(inner{})].Count != 2 {
+		t.Fatalf("bad: %#v", stats[reflect.TypeOf(inner{})])
+	}
+
+	ResetTypeStats()
+	if len(TypeStats()) != 0 {
+		t.Fatalf("bad: %#v", TypeStats())
+	}
+}
@@ -0,0 +1,436 @@
+package encoding
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// Protobuf tags for the fields of Value and its nested messages. A tag is
+// the field number shifted left by 3, ORed with the wire type.
+const (
+	tagValueType   = 1<<3 | 0 // varint
+	tagValueBool   = 2<<3 | 0 // varint
+	tagValueInt    = 3<<3 | 0 // varint
+	tagValueFloat  = 4<<3 | 1 // fixed64
+	tagValueString = 5<<3 | 2 // length-delimited
+	tagValueList   = 6<<3 | 2 // length-delimited
+	tagValueMap    = 7<<3 | 2 // length-delimited
+	tagElems       = 1<<3 | 2 // List.elems and Map.elems
+	tagKVKey       = 1<<3 | 2
+	tagKVValue     = 2<<3 | 2
+)
+
+// EncodeStream converts the Go value to a Value like GoToValue and writes
+// it to w as a length-delimited protobuf message: the size of the message
+// as a varint, followed by the serialized message. The serialized message
+// is byte-for-byte the same as proto.Marshal of the Value returned by
+// GoToValue (except for the order of map entries, which is unspecified),
+// so it can be read with DecodeStream or any protobuf library's delimited
+// message reader.
+//
+// Rather than building the Value in memory, the Go value is walked twice:
+// once to compute the size of each nested message, and once to write it.
+// Memory use is proportional to the number of lists and maps rather than
+// the size of the value. Values converted with a registered Converter or
+// with encoding.TextMarshaler are built in memory since the converter
+// returns a Value.
+//
+// The same rules for concurrent modification of maps apply as for
+// GoToValue. Additionally, the value must not be modified at all until
+// EncodeStream returns since it is read twice.
+func EncodeStream(w io.Writer, g interface{}) error {
+	// Size pass
+	s := &streamEncoder{sizing: true}
+	if err := s.message(0, func() error { return s.value(reflect.ValueOf(g)) }); err != nil {
+		return err
+	}
+
+	// Write pass, reusing the sizes and map keys from the size pass
+	bw := bufio.NewWriter(w)
+	s.sizing = false
+	s.w = bw
+	if err := s.message(0, func() error { return s.value(reflect.ValueOf(g)) }); err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	return bw.Flush()
+}
+
+// DecodeStream reads a single length-delimited Value written by
+// EncodeStream from r. It doesn't read past the end of the message if r
+// implements io.ByteReader, so multiple values can be read in sequence.
+func DecodeStream(r io.Reader) (*proto.Value, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r: r}
+	}
+
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	if size > math.MaxInt32 {
+		return nil, fmt.Errorf("value of size %d is too large", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	var v proto.Value
+	if err := protobuf.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// byteReader implements io.ByteReader for an io.Reader by reading one
+// byte at a time.
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.r, r.buf[:]); err != nil {
+		return 0, err
+	}
+
+	return r.buf[0], nil
+}
+
+// streamEncoder holds the state for EncodeStream. During the size pass
+// (sizing is true) nothing is written; n counts the bytes that would be
+// written, and the size of every nested message, the keys of every map
+// and the result of every converter are recorded in the order they are
+// visited. The write pass visits the value in the same order and consumes
+// them.
+type streamEncoder struct {
+	sizing bool
+	n      int
+	w      *bufio.Writer
+	err    error
+
+	sizes     []int
+	keys      [][]reflect.Value
+	converted []*proto.Value
+
+	nextSize, nextKeys, nextConverted int
+
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (s *streamEncoder) write(b []byte) {
+	if s.sizing {
+		s.n += len(b)
+		return
+	}
+
+	if s.err == nil {
+		_, s.err = s.w.Write(b)
+	}
+}
+
+func (s *streamEncoder) varint(x uint64) {
+	s.write(s.buf[:binary.PutUvarint(s.buf[:], x)])
+}
+
+func (s *streamEncoder) fixed64(x uint64) {
+	binary.LittleEndian.PutUint64(s.buf[:8], x)
+	s.write(s.buf[:8])
+}
+
+func (s *streamEncoder) bytes(tag uint64, b string) {
+	s.varint(tag)
+	s.varint(uint64(len(b)))
+	if s.sizing {
+		s.n += len(b)
+	} else if s.err == nil {
+		_, s.err = s.w.WriteString(b)
+	}
+}
+
+// message writes a nested message with the given tag, or the top-level
+// message if the tag is zero, whose contents are written by f.
+func (s *streamEncoder) message(tag uint64, f func() error) error {
+	if s.sizing {
+		i := len(s.sizes)
+		s.sizes = append(s.sizes, 0)
+		start := s.n
+		if err := f(); err != nil {
+			return err
+		}
+
+		size := s.n - start
+		s.sizes[i] = size
+		if tag != 0 {
+			s.varint(tag)
+		}
+		s.varint(uint64(size))
+		return nil
+	}
+
+	size := s.sizes[s.nextSize]
+	s.nextSize++
+	if tag != 0 {
+		s.varint(tag)
+	}
+	s.varint(uint64(size))
+	return f()
+}
+
+// typ writes the type field of a Value.
+func (s *streamEncoder) typ(t proto.Value_Type) {
+	s.varint(tagValueType)
+	s.varint(uint64(t))
+}
+
+// value writes the contents of the Value for v. This must convert values
+// exactly as toValue_reflect does.
+func (s *streamEncoder) value(v reflect.Value) error {
+	// Null pointer
+	if !v.IsValid() {
+		s.typ(proto.Value_NULL)
+		return nil
+	}
+
+	if c := lookupConverter(v.Type()); c != nil && c.Encode != nil && v.CanInterface() {
+		return s.convert(c, v)
+	}
+	if mv, ok := textMarshaler(v); ok {
+		return s.convert(textConverter, mv)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return s.value(v.Elem())
+
+	case reflect.Ptr:
+		switch v.Interface() {
+		case sdk.Null:
+			s.typ(proto.Value_NULL)
+			return nil
+
+		case sdk.Undefined:
+			s.typ(proto.Value_UNDEFINED)
+			return nil
+		}
+
+		return s.value(v.Elem())
+
+	case reflect.Bool:
+		s.typ(proto.Value_BOOL)
+		s.varint(tagValueBool)
+		if v.Bool() {
+			s.varint(1)
+		} else {
+			s.varint(0)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.typ(proto.Value_INT)
+		s.varint(tagValueInt)
+		s.varint(uint64(v.Int()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.typ(proto.Value_INT)
+		s.varint(tagValueInt)
+		s.varint(v.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		s.typ(proto.Value_FLOAT)
+		s.varint(tagValueFloat)
+		s.fixed64(math.Float64bits(v.Float()))
+
+	case reflect.Complex64, reflect.Complex128:
+		return errors.New("cannot convert complex number to Sentinel value")
+
+	case reflect.String:
+		s.typ(proto.Value_STRING)
+		s.bytes(tagValueString, v.String())
+
+	case reflect.Array, reflect.Slice:
+		s.typ(proto.Value_LIST)
+		return s.message(tagValueList, func() error {
+			for i := 0; i < v.Len(); i++ {
+				elem := v.Index(i)
+				if err := s.message(tagElems, func() error { return s.value(elem) }); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+	case reflect.Map:
+		s.typ(proto.Value_MAP)
+		keys := s.mapKeys(v)
+		return s.message(tagValueMap, func() error {
+			for _, key := range keys {
+				key := key
+				err := s.message(tagElems, func() error {
+					if err := s.message(tagKVKey, func() error { return s.value(key) }); err != nil {
+						return fmt.Errorf("key %v: %s", key, err)
+					}
+
+					err := s.message(tagKVValue, func() error { return s.value(v.MapIndex(key)) })
+					if err != nil {
+						return fmt.Errorf("element for key %v: %s", key, err)
+					}
+
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+	case reflect.Struct:
+		info, err := cachedStructInfo(v.Type())
+		if err != nil {
+			return err
+		}
+
+		s.typ(proto.Value_MAP)
+		return s.message(tagValueMap, func() error {
+			for _, f := range info.Fields {
+				key := f.Key
+				field := v.Field(f.Index)
+				if f.OmitEmpty && IsZeroValue(field) {
+					continue
+				}
+
+				enc := f.Encoding
+				err := s.message(tagElems, func() error {
+					err := s.message(tagKVKey, func() error {
+						s.typ(proto.Value_STRING)
+						s.bytes(tagValueString, key)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					return s.message(tagKVValue, func() error {
+						if enc != "" {
+							s.typ(proto.Value_STRING)
+							s.bytes(tagValueString, encodeByteArray(field, enc))
+							return nil
+						}
+
+						return s.value(field)
+					})
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if info.Remaining == nil {
+				return nil
+			}
+
+			m := v.Field(info.Remaining.Index)
+			for _, key := range info.remainingKeys(v) {
+				key := key
+				err := s.message(tagElems, func() error {
+					err := s.message(tagKVKey, func() error {
+						s.typ(proto.Value_STRING)
+						s.bytes(tagValueString, key)
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+
+					err = s.message(tagKVValue, func() error {
+						return s.value(m.MapIndex(reflect.ValueOf(key)))
+					})
+					if err != nil {
+						return fmt.Errorf("element for key %q: %s", key, err)
+					}
+
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+	case reflect.Chan:
+		return errors.New("cannot convert channel to Sentinel value")
+
+	case reflect.Func:
+		return errors.New("cannot convert func to Sentinel value")
+
+	default:
+		return fmt.Errorf("cannot convert type %s to Sentinel value", v.Kind())
+	}
+
+	return nil
+}
+
+// mapKeys returns the keys of the map. Map iteration order is random, so
+// the keys from the size pass are kept for the write pass.
+func (s *streamEncoder) mapKeys(v reflect.Value) []reflect.Value {
+	if s.sizing {
+		keys := v.MapKeys()
+		s.keys = append(s.keys, keys)
+		return keys
+	}
+
+	keys := s.keys[s.nextKeys]
+	s.keys[s.nextKeys] = nil
+	s.nextKeys++
+	return keys
+}
+
+// convert writes the value returned by the converter. The converter is
+// only called during the size pass so that it can't return a different
+// value for the write pass.
+func (s *streamEncoder) convert(c *Converter, v reflect.Value) error {
+	var pv *proto.Value
+	if s.sizing {
+		var err error
+		pv, err = c.Encode(v.Interface())
+		if err != nil {
+			return err
+		}
+
+		s.converted = append(s.converted, pv)
+		s.n += protobuf.Size(pv)
+		return nil
+	}
+
+	pv = s.converted[s.nextConverted]
+	s.converted[s.nextConverted] = nil
+	s.nextConverted++
+
+	data, err := protobuf.Marshal(pv)
+	if err != nil {
+		return err
+	}
+
+	s.write(data)
+	return nil
+}
@@ -0,0 +1,140 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/url"
+	"reflect"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+)
+
+func TestEncodeStream(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Source interface{}
+	}{
+		{"nil", nil},
+		{"null", sdk.Null},
+		{"undefined", sdk.Undefined},
+		{"bool", true},
+		{"false", false},
+		{"int", 42},
+		{"zero int", 0},
+		{"negative int", -42},
+		{"large uint", uint64(math.MaxUint64)},
+		{"float", 1.5},
+		{"zero float", 0.0},
+		{"string", "foo"},
+		{"empty string", ""},
+		{"empty list", []int{}},
+		{"list", []interface{}{1, "two", 3.0, nil, []int{4}}},
+		{"array", [2]string{"a", "b"}},
+		{"single key map", map[string]interface{}{"a": []int{1, 2}}},
+		{"empty map", map[string]int{}},
+		{"struct", testStruct{Name: "foo", Count: 2, Tags: []string{"a"}}},
+		{"nil pointer", (*int)(nil)},
+		{"pointer", testIntPtr(42)},
+		{"converter", testURL("https://example.com/")},
+		{"nested converter", []*url.URL{testURL("https://example.com/"), nil}},
+		{"text marshaler", []testCSV{{"a", "b"}, nil}},
+		{"encoded byte array", testDigest{SHA: [2]byte{0xab, 0xcd}}},
+		{"omitempty", testOmitEmpty{Set: "a"}},
+		{"remaining", testRemaining{Name: "a", Rest: map[string]interface{}{"x": 1, "y": "b", "name": "c"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeStream(&buf, tc.Source); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// The output must be exactly the delimited marshaled value
+			v, err := GoToValue(tc.Source)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			data, err := protobuf.Marshal(v)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			expected := make([]byte, binary.MaxVarintLen64)
+			expected = append(expected[:binary.PutUvarint(expected, uint64(len(data)))], data...)
+			if !bytes.Equal(buf.Bytes(), expected) {
+				t.Fatalf("bad: %x\n\nexpected: %x", buf.Bytes(), expected)
+			}
+		})
+	}
+}
+
+func TestEncodeStream_roundTrip(t *testing.T) {
+	source := map[string]interface{}{
+		"a": []interface{}{1, map[string]interface{}{"b": true, "c": "d"}},
+		"e": map[int]float64{1: 1.5, 2: 2.5, 3: 3.5},
+		"f": nil,
+	}
+
+	// Write two values to check DecodeStream stops at the end of each
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if err := EncodeStream(&buf, source); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		v, err := DecodeStream(&buf)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual, err := ValueToGo(v, reflect.TypeOf(map[string]interface{}{}))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		expected := map[string]interface{}{
+			"a": []interface{}{int64(1), map[string]interface{}{"b": true, "c": "d"}},
+			"e": map[int64]float64{1: 1.5, 2: 2.5, 3: 3.5},
+			"f": sdk.Null,
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("bad: %d bytes left", buf.Len())
+	}
+}
+
+func TestEncodeStream_error(t *testing.T) {
+	var buf bytes.Buffer
+	err := EncodeStream(&buf, map[string]interface{}{"a": []interface{}{1, func() {}}})
+	if err == nil {
+		t.Fatal("should error")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("nothing should be written: %x", buf.Bytes())
+	}
+}
+
+type testDigest struct {
+	SHA [2]byte `sentinel:"sha,hex"`
+}
+
+type testRemaining struct {
+	Name string                 `sentinel:"name"`
+	Rest map[string]interface{} `sentinel:",remaining"`
+}
+
+type testOmitEmpty struct {
+	Set   string `sentinel:"set,omitempty"`
+	Unset string `sentinel:"unset,omitempty"`
+}
@@ -0,0 +1,636 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+)
+
+// structField is a struct field that is converted to or from a map entry.
+type structField struct {
+	Index      int          // index of the field in the struct
+	Name       string       // name of the field, for errors
+	Type       reflect.Type // type of the field
+	Key        string       // map key for the field
+	KeyValue   *proto.Value // Key as a STRING value
+	Default    string       // value of the "default" tag
+	HasDefault bool         // true if the field has a "default" tag
+	Join       string       // separator of the "join" tag option
+	HasJoin    bool         // true if the field has a "join" tag option
+	Encoding   string       // "hex" or "base64" tag option, or empty
+	OmitEmpty  bool         // true if the field has the "omitempty" tag option
+	Unwrap     string       // key of the "unwrap" tag option, or empty
+	Remaining  bool         // true if the field has the "remaining" tag option
+
+	stats *decodeStats // decode timings, see EnableTypeStats
+}
+
+// structInfo is the result of analyzing a struct type for conversion.
+type structInfo struct {
+	// stats is first so that it is 64-bit aligned for sync/atomic
+	stats decodeStats
+
+	Fields []structField
+
+	// Remaining is the field with the "remaining" tag option, if any. It
+	// isn't in Fields since it isn't matched to a key.
+	Remaining *structField
+
+	// Err is set if the struct type can't be converted, such as when two
+	// fields have the same key.
+	Err error
+}
+
+// remainingTyp is the type of fields with the "remaining" tag option.
+var remainingTyp = reflect.TypeOf(map[string]interface{}(nil))
+
+// structCache is the map[reflect.Type]*structInfo cache of analyzed struct
+// types, since struct types are usually converted many times.
+var structCache sync.Map
+
+// cachedStructInfo returns the analysis of the struct type t.
+func cachedStructInfo(t reflect.Type) (*structInfo, error) {
+	if v, ok := structCache.Load(t); ok {
+		info := v.(*structInfo)
+		return info, info.Err
+	}
+
+	info := &structInfo{}
+	fieldsByKey := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, opts, ok := structFieldKey(field)
+		if !ok {
+			continue
+		}
+
+		def, hasDef := field.Tag.Lookup("default")
+		f := structField{
+			Index:      i,
+			Name:       field.Name,
+			Type:       field.Type,
+			Key:        key,
+			KeyValue:   toValue_string(key),
+			Default:    def,
+			HasDefault: hasDef,
+			stats:      &decodeStats{},
+		}
+		if err := f.parseOptions(opts); err != nil {
+			info.Err = fmt.Errorf("field %s: %s", field.Name, err)
+			break
+		}
+
+		// The remaining field has no key of its own
+		if f.Remaining {
+			if info.Remaining != nil {
+				info.Err = fmt.Errorf(
+					"sentinel tag option remaining on fields %s and %s", info.Remaining.Name, field.Name)
+				break
+			}
+
+			info.Remaining = &f
+			continue
+		}
+
+		if other, ok := fieldsByKey[key]; ok {
+			info.Err = fmt.Errorf(
+				"duplicate sentinel tag %q on fields %s and %s", key, other, field.Name)
+			break
+		}
+		fieldsByKey[key] = field.Name
+
+		info.Fields = append(info.Fields, f)
+	}
+
+	// Another goroutine may have analyzed the type at the same time, in
+	// which case the results are the same, so either can be used.
+	v, _ := structCache.LoadOrStore(t, info)
+	info = v.(*structInfo)
+	return info, info.Err
+}
+
+// parseOptions sets the options of the field from the options following
+// the key in its "sentinel" tag.
+func (f *structField) parseOptions(opts string) error {
+	for opts != "" {
+		// The join option must be last since the separator may contain
+		// commas.
+		if strings.HasPrefix(opts, "join=") {
+			if f.Type.Kind() != reflect.String {
+				return fmt.Errorf("sentinel tag option join requires a string field")
+			}
+
+			f.Join, f.HasJoin = strings.TrimPrefix(opts, "join="), true
+			return nil
+		}
+
+		opt := opts
+		opts = ""
+		if idx := strings.IndexByte(opt, ','); idx >= 0 {
+			opt, opts = opt[:idx], opt[idx+1:]
+		}
+
+		if strings.HasPrefix(opt, "unwrap=") {
+			f.Unwrap = strings.TrimPrefix(opt, "unwrap=")
+			if f.Unwrap == "" {
+				return fmt.Errorf("sentinel tag option unwrap requires a key")
+			}
+
+			continue
+		}
+
+		switch opt {
+		case "hex", "base64":
+			if f.Type.Kind() != reflect.Array || f.Type.Elem().Kind() != reflect.Uint8 {
+				return fmt.Errorf("sentinel tag option %s requires a byte array field", opt)
+			}
+			if f.Encoding != "" {
+				return fmt.Errorf("sentinel tag options %s and %s conflict", f.Encoding, opt)
+			}
+
+			f.Encoding = opt
+
+		case "omitempty":
+			f.OmitEmpty = true
+
+		case "remaining":
+			if f.Type != remainingTyp {
+				return fmt.Errorf("sentinel tag option remaining requires a map[string]interface{} field")
+			}
+
+			f.Remaining = true
+
+		default:
+			return fmt.Errorf("unknown sentinel tag option %q", opt)
+		}
+	}
+
+	return nil
+}
+
+// convertValueStruct converts a MAP to a struct. Fields are matched to map
+// keys the same way GoToValue converts structs to maps: by the "sentinel"
+// tag if present, otherwise by the field name. Unexported fields, fields
+// with an empty "sentinel" tag and map keys that don't match a field are
+// ignored.
+//
+// If a key is missing or UNDEFINED, the field is set from its "default"
+// tag, which is parsed as if it were a STRING value for the field (or with
+// strconv.ParseBool for bool fields). Otherwise the field is left as the
+// zero value. NULL values leave pointer, slice and map fields nil.
+//
+// A string field with the "join" tag option, such as `sentinel:"tags,join=,"`,
+// can also be set from a LIST. The elements are decoded as strings and
+// joined with the separator following "join=", which may contain commas.
+//
+// A byte array field with the "hex" or "base64" tag option, such as
+// `sentinel:"sha,hex"`, is set from a STRING with that encoding, which must
+// decode to exactly the length of the array. base64 is the standard
+// encoding with padding. GoToValue encodes these fields the same way.
+//
+// A field with the "unwrap" tag option, such as `sentinel:"count,unwrap=value"`,
+// can also be set from a MAP wrapping the value, such as {"value": 42}. The
+// value for the key following "unwrap=" is decoded into the field, and it is
+// an error if the MAP doesn't have that key. Other values are decoded as
+// usual, and GoToValue encodes the field unwrapped.
+//
+// The "omitempty" tag option, such as `sentinel:"name,omitempty"`, makes
+// GoToValue omit the field from the map if it is empty, as reported by
+// IsZeroValue. Decoding isn't affected.
+//
+// A map[string]interface{} field with the "remaining" tag option, such as
+// `sentinel:",remaining"`, is set to the entries whose keys don't match
+// any other field, decoded as with a nil type, so that they can be passed
+// through or logged. It is left nil if every key matches a field. It can't
+// be used with WithDisallowUnknownKeys, which is an error since no keys can
+// be unknown. GoToValue encodes its entries into the map alongside the
+// other fields, skipping any with the key of another field.
+//
+// See WithCaseInsensitiveKeys for how keys are matched ignoring case,
+// WithExpandDottedKeys for how dotted keys are matched to nested structs and
+// WithKeyTransform for changing keys before they are matched.
+func (d *decoder) convertValueStruct(raw *proto.Value, t reflect.Type) (interface{}, error) {
+	if raw.Type != proto.Value_MAP {
+		return nil, convertErr(raw, "struct")
+	}
+
+	info, err := cachedStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	if info.Remaining != nil && d.disallowUnknownKeys {
+		return nil, fmt.Errorf(
+			"field %s: sentinel tag option remaining can't be used with WithDisallowUnknownKeys",
+			info.Remaining.Name)
+	}
+	start := info.stats.start()
+
+	elems := raw.Value.(*proto.Value_ValueMap).ValueMap.Elems
+	if d.keyTransform != nil {
+		transformed := make([]*proto.Value_KV, len(elems))
+		for i, elt := range elems {
+			transformed[i] = &proto.Value_KV{Key: d.transformKey(elt.Key), Value: elt.Value}
+		}
+
+		elems = transformed
+	}
+	if d.expandDottedKeys {
+		elems, err = expandDottedKeys(elems, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Index the values by key. Only STRING keys can match a field.
+	values := make(map[string]*proto.Value, len(elems))
+	var folded map[string]*proto.Value
+	if d.caseInsensitiveKeys {
+		folded = make(map[string]*proto.Value, len(elems))
+	}
+	for _, elt := range elems {
+		if k, ok := elt.Key.Value.(*proto.Value_ValueString); ok {
+			if _, ok := values[k.ValueString]; ok && d.duplicateKeys != DuplicateKeysLastWins {
+				if d.duplicateKeys == DuplicateKeysError {
+					return nil, duplicateKeyErr(elt.Key)
+				}
+
+				continue
+			}
+
+			values[k.ValueString] = elt.Value
+
+			if folded != nil {
+				// The first entry wins if several keys differ only by case
+				lower := strings.ToLower(k.ValueString)
+				if _, ok := folded[lower]; !ok {
+					folded[lower] = elt.Value
+				}
+			}
+		}
+	}
+
+	structVal := reflect.New(t).Elem()
+	for _, field := range info.Fields {
+		v, ok := values[field.Key]
+		if !ok && folded != nil {
+			v, ok = folded[strings.ToLower(field.Key)]
+		}
+		if ok && v.Type != proto.Value_UNDEFINED && d.presence != nil {
+			d.presence[string(appendKeyPath(d.path, field.KeyValue))] = true
+		}
+		if !ok || v.Type == proto.Value_UNDEFINED {
+			if !field.HasDefault {
+				continue
+			}
+
+			dv, err := defaultValue(field.Default, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("default for field %s: %s", field.Name, err)
+			}
+
+			v = dv
+		}
+
+		if field.Unwrap != "" && v.Type == proto.Value_MAP {
+			inner, err := getElem(v, field.Unwrap)
+			if err != nil {
+				return nil, d.nestErr(err, "field %s", field.Name)
+			}
+
+			v = inner
+		}
+
+		if v.Type == proto.Value_NULL {
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				continue
+
+			case reflect.Interface:
+				// Interfaces that sdk.Null can't be stored in, such as
+				// those with a default implementation, are left nil.
+				if !reflect.TypeOf(sdk.Null).Implements(field.Type) {
+					continue
+				}
+			}
+		}
+
+		n := d.pushKey(field.KeyValue)
+		fieldStart := field.stats.start()
+		var elem interface{}
+		if field.HasJoin && v.Type == proto.Value_LIST {
+			elem, err = d.joinList(v, field.Join, field.Type)
+		} else if field.Encoding != "" && v.Type == proto.Value_STRING {
+			elem, err = decodeByteArray(v, field.Encoding, field.Type)
+		} else {
+			elem, err = d.valueToGo(v, field.Type)
+		}
+		field.stats.record(fieldStart)
+		d.popPath(n)
+		if err != nil {
+			return nil, d.nestErr(err, "field %s", field.Name)
+		}
+
+		structVal.Field(field.Index).Set(reflect.ValueOf(elem))
+	}
+
+	if field := info.Remaining; field != nil {
+		if unmatched := d.unmatchedElems(elems, info); len(unmatched) > 0 {
+			v := &proto.Value{
+				Type: proto.Value_MAP,
+				Value: &proto.Value_ValueMap{
+					ValueMap: &proto.Value_Map{Elems: unmatched},
+				},
+			}
+
+			elem, err := d.valueToGo(v, field.Type)
+			if err != nil {
+				return nil, d.nestErr(err, "field %s", field.Name)
+			}
+
+			structVal.Field(field.Index).Set(reflect.ValueOf(elem))
+		}
+	}
+
+	if d.disallowUnknownKeys {
+		if unknown := d.unknownKeys(elems, info); len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown keys: %v", unknown)
+		}
+	}
+
+	info.stats.record(start)
+	return structVal.Interface(), nil
+}
+
+// unknownKeys returns the keys of the map entries that don't match a field
+// of the struct, in the order of the entries. See WithDisallowUnknownKeys.
+func (d *decoder) unknownKeys(elems []*proto.Value_KV, info *structInfo) []string {
+	var result []string
+	for _, elt := range d.unmatchedElems(elems, info) {
+		if k, ok := elt.Key.Value.(*proto.Value_ValueString); ok {
+			result = append(result, k.ValueString)
+		} else {
+			result = append(result, Sprint(elt.Key))
+		}
+	}
+
+	return result
+}
+
+// unmatchedElems returns the map entries whose keys don't match a field of
+// the struct, in order.
+func (d *decoder) unmatchedElems(elems []*proto.Value_KV, info *structInfo) []*proto.Value_KV {
+	fields := make(map[string]struct{}, len(info.Fields))
+	for _, field := range info.Fields {
+		key := field.Key
+		if d.caseInsensitiveKeys {
+			key = strings.ToLower(key)
+		}
+
+		fields[key] = struct{}{}
+	}
+
+	var result []*proto.Value_KV
+	for _, elt := range elems {
+		k, ok := elt.Key.Value.(*proto.Value_ValueString)
+		if !ok {
+			// Only STRING keys can match a field
+			result = append(result, elt)
+			continue
+		}
+
+		key := k.ValueString
+		if d.caseInsensitiveKeys {
+			key = strings.ToLower(key)
+		}
+		if _, ok := fields[key]; !ok {
+			result = append(result, elt)
+		}
+	}
+
+	return result
+}
+
+// remainingKeys returns the keys of the map in the "remaining" field of the
+// struct v that don't match another field, sorted for a stable encoding.
+func (info *structInfo) remainingKeys(v reflect.Value) []string {
+	m := v.Field(info.Remaining.Index)
+	if m.Len() == 0 {
+		return nil
+	}
+
+	fields := make(map[string]struct{}, len(info.Fields))
+	for _, field := range info.Fields {
+		fields[field.Key] = struct{}{}
+	}
+
+	keys := make([]string, 0, m.Len())
+	for _, key := range m.MapKeys() {
+		if _, ok := fields[key.String()]; !ok {
+			keys = append(keys, key.String())
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// expandDottedKeys returns the map entries with STRING keys of the form
+// "prefix.rest" grouped into a MAP value for the key "prefix". Keys that
+// match a field of the struct exactly aren't expanded. See
+// WithExpandDottedKeys.
+func expandDottedKeys(elems []*proto.Value_KV, info *structInfo) ([]*proto.Value_KV, error) {
+	fields := make(map[string]struct{}, len(info.Fields))
+	for _, field := range info.Fields {
+		fields[field.Key] = struct{}{}
+	}
+
+	// Group the dotted keys by prefix, keeping the order of the entries
+	var prefixes []string
+	groups := make(map[string][]*proto.Value_KV)
+	result := make([]*proto.Value_KV, 0, len(elems))
+	for _, elt := range elems {
+		k, ok := elt.Key.Value.(*proto.Value_ValueString)
+		if !ok {
+			result = append(result, elt)
+			continue
+		}
+
+		idx := strings.IndexByte(k.ValueString, '.')
+		if _, ok := fields[k.ValueString]; ok || idx < 0 {
+			result = append(result, elt)
+			continue
+		}
+
+		prefix := k.ValueString[:idx]
+		if _, ok := groups[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+		}
+		groups[prefix] = append(groups[prefix], &proto.Value_KV{
+			Key:   toValue_string(k.ValueString[idx+1:]),
+			Value: elt.Value,
+		})
+	}
+
+	for _, prefix := range prefixes {
+		group := groups[prefix]
+
+		// Merge into an existing value for the prefix
+		existing := -1
+		for i, elt := range result {
+			if k, ok := elt.Key.Value.(*proto.Value_ValueString); ok && k.ValueString == prefix {
+				existing = i
+				break
+			}
+		}
+		if existing >= 0 {
+			v := result[existing].Value
+			if v.Type != proto.Value_MAP {
+				return nil, fmt.Errorf(
+					"key %q: cannot expand dotted keys into %s value", prefix, v.Type)
+			}
+			if err := checkPayload(v); err != nil {
+				return nil, fmt.Errorf("key %q: %s", prefix, err)
+			}
+
+			old := v.Value.(*proto.Value_ValueMap).ValueMap.Elems
+			group = append(append([]*proto.Value_KV(nil), old...), group...)
+		}
+
+		kv := &proto.Value_KV{
+			Key: toValue_string(prefix),
+			Value: &proto.Value{
+				Type: proto.Value_MAP,
+				Value: &proto.Value_ValueMap{
+					ValueMap: &proto.Value_Map{Elems: group},
+				},
+			},
+		}
+		if existing >= 0 {
+			result[existing] = kv
+		} else {
+			result = append(result, kv)
+		}
+	}
+
+	return result, nil
+}
+
+// decodeByteArray decodes the STRING raw with the encoding enc, "hex" or
+// "base64", into a value of the byte array type t. The decoded length must
+// match the length of the array.
+func decodeByteArray(raw *proto.Value, enc string, t reflect.Type) (interface{}, error) {
+	s := raw.Value.(*proto.Value_ValueString).ValueString
+
+	var b []byte
+	var err error
+	switch enc {
+	case "hex":
+		b, err = hex.DecodeString(s)
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", enc, err)
+	}
+
+	if len(b) != t.Len() {
+		return nil, fmt.Errorf("expected %d bytes, got %d", t.Len(), len(b))
+	}
+
+	array := reflect.New(t).Elem()
+	reflect.Copy(array, reflect.ValueOf(b))
+	return array.Interface(), nil
+}
+
+// encodeByteArray encodes the byte array v with the encoding enc, "hex" or
+// "base64".
+func encodeByteArray(v reflect.Value, enc string) string {
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+
+	if enc == "hex" {
+		return hex.EncodeToString(b)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// joinList decodes the elements of a LIST as strings and joins them with
+// sep into a value of the string type t.
+func (d *decoder) joinList(raw *proto.Value, sep string, t reflect.Type) (interface{}, error) {
+	if err := d.checkLen(raw); err != nil {
+		return nil, err
+	}
+
+	elems := raw.Value.(*proto.Value_ValueList).ValueList.Elems
+	parts := make([]string, len(elems))
+	for i, elt := range elems {
+		n := d.pushIndex(i)
+		v, err := d.valueToGo(elt, stringTyp)
+		d.popPath(n)
+		if err != nil {
+			return nil, d.nestErr(err, "element %d", i)
+		}
+
+		parts[i] = v.(string)
+	}
+
+	return reflect.ValueOf(strings.Join(parts, sep)).Convert(t).Interface(), nil
+}
+
+// structFieldKey returns the map key for a struct field and any options
+// following a comma in its "sentinel" tag, or false if the field isn't
+// converted. If the tag has options but no key, the field name is used.
+func structFieldKey(field reflect.StructField) (string, string, bool) {
+	// If PkgPath is non-empty, this is unexported and can be ignored
+	if field.PkgPath != "" {
+		return "", "", false
+	}
+
+	if v, ok := field.Tag.Lookup("sentinel"); ok {
+		// A blank value means to not export this value
+		if v == "" {
+			return "", "", false
+		}
+
+		key, opts := v, ""
+		if idx := strings.IndexByte(v, ','); idx >= 0 {
+			key, opts = v[:idx], v[idx+1:]
+		}
+		if key == "" {
+			key = field.Name
+		}
+
+		return key, opts, true
+	}
+
+	return field.Name, "", true
+}
+
+// defaultValue returns the value to decode for a "default" struct tag.
+func defaultValue(def string, t reflect.Type) (*proto.Value, error) {
+	if t.Kind() == reflect.Bool {
+		b, err := strconv.ParseBool(def)
+		if err != nil {
+			return nil, err
+		}
+
+		return &proto.Value{
+			Type:  proto.Value_BOOL,
+			Value: &proto.Value_ValueBool{ValueBool: b},
+		}, nil
+	}
+
+	return &proto.Value{
+		Type:  proto.Value_STRING,
+		Value: &proto.Value_ValueString{ValueString: def},
+	}, nil
+}
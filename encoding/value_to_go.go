@@ -1,9 +1,15 @@
 package encoding
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/sentinel-sdk"
 	"github.com/hashicorp/sentinel-sdk/proto/go"
@@ -14,15 +20,376 @@ var (
 	boolTyp      = reflect.TypeOf(true)
 	intTyp       = reflect.TypeOf(int64(0))
 	floatTyp     = reflect.TypeOf(float64(0))
+	timeTyp      = reflect.TypeOf(time.Time{})
 	stringTyp    = reflect.TypeOf("")
 )
 
 // ValueToGo converts a protobuf Value structure to a native Go value.
-func ValueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
-	return valueToGo(v, t)
+//
+// Types with a Converter registered with RegisterConverter are converted
+// with that converter. The options can be used to change the behavior of
+// the conversion. By default, the conversion behaves as documented for
+// each DecodeOption when that option is not given.
+func ValueToGo(v *proto.Value, t reflect.Type, opts ...DecodeOption) (interface{}, error) {
+	d := getDecoder(opts)
+	defer putDecoder(d)
+	return d.valueToGo(v, t)
 }
 
-func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
+// ValueToGoPresence is like ValueToGo but also returns the set of struct
+// fields that had a key in the MAP they were decoded from, which tells a
+// field that was set to its zero value apart from one that was absent,
+// such as for layering configuration. Fields are reported by their path
+// from v, in the same format as the path given to a FieldHook, so a field
+// "port" of a struct in the field "db" is reported as "db.port". Fields
+// of every struct decoded are reported, at any depth. A field whose value
+// is UNDEFINED isn't present, but one whose value is NULL is.
+func ValueToGoPresence(
+	v *proto.Value, t reflect.Type, opts ...DecodeOption) (interface{}, map[string]bool, error) {
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	d.presence = make(map[string]bool)
+	result, err := d.valueToGo(v, t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, d.presence, nil
+}
+
+// ValueToSlice converts a protobuf LIST Value into the slice pointed to by
+// dst. dst must be a non-nil pointer to a slice.
+//
+// If the existing slice has enough capacity for all the elements, its
+// backing array is reused and the elements are decoded in place. Otherwise
+// a new slice is allocated. This avoids an allocation per call when
+// decoding repeatedly into the same slice. If an error is returned, the
+// contents of the slice are unspecified.
+func ValueToSlice(v *proto.Value, dst interface{}, opts ...DecodeOption) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf(
+			"destination must be a non-nil pointer to a slice, got %T", dst)
+	}
+
+	if err := checkPayload(v); err != nil {
+		return err
+	}
+
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	if err := d.checkLen(v); err != nil {
+		return err
+	}
+
+	elems, ok := d.listElems(v)
+	if !ok {
+		return convertErr(v, "list")
+	}
+
+	sliceVal := ptr.Elem()
+	elems = d.skipNulls(elems, sliceVal.Type().Elem())
+	if sliceVal.Cap() < len(elems) {
+		sliceVal.Set(reflect.MakeSlice(sliceVal.Type(), len(elems), len(elems)))
+	} else {
+		sliceVal.SetLen(len(elems))
+	}
+
+	return d.decodeElems(elems, sliceVal)
+}
+
+// ValueToReflect converts a protobuf Value structure into dst, which must be
+// settable, such as an element of a slice or a field of a struct obtained
+// through a pointer. The value is converted to the type of dst with the
+// same rules as ValueToGo.
+//
+// If dst is a slice, the elements are decoded directly into it, reusing
+// its capacity as ValueToSlice does. NULL and UNDEFINED values set pointer,
+// slice and map destinations to nil.
+func ValueToReflect(v *proto.Value, dst reflect.Value, opts ...DecodeOption) error {
+	if !dst.IsValid() || !dst.CanSet() {
+		return fmt.Errorf("destination must be settable, got %s", dst)
+	}
+
+	if err := checkPayload(v); err != nil {
+		return err
+	}
+
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	if err := d.checkLen(v); err != nil {
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if v.Type == proto.Value_NULL || v.Type == proto.Value_UNDEFINED {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+	}
+
+	// Decode slices in place unless a converter handles the type
+	if dst.Kind() == reflect.Slice && lookupConverter(dst.Type()) == nil {
+		elems, ok := d.listElems(v)
+		if !ok {
+			return convertErr(v, "list")
+		}
+		elems = d.skipNulls(elems, dst.Type().Elem())
+
+		if dst.Cap() < len(elems) {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(elems), len(elems)))
+		} else {
+			dst.SetLen(len(elems))
+		}
+
+		return d.decodeElems(elems, dst)
+	}
+
+	result, err := d.valueToGo(v, dst.Type())
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(result)
+	if !rv.IsValid() || !rv.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("cannot assign %T to %s", result, dst.Type())
+	}
+
+	dst.Set(rv)
+	return nil
+}
+
+// ValueToSyncMap decodes the MAP v into dst, storing each key decoded into
+// keyTyp with its value decoded into elemTyp. A nil type decodes the same
+// way as a nil type given to ValueToGo. Existing entries in dst are kept
+// unless they have the same key as an entry in v.
+//
+// Every entry is decoded before any is stored, so dst is unchanged if an
+// error is returned. The entries are stored one at a time, so concurrent
+// readers of dst may see some of the new entries before others.
+func ValueToSyncMap(
+	v *proto.Value, keyTyp, elemTyp reflect.Type, dst *sync.Map, opts ...DecodeOption) error {
+	if err := checkPayload(v); err != nil {
+		return err
+	}
+	if v.Type != proto.Value_MAP {
+		return convertErr(v, "map")
+	}
+
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	if err := d.checkLen(v); err != nil {
+		return err
+	}
+
+	elems := v.Value.(*proto.Value_ValueMap).ValueMap.Elems
+	keys := make([]interface{}, 0, len(elems))
+	values := make([]interface{}, 0, len(elems))
+	var seen map[interface{}]struct{}
+	if d.duplicateKeys != DuplicateKeysLastWins {
+		seen = make(map[interface{}]struct{}, len(elems))
+	}
+	for _, elt := range elems {
+		key, elem, err := d.decodeMapEntry(elt, keyTyp, elemTyp)
+		if err != nil {
+			return err
+		}
+
+		if seen != nil {
+			if _, ok := seen[key]; ok {
+				if d.duplicateKeys == DuplicateKeysError {
+					return duplicateKeyErr(elt.Key)
+				}
+
+				continue
+			}
+
+			seen[key] = struct{}{}
+		}
+
+		keys = append(keys, key)
+		values = append(values, elem)
+	}
+
+	for i, key := range keys {
+		dst.Store(key, values[i])
+	}
+
+	return nil
+}
+
+// PairsToMap converts a LIST of [key, value] LISTs, a common way for
+// backends to represent a map as a list of entries, to a Go map with the
+// given key and element types. The types are used as by ValueToSyncMap,
+// with a nil type decoding as interface{}. Options apply as for
+// ValueToGo, including WithDuplicateKeys for pairs with the same key.
+//
+// An error is returned with the index of the first element that isn't a
+// LIST of exactly two elements.
+func PairsToMap(
+	v *proto.Value, keyTyp, elemTyp reflect.Type, opts ...DecodeOption) (interface{}, error) {
+	if err := checkPayload(v); err != nil {
+		return nil, err
+	}
+	if v.Type != proto.Value_LIST {
+		return nil, convertErr(v, "list")
+	}
+
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	if err := d.checkLen(v); err != nil {
+		return nil, err
+	}
+
+	if keyTyp == nil {
+		keyTyp = interfaceTyp
+	}
+	if elemTyp == nil {
+		elemTyp = interfaceTyp
+	}
+
+	elems := v.Value.(*proto.Value_ValueList).ValueList.Elems
+	mapVal := reflect.MakeMapWithSize(reflect.MapOf(keyTyp, elemTyp), len(elems))
+	for i, elt := range elems {
+		if err := checkPayload(elt); err != nil {
+			return nil, fmt.Errorf("element %d: %s", i, err)
+		}
+		if elt.Type != proto.Value_LIST {
+			return nil, fmt.Errorf("element %d: expected a LIST of 2 elements, got %s", i, elt.Type)
+		}
+		pair := elt.Value.(*proto.Value_ValueList).ValueList.Elems
+		if len(pair) != 2 {
+			return nil, fmt.Errorf(
+				"element %d: expected a LIST of 2 elements, got %d elements", i, len(pair))
+		}
+
+		// Paths are as if the pairs were a MAP
+		key, elem, err := d.decodeMapEntry(&proto.Value_KV{Key: pair[0], Value: pair[1]}, keyTyp, elemTyp)
+		if err != nil {
+			return nil, d.nestErr(err, "element %d", i)
+		}
+
+		keyVal := reflect.ValueOf(key)
+		if d.duplicateKeys != DuplicateKeysLastWins && mapVal.MapIndex(keyVal).IsValid() {
+			if d.duplicateKeys == DuplicateKeysError {
+				return nil, duplicateKeyErr(pair[0])
+			}
+
+			continue
+		}
+
+		mapVal.SetMapIndex(keyVal, reflect.ValueOf(elem))
+	}
+
+	return mapVal.Interface(), nil
+}
+
+// DecodeMapByKey converts a MAP value to a map[string]interface{}, decoding
+// the value for each key in types into the type for that key. Values for
+// other keys are converted as if by ValueToGo with a nil type. This is
+// useful for maps that hold a different type for each key when decoding
+// into a struct isn't an option.
+func DecodeMapByKey(
+	v *proto.Value, types map[string]reflect.Type, opts ...DecodeOption) (map[string]interface{}, error) {
+	if err := checkPayload(v); err != nil {
+		return nil, err
+	}
+	if v.Type != proto.Value_MAP {
+		return nil, convertErr(v, "map")
+	}
+
+	d := getDecoder(opts)
+	defer putDecoder(d)
+
+	m := v.Value.(*proto.Value_ValueMap).ValueMap
+	result := make(map[string]interface{}, len(m.Elems))
+	for _, elt := range m.Elems {
+		key, err := d.convertValueString(elt.Key)
+		if err != nil {
+			return nil, d.nestErr(err, "key %s", elt.Key.String())
+		}
+
+		n := d.pushKey(elt.Key)
+		value, err := d.valueToGo(elt.Value, types[key.(string)])
+		d.popPath(n)
+		if err != nil {
+			return nil, d.nestErr(err, "element for key %s", elt.Key.String())
+		}
+
+		if _, ok := result[key.(string)]; ok && d.duplicateKeys != DuplicateKeysLastWins {
+			if d.duplicateKeys == DuplicateKeysError {
+				return nil, duplicateKeyErr(elt.Key)
+			}
+
+			continue
+		}
+
+		result[key.(string)] = value
+	}
+
+	return result, nil
+}
+
+func (d *decoder) valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
+	if err := d.checkDeadline(); err != nil {
+		return nil, err
+	}
+
+	result, err := d.decodeValue(v, t)
+	if err == nil {
+		if f := lookupValidator(reflect.TypeOf(result)); f != nil {
+			err = f(result)
+		}
+	}
+	if err != nil {
+		if _, ok := err.(*pathError); !ok && err != errTimeout && d.errVerbosity == ErrorsVerbose {
+			err = &pathError{Path: string(d.path), Type: t, Err: err}
+		}
+
+		return nil, err
+	}
+
+	if d.fieldHook != nil && v.Type != proto.Value_LIST && v.Type != proto.Value_MAP {
+		d.fieldHook(string(d.path), v, result)
+	}
+
+	return result, nil
+}
+
+// decodeValue does the conversion for valueToGo.
+func (d *decoder) decodeValue(v *proto.Value, t reflect.Type) (interface{}, error) {
+	// Verify the value has a payload if its type requires one. A malformed
+	// or partially populated Value would otherwise panic on the type
+	// assertions in the conversion functions.
+	if err := checkPayload(v); err != nil {
+		return nil, err
+	}
+	if err := d.checkLen(v); err != nil {
+		return nil, err
+	}
+
+	// MAPs with a registered discriminator are decoded as the registered
+	// type when there is no specific target type.
+	if (t == nil || t.Kind() == reflect.Interface) && v.Type == proto.Value_MAP {
+		if dt := lookupDiscriminatedType(v); dt != nil && (t == nil || dt.Implements(t)) {
+			return d.decodeValue(v, dt)
+		}
+	}
+
+	// Interfaces with a registered implementation are decoded as that
+	// implementation.
+	if t != nil && t.Kind() == reflect.Interface {
+		if impl := lookupDefaultImpl(t); impl != nil {
+			return d.decodeValue(v, impl)
+		}
+	}
+
 	// t == nil if you call reflect.TypeOf(interface{}{}) or
 	// if the user explicitly send in nil which we make to mean
 	// the same thing.
@@ -77,7 +444,7 @@ func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
 			t = stringTyp
 
 		case reflect.Map:
-			t = valueMapType(v)
+			t = d.valueMapType(v)
 
 		case reflect.Slice:
 			t = valueSliceType(v)
@@ -87,13 +454,71 @@ func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
 		}
 	}
 
+	// Unwrap single-element LISTs for scalar targets before the converter
+	// lookup so that converters for scalar types see the element. Only a
+	// single level is unwrapped.
+	if d.listToScalar && v.Type == proto.Value_LIST {
+		switch kind {
+		case reflect.Bool, reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			elems := v.Value.(*proto.Value_ValueList).ValueList.Elems
+			if len(elems) != 1 {
+				return nil, fmt.Errorf(
+					"cannot convert list of length %d to %s", len(elems), t)
+			}
+			if elems[0].Type == proto.Value_LIST {
+				return nil, fmt.Errorf("cannot convert nested list to %s", t)
+			}
+
+			n := d.pushIndex(0)
+			result, err := d.valueToGo(elems[0], t)
+			d.popPath(n)
+			if err != nil {
+				return nil, d.nestErr(err, "element %d", 0)
+			}
+
+			return result, nil
+		}
+	}
+
+	// A registered converter takes precedence over the conversion for
+	// the kind of the type.
+	if c := lookupConverter(t); c != nil && c.Decode != nil {
+		return c.Decode(v)
+	}
+
+	if t == timeTyp && v.Type == proto.Value_INT {
+		return d.unixTime(v.Value.(*proto.Value_ValueInt).ValueInt), nil
+	}
+	if t == timeTyp && v.Type == proto.Value_MAP && d.timeFromMap != nil {
+		return d.mapTime(v)
+	}
+
+	if v.Type == proto.Value_STRING {
+		if result, ok, err := unmarshalText(v, t); ok {
+			return result, err
+		}
+	}
+
+	if d.emptyStringAsZero && v.Type == proto.Value_STRING && d.trim(v.GetValueString()) == "" {
+		switch kind {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return reflect.Zero(t).Interface(), nil
+		}
+	}
+
 	switch kind {
 	case reflect.Bool:
 		return convertValueBool(v)
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v, err := convertValueInt64(v)
-		if err != nil {
+		v, err := d.convertValueInt64(v)
+		if err != nil || t == intTyp {
 			return v, err
 		}
 
@@ -103,7 +528,7 @@ func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
 		return reflect.ValueOf(v).Convert(t).Interface(), nil
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		v, err := convertValueUint64(v)
+		v, err := d.convertValueUint64(v)
 		if err != nil {
 			return v, err
 		}
@@ -111,7 +536,7 @@ func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
 		return reflect.ValueOf(v).Convert(t).Interface(), nil
 
 	case reflect.Float32:
-		v, err := convertValueFloat(v, 32)
+		v, err := d.convertValueFloat(v, 32)
 		if err != nil {
 			return v, err
 		}
@@ -119,27 +544,67 @@ func valueToGo(v *proto.Value, t reflect.Type) (interface{}, error) {
 		return float32(v.(float64)), nil
 
 	case reflect.Float64:
-		return convertValueFloat(v, 64)
+		return d.convertValueFloat(v, 64)
 
 	case reflect.String:
-		return convertValueString(v)
+		return d.convertValueString(v)
+
+	case reflect.Complex64:
+		v, err := d.convertValueComplex(v, t)
+		if err != nil {
+			return v, err
+		}
+
+		return complex64(v.(complex128)), nil
+
+	case reflect.Complex128:
+		return d.convertValueComplex(v, t)
 
 	case reflect.Slice:
-		return convertValueSlice(v, t)
+		return d.convertValueSlice(v, t)
+
+	case reflect.Array:
+		return d.convertValueArray(v, t)
 
 	case reflect.Map:
-		return convertValueMap(v, t)
+		return d.convertValueMap(v, t)
+
+	case reflect.Struct:
+		return d.convertValueStruct(v, t)
 
 	case reflect.Ptr:
+		// NULL and UNDEFINED decode to a nil pointer, unless the target is
+		// the type of the sentinel values themselves.
 		switch v.Type {
 		case proto.Value_NULL:
-			return sdk.Null, nil
+			if reflect.TypeOf(sdk.Null).AssignableTo(t) {
+				return sdk.Null, nil
+			}
+
+			return reflect.Zero(t).Interface(), nil
 
 		case proto.Value_UNDEFINED:
-			return sdk.Undefined, nil
+			if reflect.TypeOf(sdk.Undefined).AssignableTo(t) {
+				return sdk.Undefined, nil
+			}
+
+			return reflect.Zero(t).Interface(), nil
+		}
+
+		// Decode the value into a newly allocated element
+		elem, err := d.decodeValue(v, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if f := lookupValidator(t.Elem()); f != nil {
+			if err := f(elem); err != nil {
+				return nil, err
+			}
 		}
 
-		fallthrough
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(reflect.ValueOf(elem))
+		return ptr.Interface(), nil
 
 	default:
 		return nil, convertErr(v, t.Kind().String())
@@ -154,20 +619,137 @@ func convertValueBool(raw *proto.Value) (interface{}, error) {
 	return nil, convertErr(raw, "bool")
 }
 
-func convertValueInt64(raw *proto.Value) (interface{}, error) {
+func (d *decoder) convertValueInt64(raw *proto.Value) (interface{}, error) {
 	switch raw.Type {
 	case proto.Value_INT:
 		return raw.Value.(*proto.Value_ValueInt).ValueInt, nil
 
+	case proto.Value_FLOAT:
+		if !d.lenientNumbers {
+			return nil, convertErr(raw, "int")
+		}
+
+		value, err := floatToInt64(raw.Value.(*proto.Value_ValueFloat).ValueFloat)
+		if err != nil {
+			return nil, err
+		}
+
+		return value, nil
+
 	case proto.Value_STRING:
-		return strconv.ParseInt(raw.Value.(*proto.Value_ValueString).ValueString, 0, 64)
+		return strconv.ParseInt(d.normalizeNumber(raw.Value.(*proto.Value_ValueString).ValueString), 0, 64)
 
 	default:
 		return nil, convertErr(raw, "int")
 	}
 }
 
-func convertValueUint64(raw *proto.Value) (interface{}, error) {
+// timeComponents are the keys of the MAP values decoded by mapTime with
+// their ranges. The maximum day is further limited by the month.
+var timeComponents = []struct {
+	key      string
+	min, max int64
+}{
+	{"year", math.MinInt32, math.MaxInt32},
+	{"month", 1, 12},
+	{"day", 1, 31},
+	{"hour", 0, 23},
+	{"minute", 0, 59},
+	{"second", 0, 59},
+	{"nanosecond", 0, 999999999},
+}
+
+// mapTime assembles a time.Time from a MAP of date and time components
+// for WithTimeFromMap.
+func (d *decoder) mapTime(v *proto.Value) (time.Time, error) {
+	// The defaults are used for missing components other than the year.
+	// A year of 0 is valid, so the year is tracked separately.
+	values := []int64{0, 1, 1, 0, 0, 0, 0}
+	hasYear := false
+
+	for _, elt := range v.Value.(*proto.Value_ValueMap).ValueMap.Elems {
+		key, err := d.decodeValue(elt.Key, stringTyp)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time component key: %s", err)
+		}
+
+		i := 0
+		for i < len(timeComponents) && timeComponents[i].key != key.(string) {
+			i++
+		}
+		if i == len(timeComponents) {
+			return time.Time{}, fmt.Errorf("unknown time component %q", key)
+		}
+
+		c := timeComponents[i]
+		n, err := d.decodeValue(elt.Value, intTyp)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time component %s: %s", c.key, err)
+		}
+		if n.(int64) < c.min || n.(int64) > c.max {
+			return time.Time{}, fmt.Errorf(
+				"time component %s: %d out of range [%d, %d]", c.key, n, c.min, c.max)
+		}
+
+		values[i] = n.(int64)
+		if i == 0 {
+			hasYear = true
+		}
+	}
+
+	if !hasYear {
+		return time.Time{}, errors.New("time component year is required")
+	}
+
+	year, month, day := int(values[0]), time.Month(values[1]), int(values[2])
+	result := time.Date(year, month, day,
+		int(values[3]), int(values[4]), int(values[5]), int(values[6]), d.timeFromMap)
+
+	// time.Date normalizes days past the end of the month into the next
+	// month, so that is the only invalid date left to check for.
+	if result.Day() != day {
+		return time.Time{}, fmt.Errorf(
+			"time component day: %d out of range for %s %d", day, month, year)
+	}
+
+	return result, nil
+}
+
+// unixTime converts n units since the Unix epoch to a time.Time in UTC,
+// with the unit set by WithTimeUnit.
+func (d *decoder) unixTime(n int64) time.Time {
+	unit := d.timeUnit
+	if unit == 0 {
+		unit = time.Second
+	}
+
+	switch {
+	case unit%time.Second == 0:
+		return time.Unix(n*int64(unit/time.Second), 0).UTC()
+
+	case time.Second%unit == 0:
+		// Split into seconds first so that large values don't overflow
+		perSec := int64(time.Second / unit)
+		return time.Unix(n/perSec, n%perSec*int64(unit)).UTC()
+
+	default:
+		return time.Unix(0, n*int64(unit)).UTC()
+	}
+}
+
+// floatToInt64 converts a whole number float to an int64 for
+// WithLenientNumbers.
+func floatToInt64(value float64) (int64, error) {
+	// 2^63 is exactly representable, unlike the maximum int64. Any float
+	// below it that is a whole number fits in an int64.
+	if value != math.Trunc(value) || value < -(1<<63) || value >= 1<<63 {
+		return 0, fmt.Errorf("float %v cannot be converted exactly to an int", value)
+	}
+
+	return int64(value), nil
+}
+
+func (d *decoder) convertValueUint64(raw *proto.Value) (interface{}, error) {
 	switch raw.Type {
 	case proto.Value_INT:
 		value := raw.Value.(*proto.Value_ValueInt).ValueInt
@@ -178,64 +760,416 @@ func convertValueUint64(raw *proto.Value) (interface{}, error) {
 
 		return uint64(value), nil
 
+	case proto.Value_FLOAT:
+		if !d.lenientNumbers {
+			return nil, convertErr(raw, "uint")
+		}
+
+		value := raw.Value.(*proto.Value_ValueFloat).ValueFloat
+		if value != math.Trunc(value) || value < 0 || value >= 1<<64 {
+			return nil, fmt.Errorf("float %v cannot be converted exactly to a uint", value)
+		}
+
+		return uint64(value), nil
+
 	case proto.Value_STRING:
-		return strconv.ParseUint(raw.Value.(*proto.Value_ValueString).ValueString, 0, 64)
+		return strconv.ParseUint(d.normalizeNumber(raw.Value.(*proto.Value_ValueString).ValueString), 0, 64)
 
 	default:
 		return nil, convertErr(raw, "uint")
 	}
 }
 
-func convertValueFloat(raw *proto.Value, bitSize int) (interface{}, error) {
+func (d *decoder) convertValueFloat(raw *proto.Value, bitSize int) (interface{}, error) {
 	switch raw.Type {
 	case proto.Value_INT:
-		return float64(raw.Value.(*proto.Value_ValueInt).ValueInt), nil
+		value := raw.Value.(*proto.Value_ValueInt).ValueInt
+		if d.exactFloat {
+			if err := checkFloatPrecision(value, bitSize); err != nil {
+				return nil, err
+			}
+		}
+
+		return float64(value), nil
 
 	case proto.Value_FLOAT:
 		return raw.Value.(*proto.Value_ValueFloat).ValueFloat, nil
 
 	case proto.Value_STRING:
-		return strconv.ParseFloat(raw.Value.(*proto.Value_ValueString).ValueString, bitSize)
+		s := d.normalizeNumber(raw.Value.(*proto.Value_ValueString).ValueString)
+		if d.exactFloat {
+			// Integer strings are checked just like INT values. Anything
+			// else is parsed as a float as usual.
+			if value, err := strconv.ParseInt(s, 0, 64); err == nil {
+				if err := checkFloatPrecision(value, bitSize); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return strconv.ParseFloat(s, bitSize)
 
 	default:
 		return nil, convertErr(raw, "float")
 	}
 }
 
-func convertValueString(raw *proto.Value) (interface{}, error) {
+// checkFloatPrecision returns an error if the integer can't be represented
+// exactly by a float of the given bit size.
+func checkFloatPrecision(value int64, bitSize int) error {
+	// The number of bits in the mantissa, including the implicit bit.
+	mantissa := uint(53)
+	if bitSize == 32 {
+		mantissa = 24
+	}
+
+	limit := int64(1) << mantissa
+	if value > limit || value < -limit {
+		return fmt.Errorf(
+			"integer %d cannot be represented exactly as a float%d", value, bitSize)
+	}
+
+	return nil
+}
+
+// convertValueComplex converts a value of the shape set with
+// WithComplexShape to a complex128.
+func (d *decoder) convertValueComplex(raw *proto.Value, t reflect.Type) (interface{}, error) {
+	var re, im *proto.Value
+	switch {
+	case d.complexShape == ComplexList && raw.Type == proto.Value_LIST:
+		elems := raw.Value.(*proto.Value_ValueList).ValueList.Elems
+		if len(elems) != 2 {
+			return nil, fmt.Errorf(
+				"complex number must be a list of 2 elements, got %d", len(elems))
+		}
+
+		re, im = elems[0], elems[1]
+
+	case d.complexShape == ComplexMap && raw.Type == proto.Value_MAP:
+		for _, elt := range raw.Value.(*proto.Value_ValueMap).ValueMap.Elems {
+			switch elt.Key.GetValueString() {
+			case "re":
+				re = elt.Value
+			case "im":
+				im = elt.Value
+			default:
+				return nil, fmt.Errorf(
+					"complex number map has unexpected key %s", elt.Key.String())
+			}
+		}
+		if re == nil || im == nil {
+			return nil, errors.New(`complex number map must have keys "re" and "im"`)
+		}
+
+	default:
+		return nil, convertErr(raw, t.Kind().String())
+	}
+
+	r, err := complexPart(re)
+	if err != nil {
+		return nil, fmt.Errorf("real part: %s", err)
+	}
+	i, err := complexPart(im)
+	if err != nil {
+		return nil, fmt.Errorf("imaginary part: %s", err)
+	}
+
+	return complex(r, i), nil
+}
+
+// complexPart converts an INT or FLOAT part of a complex number to a float.
+func complexPart(raw *proto.Value) (float64, error) {
+	if err := checkPayload(raw); err != nil {
+		return 0, err
+	}
+
+	switch raw.Type {
+	case proto.Value_INT:
+		return float64(raw.Value.(*proto.Value_ValueInt).ValueInt), nil
+
+	case proto.Value_FLOAT:
+		return raw.Value.(*proto.Value_ValueFloat).ValueFloat, nil
+
+	default:
+		return 0, convertErr(raw, "float")
+	}
+}
+
+func (d *decoder) convertValueString(raw *proto.Value) (interface{}, error) {
 	switch raw.Type {
 	case proto.Value_INT:
 		return strconv.FormatInt(raw.Value.(*proto.Value_ValueInt).ValueInt, 10), nil
 
 	case proto.Value_STRING:
-		return raw.Value.(*proto.Value_ValueString).ValueString, nil
+		s := raw.Value.(*proto.Value_ValueString).ValueString
+		if d.strictUTF8 && !utf8.ValidString(s) {
+			return nil, errors.New("invalid UTF-8 in string value")
+		}
+
+		return d.intern(s), nil
 
 	default:
 		return nil, convertErr(raw, "string")
 	}
 }
 
-func convertValueSlice(raw *proto.Value, t reflect.Type) (interface{}, error) {
-	if raw.Type != proto.Value_LIST {
+func (d *decoder) convertValueSlice(raw *proto.Value, t reflect.Type) (interface{}, error) {
+	elems, ok := d.listElems(raw)
+	if !ok {
 		return nil, convertErr(raw, "list")
 	}
+	elems = d.skipNulls(elems, t.Elem())
 
-	list := raw.Value.(*proto.Value_ValueList).ValueList
-	elemTyp := t.Elem()
-	sliceVal := reflect.MakeSlice(t, len(list.Elems), len(list.Elems))
-	for i, elt := range list.Elems {
-		v, err := valueToGo(elt, elemTyp)
+	sliceVal := reflect.MakeSlice(t, len(elems), len(elems))
+	if err := d.decodeElems(elems, sliceVal); err != nil {
+		return nil, err
+	}
+
+	return sliceVal.Interface(), nil
+}
+
+func (d *decoder) convertValueArray(raw *proto.Value, t reflect.Type) (interface{}, error) {
+	elems, ok := d.listElems(raw)
+	if !ok {
+		return nil, convertErr(raw, "array")
+	}
+
+	if len(elems) != t.Len() {
+		return nil, fmt.Errorf(
+			"cannot convert list of length %d to %s", len(elems), t)
+	}
+
+	arrayVal := reflect.New(t).Elem()
+	if err := d.decodeElems(elems, arrayVal); err != nil {
+		return nil, err
+	}
+
+	return arrayVal.Interface(), nil
+}
+
+// checkLen returns an error if raw is a LIST or MAP with more elements
+// than allowed by WithMaxListLen or WithMaxMapLen. This is checked before
+// anything is allocated for the elements.
+func (d *decoder) checkLen(raw *proto.Value) error {
+	switch x := raw.Value.(type) {
+	case *proto.Value_ValueList:
+		if d.maxListLen > 0 && len(x.ValueList.GetElems()) > d.maxListLen {
+			return fmt.Errorf("list exceeds maximum length of %d with %d elements",
+				d.maxListLen, len(x.ValueList.GetElems()))
+		}
+
+	case *proto.Value_ValueMap:
+		if d.maxMapLen > 0 && len(x.ValueMap.GetElems()) > d.maxMapLen {
+			return fmt.Errorf("map exceeds maximum length of %d with %d elements",
+				d.maxMapLen, len(x.ValueMap.GetElems()))
+		}
+	}
+
+	return nil
+}
+
+// listElems returns the elements to decode into a slice or array, or false
+// if the value can't be decoded into one. This is the elements of a LIST,
+// or the value itself if scalarToList is set and it isn't NULL or
+// UNDEFINED.
+func (d *decoder) listElems(raw *proto.Value) ([]*proto.Value, bool) {
+	switch raw.Type {
+	case proto.Value_LIST:
+		return raw.Value.(*proto.Value_ValueList).ValueList.Elems, true
+
+	case proto.Value_NULL, proto.Value_UNDEFINED:
+		return nil, false
+
+	default:
+		return []*proto.Value{raw}, d.scalarToList
+	}
+}
+
+// skipNulls returns elems without its NULL and UNDEFINED elements if
+// skipNullElements is set and elemTyp can't be nil. elems is returned
+// unchanged if it has none.
+func (d *decoder) skipNulls(elems []*proto.Value, elemTyp reflect.Type) []*proto.Value {
+	if !d.skipNullElements {
+		return elems
+	}
+
+	switch elemTyp.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return elems
+	}
+
+	isNull := func(v *proto.Value) bool {
+		return v.Type == proto.Value_NULL || v.Type == proto.Value_UNDEFINED
+	}
+
+	for i, elt := range elems {
+		if !isNull(elt) {
+			continue
+		}
+
+		result := make([]*proto.Value, i, len(elems)-1)
+		copy(result, elems[:i])
+		for _, elt := range elems[i+1:] {
+			if !isNull(elt) {
+				result = append(result, elt)
+			}
+		}
+
+		return result
+	}
+
+	return elems
+}
+
+// decodeElems decodes the list elements into sliceVal, which must be a
+// slice or array with the same length as elems.
+func (d *decoder) decodeElems(elems []*proto.Value, sliceVal reflect.Value) error {
+	if d.decodeScalarElems(elems, sliceVal) {
+		return nil
+	}
+
+	elemTyp := sliceVal.Type().Elem()
+	for i, elt := range elems {
+		n := d.pushIndex(i)
+		v, err := d.valueToGo(elt, elemTyp)
+		d.popPath(n)
 		if err != nil {
-			return nil, fmt.Errorf("element %d: %s", i, err)
+			return d.nestErr(err, "element %d", i)
 		}
 
 		sliceVal.Index(i).Set(reflect.ValueOf(v))
 	}
 
-	return sliceVal.Interface(), nil
+	return nil
+}
+
+// decodeScalarElems is a fast path for decodeElems for slices of int64,
+// float64, string and bool where every element is a value of the matching
+// type, which is common for lists of IDs or names, and for slices of
+// *big.Int where every element is a STRING. It reads the elements
+// directly rather than dispatching each one through valueToGo. It returns
+// false if the fast path doesn't apply, in which case sliceVal may have
+// been partially written and must be decoded with the generic path.
+func (d *decoder) decodeScalarElems(elems []*proto.Value, sliceVal reflect.Value) bool {
+	if d.fieldHook != nil || sliceVal.Kind() != reflect.Slice {
+		return false
+	}
+	if elemTyp := sliceVal.Type().Elem(); lookupConverter(elemTyp) != nil || lookupValidator(elemTyp) != nil {
+		return false
+	}
+
+	switch dst := sliceVal.Interface().(type) {
+	case []int64:
+		for i, elt := range elems {
+			v, ok := elt.Value.(*proto.Value_ValueInt)
+			if !ok || elt.Type != proto.Value_INT {
+				return false
+			}
+
+			dst[i] = v.ValueInt
+		}
+
+	case []float64:
+		for i, elt := range elems {
+			v, ok := elt.Value.(*proto.Value_ValueFloat)
+			if !ok || elt.Type != proto.Value_FLOAT {
+				return false
+			}
+
+			dst[i] = v.ValueFloat
+		}
+
+	case []string:
+		for i, elt := range elems {
+			v, ok := elt.Value.(*proto.Value_ValueString)
+			if !ok || elt.Type != proto.Value_STRING {
+				return false
+			}
+			if d.strictUTF8 && !utf8.ValidString(v.ValueString) {
+				return false
+			}
+
+			if d.internStrings {
+				dst[i] = d.intern(v.ValueString).(string)
+			} else {
+				dst[i] = v.ValueString
+			}
+		}
+
+	case []bool:
+		for i, elt := range elems {
+			v, ok := elt.Value.(*proto.Value_ValueBool)
+			if !ok || elt.Type != proto.Value_BOOL {
+				return false
+			}
+
+			dst[i] = v.ValueBool
+		}
+
+	case []*big.Int:
+		// STRING elements are parsed the same way as big.Int.UnmarshalText
+		// would parse them through the generic path.
+		for i, elt := range elems {
+			v, ok := elt.Value.(*proto.Value_ValueString)
+			if !ok || elt.Type != proto.Value_STRING {
+				return false
+			}
+
+			x, ok := new(big.Int).SetString(v.ValueString, 0)
+			if !ok {
+				return false
+			}
+
+			dst[i] = x
+		}
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// decodeMapEntry decodes the key and value of a map entry into the given
+// types. The key must be hashable so it can be used as a Go map key.
+func (d *decoder) decodeMapEntry(
+	elt *proto.Value_KV, keyTyp, elemTyp reflect.Type) (interface{}, interface{}, error) {
+	// Convert the key
+	// Keys aren't fields, so they aren't reported to the field hook or
+	// for presence. Keys have no path of their own, so errors for them are
+	// nested below the path to the map instead.
+	hook, verbosity, presence := d.fieldHook, d.errVerbosity, d.presence
+	d.fieldHook, d.presence = nil, nil
+	if verbosity == ErrorsVerbose {
+		d.errVerbosity = ErrorsDefault
+	}
+	key, err := d.valueToGo(d.transformKey(elt.Key), keyTyp)
+	d.fieldHook, d.errVerbosity, d.presence = hook, verbosity, presence
+	if err != nil {
+		return nil, nil, d.nestErr(err, "key %s", elt.Key.String())
+	}
+
+	// Keys decoded into an interface{} key type (or a struct with
+	// interface{} fields) may be lists or maps, which can't be used as
+	// map keys. SetMapIndex would panic for these.
+	if !hashable(reflect.ValueOf(key)) {
+		return nil, nil, fmt.Errorf(
+			"key %s: cannot use value of type %T as a map key", elt.Key.String(), key)
+	}
+
+	// Convert the value
+	n := d.pushKey(elt.Key)
+	elem, err := d.valueToGo(elt.Value, elemTyp)
+	d.popPath(n)
+	if err != nil {
+		return nil, nil, d.nestErr(err, "element for key %s", elt.Key.String())
+	}
+
+	return key, elem, nil
 }
 
-func convertValueMap(raw *proto.Value, t reflect.Type) (interface{}, error) {
+func (d *decoder) convertValueMap(raw *proto.Value, t reflect.Type) (interface{}, error) {
 	if raw.Type != proto.Value_MAP {
 		return nil, convertErr(raw, "map")
 	}
@@ -247,38 +1181,87 @@ func convertValueMap(raw *proto.Value, t reflect.Type) (interface{}, error) {
 	m := raw.Value.(*proto.Value_ValueMap).ValueMap
 	keyTyp := t.Key()
 	elemTyp := t.Elem()
-	mapVal := reflect.MakeMap(t)
+	mapVal := reflect.MakeMapWithSize(t, len(m.Elems))
 	for _, elt := range m.Elems {
-		// Convert the key
-		key, err := valueToGo(elt.Key, keyTyp)
+		key, elem, err := d.decodeMapEntry(elt, keyTyp, elemTyp)
 		if err != nil {
-			return nil, fmt.Errorf("key %s: %s", elt.Key.String(), err)
+			return nil, err
 		}
 
-		// Convert the value
-		elem, err := valueToGo(elt.Value, elemTyp)
-		if err != nil {
-			return nil, fmt.Errorf("element for key %s: %s", elt.Key.String(), err)
+		// Set it
+		keyVal := reflect.ValueOf(key)
+		if d.duplicateKeys != DuplicateKeysLastWins && mapVal.MapIndex(keyVal).IsValid() {
+			if d.duplicateKeys == DuplicateKeysError {
+				return nil, duplicateKeyErr(elt.Key)
+			}
+
+			continue
 		}
 
-		// Set it
-		mapVal.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(elem))
+		mapVal.SetMapIndex(keyVal, reflect.ValueOf(elem))
 	}
 
 	return mapVal.Interface(), nil
 }
 
+// hashable returns true if v can be used as a map key.
+func hashable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+
+	case reflect.Interface:
+		return v.IsNil() || hashable(v.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashable(v.Field(i)) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !hashable(v.Index(i)) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return v.Type().Comparable()
+	}
+}
+
 // valueMapType creates a map type to match the keys/values in the value.
-func valueMapType(raw *proto.Value) reflect.Type {
+func (d *decoder) valueMapType(raw *proto.Value) reflect.Type {
 	m := raw.Value.(*proto.Value_ValueMap).ValueMap
-	var keys []*proto.Value
-	var values []*proto.Value
+
+	// The keys and values are gathered into the decoder's scratch buffer
+	// so that repeated decodes don't allocate a new slice for every map.
+	scratch := d.scratch[:0]
+	for _, elt := range m.Elems {
+		scratch = append(scratch, elt.Key)
+	}
+	keyTyp := elemType(scratch)
+
+	scratch = scratch[:0]
 	for _, elt := range m.Elems {
-		keys = append(keys, elt.Key)
-		values = append(values, elt.Value)
+		scratch = append(scratch, elt.Value)
+	}
+	elemTyp := elemType(scratch)
+
+	// Clear the references so the scratch buffer doesn't keep the
+	// values alive while the decoder is pooled.
+	for i := range scratch {
+		scratch[i] = nil
 	}
+	d.scratch = scratch[:0]
 
-	return reflect.MapOf(elemType(keys), elemType(values))
+	return reflect.MapOf(keyTyp, elemTyp)
 }
 
 // valueSliceType creates a slice type to match the keys/values in the value.
@@ -322,6 +1305,110 @@ func elemType(vs []*proto.Value) reflect.Type {
 	}
 }
 
+// checkPayload returns an error if the value is nil, or has a type that
+// carries a payload (all types except UNDEFINED and NULL) but the payload
+// isn't set. The elements of a LIST and the entries of a MAP are checked
+// for nil too, including the key and value of each entry, so that they can
+// be read without further checks. Nested values aren't checked; they are
+// checked when they are decoded.
+func checkPayload(raw *proto.Value) error {
+	if raw == nil {
+		return errors.New("nil value")
+	}
+
+	switch raw.Type {
+	case proto.Value_BOOL, proto.Value_INT, proto.Value_FLOAT,
+		proto.Value_STRING, proto.Value_LIST, proto.Value_MAP:
+		if raw.Value == nil {
+			return payloadErr(raw)
+		}
+	}
+
+	switch x := raw.Value.(type) {
+	case *proto.Value_ValueList:
+		if x.ValueList == nil {
+			return payloadErr(raw)
+		}
+		for i, elt := range x.ValueList.Elems {
+			if elt == nil {
+				return fmt.Errorf("nil list element %d", i)
+			}
+		}
+
+	case *proto.Value_ValueMap:
+		if x.ValueMap == nil {
+			return payloadErr(raw)
+		}
+		for i, elt := range x.ValueMap.Elems {
+			switch {
+			case elt == nil:
+				return fmt.Errorf("nil map entry %d", i)
+			case elt.Key == nil:
+				return fmt.Errorf("nil key for map entry %d", i)
+			case elt.Value == nil:
+				return fmt.Errorf("nil value for map entry %d", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+func payloadErr(raw *proto.Value) error {
+	return fmt.Errorf("proto.Value has type %s but no payload", raw.Type)
+}
+
 func convertErr(raw *proto.Value, t string) error {
-	return fmt.Errorf("cannot convert to %s: %s", t, raw.Type)
+	return &convertError{Type: raw.Type, Target: t}
+}
+
+func duplicateKeyErr(key *proto.Value) error {
+	return fmt.Errorf("duplicate map key %s", Sprint(key))
+}
+
+// convertError is the error for a value that can't be converted to the
+// target because of its type.
+type convertError struct {
+	Type   proto.Value_Type
+	Target string
+}
+
+func (e *convertError) Error() string {
+	return fmt.Sprintf("cannot convert to %s: %s", e.Target, e.Type)
+}
+
+// pathError is the error for the value at Path, returned when errors are
+// verbose. Type is the Go type the value was decoded into, if known.
+type pathError struct {
+	Path string
+	Type reflect.Type
+	Err  error
+}
+
+func (e *pathError) Error() string {
+	msg := e.Err.Error()
+	if ce, ok := e.Err.(*convertError); ok && e.Type != nil && e.Type.Kind() != reflect.Interface {
+		msg = fmt.Sprintf("cannot convert %s value to Go type %s", ce.Type, e.Type)
+	}
+	if e.Path == "" {
+		return msg
+	}
+
+	return e.Path + ": " + msg
+}
+
+// nestErr returns the error err for a value within an element, key or
+// field described by format and args, according to the error verbosity.
+func (d *decoder) nestErr(err error, format string, args ...interface{}) error {
+	if d.errVerbosity == ErrorsTerse || err == errTimeout {
+		// Timeouts aren't caused by the value, so where they happen
+		// doesn't matter
+		return err
+	}
+	if _, ok := err.(*pathError); ok {
+		// The path already says where the error is
+		return err
+	}
+
+	return fmt.Errorf(format+": %s", append(args, err)...)
 }
@@ -0,0 +1,86 @@
+package encoding
+
+import (
+	"reflect"
+)
+
+// isZeroer is implemented by types such as time.Time that define their own
+// zero value.
+type isZeroer interface {
+	IsZero() bool
+}
+
+// isZeroerTyp is a reflect.Type for isZeroer.
+var isZeroerTyp = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// IsZeroValue reports whether v is empty, which is how GoToValue decides to
+// omit a struct field with the "omitempty" tag option.
+//
+// Nil pointers, interfaces, channels and functions are empty, but a pointer
+// to an empty value isn't, so a pointer can be used to always encode a
+// field that is set. Slices and maps are empty if they have no elements,
+// whether or not they are nil. Arrays and structs are empty if all of their
+// elements or fields are, recursively, including unexported fields. Values
+// whose type has an IsZero() bool method, such as time.Time, are empty if
+// it returns true. Other values are empty if they are the zero value of
+// their type. An invalid reflect.Value is empty.
+func IsZeroValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Chan, reflect.Func:
+		// These are checked before IsZero so that a pointer to an empty
+		// value, such as a *time.Time, isn't empty.
+		return v.IsNil()
+	}
+
+	if v.Type().Implements(isZeroerTyp) && v.CanInterface() {
+		return v.Interface().(isZeroer).IsZero()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !IsZeroValue(v.Index(i)) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !IsZeroValue(v.Field(i)) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.String:
+		return v.Len() == 0
+
+	case reflect.Bool:
+		return !v.Bool()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() == 0
+
+	default:
+		return false
+	}
+}
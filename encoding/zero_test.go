@@ -0,0 +1,77 @@
+package encoding
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsZeroValue(t *testing.T) {
+	type inner struct {
+		Name string
+		Tags []string
+	}
+
+	type outer struct {
+		Inner inner
+		When  time.Time
+		count int
+	}
+
+	cases := []struct {
+		Name     string
+		Value    interface{}
+		Expected bool
+	}{
+		{"nil", nil, true},
+		{"false", false, true},
+		{"true", true, false},
+		{"zero int", 0, true},
+		{"int", 42, false},
+		{"zero uint", uint8(0), true},
+		{"zero float", 0.0, true},
+		{"negative zero float", -1 * 0.0, true},
+		{"float", 1.5, false},
+		{"zero complex", complex(0, 0), true},
+		{"empty string", "", true},
+		{"string", "a", false},
+		{"nil slice", []int(nil), true},
+		{"empty slice", []int{}, true},
+		{"slice", []int{0}, false},
+		{"nil map", map[string]int(nil), true},
+		{"empty map", map[string]int{}, true},
+		{"map", map[string]int{"a": 0}, false},
+		{"zero array", [2]int{}, true},
+		{"array", [2]int{0, 1}, false},
+		{"empty array", [0]int{}, true},
+		{"nil pointer", (*int)(nil), true},
+		{"pointer to zero", new(int), false},
+		{"nil func", (func())(nil), true},
+		{"func", func() {}, false},
+		{"zero time", time.Time{}, true},
+		{"time", time.Unix(0, 0), false},
+		{"nil time pointer", (*time.Time)(nil), true},
+		{"pointer to zero time", &time.Time{}, false},
+		{"zero struct", outer{}, true},
+		{"struct with empty slice", outer{Inner: inner{Tags: []string{}}}, true},
+		{"struct with nested field", outer{Inner: inner{Name: "a"}}, false},
+		{"struct with time", outer{When: time.Unix(0, 0)}, false},
+		{"struct with unexported field", outer{count: 1}, false},
+		{"empty struct", struct{}{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := IsZeroValue(reflect.ValueOf(tc.Value))
+			if actual != tc.Expected {
+				t.Fatalf("bad: %v", actual)
+			}
+		})
+	}
+
+	// An interface holding a zero value isn't empty
+	var v interface{} = 0
+	if IsZeroValue(reflect.ValueOf(&v).Elem()) {
+		t.Fatal("interface should not be empty")
+	}
+}
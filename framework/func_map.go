@@ -0,0 +1,50 @@
+package framework
+
+import (
+	"sort"
+)
+
+// FuncMap is a namespace of functions keyed by name. It implements Call,
+// so any key can be called as a function and the arguments are converted
+// to the function's parameter types automatically. The functions follow
+// the same rules as functions returned by Call.Func.
+//
+// For example, if the key "math" returns this FuncMap:
+//
+//	FuncMap{
+//		"add": func(a, b int) int { return a + b },
+//		"max": func(a, b int) int { ... },
+//	}
+//
+// then "math.add(1, 2)" calls the "add" function. Accessing "math" itself
+// returns the sorted list of callable keys, ["add", "max"].
+type FuncMap map[string]interface{}
+
+// Get implements Namespace. Functions aren't values, so accessing a key
+// without calling it is always undefined.
+func (m FuncMap) Get(string) (interface{}, error) {
+	return nil, nil
+}
+
+// Func implements Call.
+func (m FuncMap) Func(key string) interface{} {
+	return m[key]
+}
+
+// Keys implements KeyLister. It returns the sorted list of callable keys
+// in the map.
+func (m FuncMap) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Functions implements sdk.FunctionLister. It returns the same keys as
+// Keys, so a Root that embeds a FuncMap lists its functions to the host.
+func (m FuncMap) Functions() ([]string, error) {
+	return m.Keys(), nil
+}
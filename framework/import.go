@@ -11,9 +11,11 @@ package framework
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/sentinel-sdk"
@@ -30,6 +32,30 @@ type Import struct {
 	// implementation for an import. See the docs for Root for more details.
 	Root Root
 
+	// Retry is the policy for retrying the lookups of keys, function calls
+	// and Map calls that fail with an error for which Retryable is true.
+	// Retries stop if they would continue past the execution deadline of
+	// the request. The zero value doesn't retry.
+	Retry RetryPolicy
+
+	// configured is non-zero once Configure has succeeded. This should be
+	// modified with sync/atomic.
+	configured int32
+
+	// partialResults is non-zero if the host enabled the
+	// sdk.CapabilityPartialResults capability. This should be modified
+	// with sync/atomic.
+	partialResults int32
+
+	// state is given to a Root that implements StateSetter.
+	state State
+
+	// closeLock is held for reading by Get and for writing by Close, so
+	// that Root isn't closed while a Get is in progress. closed is set by
+	// the first Close and is protected by closeLock.
+	closeLock sync.RWMutex
+	closed    bool
+
 	// namespaceMap keeps track of all the Namespaces for the various
 	// executions. These are cleaned up based on the ExecDeadline.
 	namespaceMap  map[uint64]Namespace
@@ -47,117 +73,257 @@ func (m *Import) Configure(raw map[string]interface{}) error {
 			"bug to the developer of this import")
 	}
 
+	if ss, ok := m.Root.(StateSetter); ok {
+		ss.SetState(&m.state)
+	}
+
 	// Configure the object itself
-	return m.Root.Configure(raw)
+	if err := m.Root.Configure(raw); err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&m.configured, 1)
+	return nil
 }
 
 // plugin.Import impl.
 func (m *Import) Get(reqs []*sdk.GetReq) ([]*sdk.GetResult, error) {
+	m.closeLock.RLock()
+	defer m.closeLock.RUnlock()
+
+	// State set up by Configure must never be seen partially initialized
+	if m.closed || atomic.LoadInt32(&m.configured) == 0 {
+		return nil, fmt.Errorf("import must be configured before Get")
+	}
+
+	partial := atomic.LoadInt32(&m.partialResults) != 0
 	resp := make([]*sdk.GetResult, len(reqs))
 	for i, req := range reqs {
-		// Get the namespace
-		ns := m.namespace(req)
-
-		// Is this a call?
-		call := req.Args != nil
-
-		// For each key, perform a get
-		var result interface{} = ns
-		for i, k := range req.Keys {
-			// If this is the last key in a call, then we have to perform
-			// the actual function call here.
-			if call && i == len(req.Keys)-1 {
-				x, ok := result.(Call)
-				if !ok {
-					return nil, fmt.Errorf(
-						"key %q doesn't support function calls",
-						strings.Join(req.Keys[:i], "."))
-				}
-
-				v, err := m.call(x.Func(k), req.Args)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"error calling function %q: %s",
-						strings.Join(req.Keys[:i], "."), err)
-				}
+		result, err := m.get(req)
+		if err != nil {
+			// With partial results, the error is reported for this
+			// request alone and the other requests continue.
+			if !partial {
+				return nil, err
+			}
 
-				result = v
-				break
+			resp[i] = &sdk.GetResult{
+				KeyId: req.KeyId,
+				Keys:  req.Keys,
+				Err:   err,
 			}
+			continue
+		}
 
-			switch x := result.(type) {
-			// For namespaces, we get the next value in the chain
-			case Namespace:
-				v, err := x.Get(k)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"error retrieving key %q: %s",
-						strings.Join(req.Keys[:i], "."), err)
-				}
+		// Build the actual result
+		resp[i] = &sdk.GetResult{
+			KeyId: req.KeyId,
+			Keys:  req.Keys,
+			Value: result,
+		}
+	}
 
-				result = v
-
-			// For maps with string keys, get the value
-			case map[string]interface{}:
-				result = x[k]
-
-			// Else...
-			default:
-				// If it is a map with reflection with a string key,
-				// then access it.
-				v := reflect.ValueOf(x)
-				if v.Kind() == reflect.Map && v.Type().Key() == stringTyp {
-					// If the value exists within the map, set it to the value
-					if v = v.MapIndex(reflect.ValueOf(k)); v.IsValid() {
-						result = v.Interface()
-						break
-					}
-				}
+	return resp, nil
+}
 
-				// Finally, its undefined
-				result = nil
+// get performs a single request for Get.
+func (m *Import) get(req *sdk.GetReq) (interface{}, error) {
+	// Get the namespace
+	ns := m.namespace(req)
+
+	// Is this a call?
+	call := req.Args != nil
+
+	// For each key, perform a get
+	var result interface{} = ns
+	for i, k := range req.Keys {
+		// If this is the last key in a call, then we have to perform
+		// the actual function call here.
+		if call && i == len(req.Keys)-1 {
+			x, ok := result.(Call)
+			if !ok {
+				return nil, fmt.Errorf(
+					"key %q doesn't support function calls",
+					strings.Join(req.Keys[:i], "."))
 			}
 
-			if result == nil {
-				break
+			var v interface{}
+			err := m.Retry.do(req.ExecDeadline, func() error {
+				var err error
+				v, err = m.call(x.Func(k), req.Args)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf(
+					"error calling function %q: %s",
+					strings.Join(req.Keys[:i], "."), err)
 			}
+
+			result = v
+			break
 		}
 
-		// If we have a Map implementation, we return the whole thing.
-		if m, ok := result.(Map); ok {
-			var err error
-			result, err = m.Map()
+		switch x := result.(type) {
+		// For namespaces, we get the next value in the chain
+		case Namespace:
+			var v interface{}
+			err := m.Retry.do(req.ExecDeadline, func() error {
+				var err error
+				v, err = x.Get(k)
+				return err
+			})
+			if err == ErrNoKey {
+				// Unknown keys are undefined
+				result = nil
+				break
+			}
 			if err != nil {
 				return nil, fmt.Errorf(
 					"error retrieving key %q: %s",
-					strings.Join(req.Keys, "."), err)
+					strings.Join(req.Keys[:i], "."), err)
 			}
+
+			result = v
+
+		// For maps with string keys, get the value
+		case map[string]interface{}:
+			result = x[k]
+
+		// Else...
+		default:
+			// If it is a map with reflection with a string key,
+			// then access it.
+			v := reflect.ValueOf(x)
+			if v.Kind() == reflect.Map && v.Type().Key() == stringTyp {
+				// If the value exists within the map, set it to the value
+				if v = v.MapIndex(reflect.ValueOf(k)); v.IsValid() {
+					result = v.Interface()
+					break
+				}
+			}
+
+			// Finally, its undefined
+			result = nil
 		}
 
-		// We now need to do a bit of reflection to convert any dangling
-		// namespace values into values that can be returned across the
-		// plugin interface.
-		result, err := m.reflect(result)
+		if result == nil {
+			break
+		}
+	}
+
+	// If we have a Map implementation, we return the whole thing.
+	if mv, ok := result.(Map); ok {
+		err := m.Retry.do(req.ExecDeadline, func() error {
+			var err error
+			result, err = mv.Map()
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf(
 				"error retrieving key %q: %s",
 				strings.Join(req.Keys, "."), err)
 		}
+	}
 
-		// Convert the result based on types
-		if result == nil {
-			result = sdk.Undefined
-		}
+	// We now need to do a bit of reflection to convert any dangling
+	// namespace values into values that can be returned across the
+	// plugin interface.
+	result, err := m.reflect(result)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error retrieving key %q: %s",
+			strings.Join(req.Keys, "."), err)
+	}
 
-		// Build the actual result
-		resp[i] = &sdk.GetResult{
-			KeyId: req.KeyId,
-			Keys:  req.Keys,
-			Value: result,
+	// Convert the result based on types
+	if result == nil {
+		result = sdk.Undefined
+	}
+
+	return result, nil
+}
+
+// io.Closer impl. Close waits for any Get in progress, then calls Close
+// on Root if it implements io.Closer and closes the values in the State,
+// so that state created in Configure can be released. This is done even
+// if Configure failed, since Root may have created state before failing.
+// Get returns an error after Close, and closing again does nothing.
+func (m *Import) Close() error {
+	m.closeLock.Lock()
+	defer m.closeLock.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	atomic.StoreInt32(&m.configured, 0)
+
+	m.namespaceLock.Lock()
+	m.namespaceMap = nil
+	m.namespaceLock.Unlock()
+
+	var err error
+	if c, ok := m.Root.(io.Closer); ok {
+		err = c.Close()
+	}
+
+	if stateErr := m.state.close(); err == nil {
+		err = stateErr
+	}
+
+	return err
+}
+
+// sdk.Invalidator impl.
+func (m *Import) Invalidate(path []string) error {
+	if i, ok := m.Root.(sdk.Invalidator); ok {
+		return i.Invalidate(path)
+	}
+
+	return nil
+}
+
+// sdk.SchemaVersioner impl.
+func (m *Import) SchemaVersion(requested string) (string, error) {
+	if sv, ok := m.Root.(sdk.SchemaVersioner); ok {
+		return sv.SchemaVersion(requested)
+	}
+
+	return "", nil
+}
+
+// sdk.FunctionLister impl.
+func (m *Import) Functions() ([]string, error) {
+	if fl, ok := m.Root.(sdk.FunctionLister); ok {
+		return fl.Functions()
+	}
+
+	return nil, nil
+}
+
+// sdk.CapabilityNegotiator impl. The capabilities of the Root are enabled
+// along with sdk.CapabilityPartialResults, which the framework supports
+// for every Root by reporting an error for a request in its result rather
+// than failing every request in the Get.
+func (m *Import) NegotiateCapabilities(host []string) []string {
+	var result []string
+	if cn, ok := m.Root.(sdk.CapabilityNegotiator); ok {
+		result = cn.NegotiateCapabilities(host)
+	}
+
+	if len(sdk.NegotiateCapabilities(host, []string{sdk.CapabilityPartialResults})) == 0 {
+		return result
+	}
+	atomic.StoreInt32(&m.partialResults, 1)
+
+	// The Root may have enabled it too
+	for _, c := range result {
+		if c == sdk.CapabilityPartialResults {
+			return result
 		}
 	}
 
-	return resp, nil
+	return append(result, sdk.CapabilityPartialResults)
 }
 
 // namespace returns the namespace for the request.
@@ -219,6 +385,22 @@ func (m *Import) call(f interface{}, args []interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("function call unsupported")
 	}
 
+	// Functions wrapped with PackList or PackMap have their results packed,
+	// and functions wrapped with ValidateArgs have their arguments checked.
+	// They may be wrapped with both, in either order.
+	var packed *packedFunc
+	var validated *validatedFunc
+	for unwrapped := false; !unwrapped; {
+		switch w := f.(type) {
+		case *packedFunc:
+			packed, f = w, w.f
+		case *validatedFunc:
+			validated, f = w, w.f
+		default:
+			unwrapped = true
+		}
+	}
+
 	// Reflect on the function and verify it is a function
 	funcVal := reflect.ValueOf(f)
 	if funcVal.Kind() != reflect.Func {
@@ -265,8 +447,23 @@ func (m *Import) call(f interface{}, args []interface{}) (interface{}, error) {
 		funcArgs[i] = argValue
 	}
 
+	// Check the arguments now that they have their final types
+	if validated != nil {
+		values := make([]interface{}, len(funcArgs))
+		for i, arg := range funcArgs {
+			values[i] = arg.Interface()
+		}
+
+		if err := validateArgs(validated.args, values); err != nil {
+			return nil, err
+		}
+	}
+
 	// Call the function
 	funcRets := funcVal.Call(funcArgs)
+	if packed != nil {
+		return packResults(funcType, funcRets, packed.keys)
+	}
 
 	// Build the return values
 	var err error
@@ -278,3 +475,38 @@ func (m *Import) call(f interface{}, args []interface{}) (interface{}, error) {
 
 	return funcRets[0].Interface(), err
 }
+
+// packResults packs the results of a function wrapped with PackList or
+// PackMap into a list, or a map with the given keys if keys is non-nil.
+func packResults(funcType reflect.Type, rets []reflect.Value, keys []string) (interface{}, error) {
+	// A trailing error is the error of the call
+	if n := len(rets); n > 0 && funcType.Out(n-1) == errorTyp {
+		if v := rets[n-1].Interface(); v != nil {
+			return nil, v.(error)
+		}
+
+		rets = rets[:n-1]
+	}
+
+	if keys == nil {
+		result := make([]interface{}, len(rets))
+		for i, ret := range rets {
+			result[i] = ret.Interface()
+		}
+
+		return result, nil
+	}
+
+	if len(keys) != len(rets) {
+		return nil, fmt.Errorf(
+			"internal error: function returns %d values but %d keys were given",
+			len(rets), len(keys))
+	}
+
+	result := make(map[string]interface{}, len(rets))
+	for i, ret := range rets {
+		result[keys[i]] = ret.Interface()
+	}
+
+	return result, nil
+}
@@ -1,7 +1,9 @@
 package framework
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"sync/atomic"
 	"testing"
@@ -14,6 +16,11 @@ import (
 
 func TestImport_impl(t *testing.T) {
 	var _ sdk.Import = new(Import)
+	var _ sdk.Invalidator = new(Import)
+	var _ sdk.SchemaVersioner = new(Import)
+	var _ sdk.CapabilityNegotiator = new(Import)
+	var _ io.Closer = new(Import)
+	var _ sdk.FunctionLister = new(Import)
 }
 
 //-------------------------------------------------------------------
@@ -69,6 +76,255 @@ func TestImportConfigure_noNamespace(t *testing.T) {
 	}
 }
 
+func TestImportGet_notConfigured(t *testing.T) {
+	impt := &Import{Root: &rootNamespace{}}
+	if _, err := impt.Get([]*sdk.GetReq{{Keys: []string{"foo"}}}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+//-------------------------------------------------------------------
+// Close
+
+func TestImportClose(t *testing.T) {
+	root := &rootCloser{}
+	impt := &Import{Root: root}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if root.Closed != 1 {
+		t.Fatalf("bad: %d", root.Closed)
+	}
+
+	// Closing again does nothing and Get fails after Close
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if root.Closed != 1 {
+		t.Fatalf("bad: %d", root.Closed)
+	}
+	if _, err := impt.Get([]*sdk.GetReq{{Keys: []string{"foo"}}}); err == nil {
+		t.Fatal("should error")
+	}
+
+	// Roots that aren't closers are fine
+	impt = &Import{Root: &rootNamespace{}}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+type rootCloser struct {
+	rootNamespace
+
+	Closed int
+}
+
+func (r *rootCloser) Close() error {
+	r.Closed++
+	return nil
+}
+
+func TestImportClose_state(t *testing.T) {
+	root := &rootState{}
+	impt := &Import{Root: root}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The value stored in Configure is seen by Get
+	actual, err := impt.Get([]*sdk.GetReq{{Keys: []string{"client"}, KeyId: 1}})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 1 || actual[0].Value != "connected" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	client, _ := root.State.Load(testStateKey)
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !client.(*testStateClient).Closed {
+		t.Fatal("state value should be closed")
+	}
+	if _, ok := root.State.Load(testStateKey); ok {
+		t.Fatal("state should be empty")
+	}
+}
+
+func TestImportClose_configureFailed(t *testing.T) {
+	root := &rootState{Err: errors.New("bad config")}
+	impt := &Import{Root: root}
+	if err := impt.Configure(map[string]interface{}{}); err == nil {
+		t.Fatal("should error")
+	}
+
+	client, ok := root.State.Load(testStateKey)
+	if !ok {
+		t.Fatal("state should be stored")
+	}
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !client.(*testStateClient).Closed {
+		t.Fatal("state value should be closed")
+	}
+	if root.Closed != 1 {
+		t.Fatalf("bad: %d", root.Closed)
+	}
+
+	// Closing again does nothing
+	if err := impt.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if root.Closed != 1 {
+		t.Fatalf("bad: %d", root.Closed)
+	}
+}
+
+func TestImportClose_waitsForGet(t *testing.T) {
+	root := &rootBlocking{Started: make(chan struct{}), Release: make(chan struct{})}
+	impt := &Import{Root: root}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	getDone := make(chan struct{})
+	go func() {
+		defer close(getDone)
+		impt.Get([]*sdk.GetReq{{Keys: []string{"foo"}}})
+	}()
+	<-root.Started
+
+	closeDone := make(chan struct{})
+	go func() {
+		defer close(closeDone)
+		impt.Close()
+	}()
+
+	select {
+	case <-closeDone:
+		t.Fatal("Close should wait for Get")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(root.Release)
+	<-getDone
+	<-closeDone
+	if root.ClosedDuringGet {
+		t.Fatal("root closed during Get")
+	}
+}
+
+// testStateKey is the State key used by rootState.
+var testStateKey = new(int)
+
+// rootState keeps a client in the State of its Import. If Err is set,
+// Configure fails with it after storing the client.
+type rootState struct {
+	State  *State
+	Err    error
+	Closed int
+}
+
+func (r *rootState) SetState(s *State) { r.State = s }
+
+func (r *rootState) Configure(map[string]interface{}) error {
+	r.State.Store(testStateKey, &testStateClient{})
+	return r.Err
+}
+
+func (r *rootState) Close() error {
+	r.Closed++
+	return nil
+}
+
+func (r *rootState) Get(key string) (interface{}, error) {
+	v, ok := r.State.Load(testStateKey)
+	if !ok || key != "client" {
+		return nil, ErrNoKey
+	}
+
+	if v.(*testStateClient).Closed {
+		return "closed", nil
+	}
+
+	return "connected", nil
+}
+
+type testStateClient struct {
+	Closed bool
+}
+
+func (c *testStateClient) Close() error {
+	c.Closed = true
+	return nil
+}
+
+// rootBlocking blocks Get until Release is closed.
+type rootBlocking struct {
+	Started chan struct{}
+	Release chan struct{}
+
+	inGet           int32
+	ClosedDuringGet bool
+}
+
+func (r *rootBlocking) Configure(map[string]interface{}) error { return nil }
+
+func (r *rootBlocking) Get(key string) (interface{}, error) {
+	atomic.StoreInt32(&r.inGet, 1)
+	defer atomic.StoreInt32(&r.inGet, 0)
+
+	close(r.Started)
+	<-r.Release
+	return nil, nil
+}
+
+func (r *rootBlocking) Close() error {
+	r.ClosedDuringGet = atomic.LoadInt32(&r.inGet) != 0
+	return nil
+}
+
+//-------------------------------------------------------------------
+// Functions
+
+func TestImportFunctions(t *testing.T) {
+	impt := &Import{Root: &rootFuncMap{FuncMap{
+		"b": func() int { return 1 },
+		"a": func() int { return 2 },
+	}}}
+
+	actual, err := impt.Functions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"a", "b"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Roots that don't list functions have none
+	impt = &Import{Root: &rootNamespace{}}
+	actual, err = impt.Functions()
+	if err != nil || actual != nil {
+		t.Fatalf("bad: %#v %s", actual, err)
+	}
+}
+
+type rootFuncMap struct {
+	FuncMap
+}
+
+func (r *rootFuncMap) Configure(map[string]interface{}) error { return nil }
+
 type rootNoImpl struct{}
 
 func (r *rootNoImpl) Configure(map[string]interface{}) error { return nil }
@@ -83,6 +339,38 @@ type rootNamespaceCreator struct{}
 func (r *rootNamespaceCreator) Configure(map[string]interface{}) error { return nil }
 func (r *rootNamespaceCreator) Namespace() Namespace                   { return nil }
 
+//-------------------------------------------------------------------
+// Invalidate
+
+func TestImportInvalidate(t *testing.T) {
+	root := &rootInvalidator{}
+	impt := &Import{Root: root}
+	if err := impt.Invalidate([]string{"foo", "bar"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(root.Paths, [][]string{{"foo", "bar"}}) {
+		t.Fatalf("bad: %#v", root.Paths)
+	}
+
+	// A root that doesn't implement Invalidator is ignored
+	impt = &Import{Root: &rootNamespace{}}
+	if err := impt.Invalidate(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// rootInvalidator records the paths it is asked to invalidate.
+type rootInvalidator struct {
+	rootNamespace
+	Paths [][]string
+}
+
+func (r *rootInvalidator) Invalidate(path []string) error {
+	r.Paths = append(r.Paths, path)
+	return nil
+}
+
 //-------------------------------------------------------------------
 // Get
 
@@ -135,6 +423,25 @@ func TestImportGet(t *testing.T) {
 			false,
 		},
 
+		{
+			"key get ErrNoKey",
+			&rootEmbedNamespace{&nsNoKey{Key: "foo", Value: "bar"}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"baz", "qux"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"baz", "qux"},
+					KeyId: 42,
+					Value: undefined,
+				},
+			},
+			false,
+		},
+
 		{
 			"key get map",
 			&rootEmbedNamespace{&nsKeyValue{
@@ -652,44 +959,576 @@ func TestImportGet(t *testing.T) {
 			nil,
 			true,
 		},
-	}
 
-	for _, tc := range cases {
-		t.Run(tc.Name, func(t *testing.T) {
-			impt := &Import{
-				Root: tc.Root,
-			}
+		{
+			"func map call",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"add": func(a, b int) int { return a + b },
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "add"},
+					KeyId: 42,
+					Args:  []interface{}{int64(1), int64(2)},
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "add"},
+					KeyId: 42,
+					Value: 3,
+				},
+			},
+			false,
+		},
 
-			// Configure
-			err := impt.Configure(map[string]interface{}{})
-			if err != nil {
-				t.Fatalf("err: %s", err)
-			}
+		{
+			"func map call packed list",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"divmod": PackList(func(a, b int) (int, int, error) {
+						return a / b, a % b, nil
+					}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(2)},
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Value: []interface{}{3, 1},
+				},
+			},
+			false,
+		},
 
-			// Perform the req
-			actual, err := impt.Get(tc.Req)
-			if (err != nil) != tc.Err {
-				t.Fatalf("err: %s", err)
-			}
-			if err != nil {
-				return
-			}
+		{
+			"func map call packed map",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"divmod": PackMap(func(a, b int) (int, int) {
+						return a / b, a % b
+					}, "quotient", "remainder"),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(2)},
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Value: map[string]interface{}{
+						"quotient":  3,
+						"remainder": 1,
+					},
+				},
+			},
+			false,
+		},
 
-			// Compare the response
-			if !reflect.DeepEqual(actual, tc.Resp) {
-				t.Fatalf("bad: %s", pretty.Sprint(actual))
-			}
-		})
-	}
-}
+		{
+			"func map call packed error",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"divmod": PackList(func(a, b int) (int, int, error) {
+						return 0, 0, fmt.Errorf("division by zero")
+					}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(0)},
+				},
+			},
+			nil,
+			true,
+		},
 
-// rootEmbedNamespace embeds a Namespace for easy testing.
-type rootEmbedNamespace struct{ Namespace }
+		{
+			"func map call packed map with wrong keys",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"divmod": PackMap(func(a, b int) (int, int) {
+						return a / b, a % b
+					}, "quotient"),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(2)},
+				},
+			},
+			nil,
+			true,
+		},
 
-func (r *rootEmbedNamespace) Configure(map[string]interface{}) error { return nil }
+		{
+			"func map call validated",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"div": ValidateArgs(func(a, b int) int {
+						return a / b
+					}, Arg{}, Arg{Name: "b", Checks: []ArgCheck{testNonZero}}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "div"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(2)},
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "div"},
+					KeyId: 42,
+					Value: 3,
+				},
+			},
+			false,
+		},
 
-// rootEmbedCall embeds a Call for easy testing.
-type rootEmbedCall struct{ C Call }
+		{
+			"func map call validated invalid",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"div": ValidateArgs(func(a, b int) int {
+						panic("should not be called")
+					}, Arg{}, Arg{Name: "b", Checks: []ArgCheck{testNonZero}}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "div"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(0)},
+				},
+			},
+			nil,
+			true,
+		},
+
+		{
+			"func map call validated and packed",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"divmod": ValidateArgs(PackList(func(a, b int) (int, int) {
+						return a / b, a % b
+					}), Arg{}, Arg{Name: "b", Checks: []ArgCheck{testNonZero}}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7), int64(2)},
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "divmod"},
+					KeyId: 42,
+					Value: []interface{}{3, 1},
+				},
+			},
+			false,
+		},
+
+		{
+			"func map call with too many validated args",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"neg": ValidateArgs(func(a int) int {
+						return -a
+					}, Arg{}, Arg{}),
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "neg"},
+					KeyId: 42,
+					Args:  []interface{}{int64(7)},
+				},
+			},
+			nil,
+			true,
+		},
+
+		{
+			"map namespace key",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"region"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"region"},
+					KeyId: 42,
+					Value: "us-east-1",
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace nested key",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"db", "port"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"db", "port"},
+					KeyId: 42,
+					Value: 5432,
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace list",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"db", "hosts"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"db", "hosts"},
+					KeyId: 42,
+					Value: []interface{}{"a", "b"},
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace null",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"db", "tls"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"db", "tls"},
+					KeyId: 42,
+					Value: sdk.Null,
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace missing key",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"nope"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"nope"},
+					KeyId: 42,
+					Value: undefined,
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace missing nested key",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"db", "nope", "deeper"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"db", "nope", "deeper"},
+					KeyId: 42,
+					Value: undefined,
+				},
+			},
+			false,
+		},
+
+		{
+			"map namespace nested map",
+			&rootEmbedNamespace{MapNamespace(map[string]interface{}{
+				"region": "us-east-1",
+				"db": map[string]interface{}{
+					"port":  5432,
+					"hosts": []interface{}{"a", "b"},
+					"tls":   nil,
+				},
+			})},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"db"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"db"},
+					KeyId: 42,
+					Value: map[string]interface{}{
+						"port":  5432,
+						"hosts": []interface{}{"a", "b"},
+						"tls":   nil,
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"func map keys",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"sub": func(a, b int) int { return a - b },
+					"add": func(a, b int) int { return a + b },
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math"},
+					KeyId: 42,
+					Value: []string{"add", "sub"},
+				},
+			},
+			false,
+		},
+
+		{
+			"key lister root",
+			&rootKeyLister{nsKeyLister{Key: "a", Value: 42}},
+			[]*sdk.GetReq{
+				{
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					KeyId: 42,
+					Value: []string{"a"},
+				},
+			},
+			false,
+		},
+
+		{
+			"key lister namespace",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key:   "zones",
+				Value: &nsKeyLister{Key: "pst", Value: 42},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"zones"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"zones"},
+					KeyId: 42,
+					Value: []string{"pst"},
+				},
+			},
+			false,
+		},
+
+		{
+			"key lister key",
+			&rootKeyLister{nsKeyLister{Key: "a", Value: 42}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"a"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"a"},
+					KeyId: 42,
+					Value: 42,
+				},
+			},
+			false,
+		},
+
+		{
+			"func map get without call",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"add": func(a, b int) int { return a + b },
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "add"},
+					KeyId: 42,
+				},
+			},
+			[]*sdk.GetResult{
+				{
+					Keys:  []string{"math", "add"},
+					KeyId: 42,
+					Value: undefined,
+				},
+			},
+			false,
+		},
+
+		{
+			"func map call unknown key",
+			&rootEmbedNamespace{&nsKeyValue{
+				Key: "math",
+				Value: FuncMap{
+					"add": func(a, b int) int { return a + b },
+				},
+			}},
+			[]*sdk.GetReq{
+				{
+					Keys:  []string{"math", "unknown"},
+					KeyId: 42,
+					Args:  []interface{}{},
+				},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			impt := &Import{
+				Root: tc.Root,
+			}
+
+			// Configure
+			err := impt.Configure(map[string]interface{}{})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// Perform the req
+			actual, err := impt.Get(tc.Req)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			// Compare the response
+			if !reflect.DeepEqual(actual, tc.Resp) {
+				t.Fatalf("bad: %s", pretty.Sprint(actual))
+			}
+		})
+	}
+}
+
+// rootEmbedNamespace embeds a Namespace for easy testing.
+type rootEmbedNamespace struct{ Namespace }
+
+func (r *rootEmbedNamespace) Configure(map[string]interface{}) error { return nil }
+
+// rootEmbedCall embeds a Call for easy testing.
+type rootEmbedCall struct{ C Call }
 
 func (r *rootEmbedCall) Configure(map[string]interface{}) error { return nil }
 
@@ -715,6 +1554,36 @@ func (v *nsKeyValue) Get(key string) (interface{}, error) {
 	return v.Value, nil
 }
 
+// nsNoKey is like nsKeyValue but returns ErrNoKey for other keys.
+type nsNoKey struct {
+	Key   string
+	Value interface{}
+}
+
+func (v *nsNoKey) Get(key string) (interface{}, error) {
+	if v.Key != key {
+		return nil, ErrNoKey
+	}
+
+	return v.Value, nil
+}
+
+// nsKeyLister is a KeyLister with a single key.
+type nsKeyLister nsKeyValue
+
+func (v *nsKeyLister) Get(key string) (interface{}, error) {
+	return (*nsKeyValue)(v).Get(key)
+}
+
+func (v *nsKeyLister) Keys() []string {
+	return []string{v.Key}
+}
+
+// rootKeyLister is a Root that is a KeyLister.
+type rootKeyLister struct{ nsKeyLister }
+
+func (r *rootKeyLister) Configure(map[string]interface{}) error { return nil }
+
 // nsKeyValueMap implements Namespace and returns a value by looking up
 // the key in a static map.
 type nsKeyValueMap struct{ Value map[string]interface{} }
@@ -745,6 +1614,94 @@ func (v *nsCall) Get(key string) (interface{}, error) {
 	return nil, fmt.Errorf("can't get")
 }
 
+// testNonZero is an ArgCheck for int arguments that must be non-zero.
+var testNonZero = ArgCheck{
+	Valid:   func(v interface{}) bool { return v.(int) != 0 },
+	Message: "must be non-zero",
+}
+
+func TestImportGet_validateArgsError(t *testing.T) {
+	f := ValidateArgs(func(id string, n int) string {
+		return id
+	}, Arg{Name: "id", Checks: []ArgCheck{{
+		Valid:   func(v interface{}) bool { return v.(string) != "" },
+		Message: "must be non-empty",
+	}}}, Arg{Checks: []ArgCheck{
+		{Valid: func(v interface{}) bool { return v.(int) >= 0 }, Message: "must be positive"},
+		testNonZero,
+	}})
+
+	cases := []struct {
+		Args     []interface{}
+		Expected string
+	}{
+		{[]interface{}{"", int64(1)}, "argument 1 (id): must be non-empty"},
+		{[]interface{}{"a", int64(-1)}, "argument 2: must be positive"},
+		{[]interface{}{"a", int64(0)}, "argument 2: must be non-zero"},
+	}
+
+	for _, tc := range cases {
+		_, err := new(Import).call(f, tc.Args)
+		argErr, ok := err.(*ArgError)
+		if !ok {
+			t.Fatalf("bad: %#v", err)
+		}
+		if argErr.Error() != tc.Expected {
+			t.Fatalf("bad: %s", argErr)
+		}
+	}
+}
+
+func TestImportGet_partialResults(t *testing.T) {
+	reqs := []*sdk.GetReq{
+		{
+			Keys:  []string{"foo"},
+			KeyId: 1,
+		},
+		{
+			Keys:  []string{"foo", "bar"},
+			KeyId: 2,
+			Args:  []interface{}{},
+		},
+	}
+
+	// Without the capability, the error fails the whole Get
+	impt := &Import{Root: &rootEmbedNamespace{&nsKeyValue{Key: "foo", Value: "bar"}}}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if caps := impt.NegotiateCapabilities(nil); len(caps) != 0 {
+		t.Fatalf("bad: %#v", caps)
+	}
+	if _, err := impt.Get(reqs); err == nil {
+		t.Fatal("should error")
+	}
+
+	// With it, the error is reported for the failed request only
+	impt = &Import{Root: &rootEmbedNamespace{&nsKeyValue{Key: "foo", Value: "bar"}}}
+	if err := impt.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	caps := impt.NegotiateCapabilities([]string{"gzip", sdk.CapabilityPartialResults})
+	if !reflect.DeepEqual(caps, []string{sdk.CapabilityPartialResults}) {
+		t.Fatalf("bad: %#v", caps)
+	}
+
+	actual, err := impt.Get(reqs)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 2 {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if actual[0].Err != nil || actual[0].Value != "bar" || actual[0].KeyId != 1 {
+		t.Fatalf("bad: %#v", actual[0])
+	}
+	if actual[1].Err == nil || actual[1].Value != nil || actual[1].KeyId != 2 {
+		t.Fatalf("bad: %#v", actual[1])
+	}
+}
+
 // Test Get with a Root that implements NamespaceCreator.
 func TestImportGet_namespaceCreator(t *testing.T) {
 	impt := &Import{
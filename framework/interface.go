@@ -1,5 +1,7 @@
 package framework
 
+import "errors"
+
 //go:generate rm -f mock_*.go
 //go:generate mockery -inpkg -note "Generated code. DO NOT MODIFY." -name=Root -testonly
 //go:generate mockery -inpkg -note "Generated code. DO NOT MODIFY." -name=Namespace -testonly
@@ -17,6 +19,21 @@ package framework
 //
 // A single root implementation and instance may be shared by many policy
 // executions if their configurations match.
+//
+// Root is the place for state that is expensive to create and shared by
+// all executions for a configuration, such as a client for a backend. The
+// framework guarantees that Configure returns successfully before any
+// Namespace or Get is called, so state created in Configure can be stored
+// on the Root and read by its namespaces without further locking, as long
+// as it isn't modified afterwards. If Root also implements io.Closer, it
+// is closed when the host closes the import, once any Get in progress has
+// returned, after which no further calls are made. Each configured import
+// has its own Import, so to keep state separate for each configuration,
+// create a new Root for each Import, or implement StateSetter to keep it
+// in the State of the Import, which is safe for concurrent use and closes
+// its values along with the Import.
+// Setup shared by every configuration, such as loading static data, is
+// done once for the plugin with rpc.ServeOpts.Init instead.
 type Root interface {
 	// Configure is called to configure this import with the operator
 	// supplied configuration for this import.
@@ -26,6 +43,12 @@ type Root interface {
 	// or Namespace itself. See the documentation for each for when you'd
 	// want to implement one or the other. If neither is implemented,
 	// an error will be returned immediately upon configuration.
+	//
+	// Root may also implement sdk.Invalidator to support clearing any
+	// cached data when requested by the host, sdk.SchemaVersioner to
+	// report the schema version of its values, sdk.FunctionLister to
+	// list its functions, sdk.CapabilityNegotiator to enable optional
+	// protocol features, and StateSetter to keep its state in a State.
 }
 
 // NamespaceCreator is an interface only used in conjunction with the
@@ -71,9 +94,10 @@ type Namespace interface {
 	// Get requests the value for a specific key. This must return a value
 	// convertable by lang/object.ToObject or another Interface value.
 	//
-	// If the value doesn't exist, nil should be returned. This will turn
-	// into "undefined" eventually in the Sentinel policy. If you want to
-	// return an explicit "null" value, please return object.Null directly.
+	// If the value doesn't exist, nil or ErrNoKey should be returned. This
+	// will turn into "undefined" eventually in the Sentinel policy. If you
+	// want to return an explicit "null" value, please return object.Null
+	// directly.
 	//
 	// If an Interface implementation is returned, this is treated like
 	// a namespace. For example, "time.pst" may return an Interface since
@@ -81,6 +105,13 @@ type Namespace interface {
 	Get(string) (interface{}, error)
 }
 
+// ErrNoKey can be returned by Namespace.Get for a key that doesn't exist.
+// The framework treats it the same as returning a nil value: the key is
+// undefined in the policy, following Sentinel semantics for accessing an
+// unknown field, rather than an error. Returning ErrNoKey lets a Namespace
+// report a missing key the same way as any other import.
+var ErrNoKey = errors.New("key not found")
+
 // Map is a Namespace that supports returning the entire map of data.
 // For example, if "time.pst" implemented this, then the writer of a policy
 // may request "time.pst" and get the entire value back as a map.
@@ -93,6 +124,24 @@ type Map interface {
 	Map() (map[string]interface{}, error)
 }
 
+// KeyLister is a Namespace that can list its keys, for imports that want
+// to be discoverable. If a KeyLister that isn't a Map is accessed as a
+// value itself, rather than one of its keys, the framework returns the
+// result of Keys as a list. For example, if "time.zones" implements this,
+// then the writer of a policy may request "time.zones" to get the list of
+// zones that can be accessed, such as ["pst", "utc"].
+//
+// The root namespace is listed by a request with no keys, which is how
+// the import itself is accessed as a value. A FuncMap is a KeyLister of
+// its callable keys.
+type KeyLister interface {
+	Namespace
+
+	// Keys returns the keys of this namespace. The list is returned as-is,
+	// so it should be sorted if the order isn't otherwise meaningful.
+	Keys() []string
+}
+
 // Call is a Namespace that supports call expressions. For example, "time.now()"
 // would invoke the Func function for "now".
 type Call interface {
@@ -105,7 +154,8 @@ type Call interface {
 	//
 	// The returned function may also return only interface{}. In this case,
 	// it is assumed an error scenario is impossible. Any other number of
-	// return values will result in an error.
+	// return values will result in an error, unless the function is wrapped
+	// with PackList or PackMap to pack its return values into one.
 	//
 	// This should return nil if the key doesn't support being called.
 	Func(string) interface{}
@@ -1,17 +1,69 @@
 package framework
 
+import (
+	"github.com/hashicorp/sentinel-sdk"
+)
+
 // MapFromKeys creates a map[string]interface{} for a Namespace from the
 // given set of keys. This is a useful helper for implementing the Map
-// interface.
+// interface. Keys for which Get returns ErrNoKey aren't included.
 func MapFromKeys(ns Namespace, keys []string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 	for _, k := range keys {
-		var err error
-		result[k], err = ns.Get(k)
+		v, err := ns.Get(k)
+		if err == ErrNoKey {
+			// Keys that don't exist are left out of the map
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
+
+		result[k] = v
 	}
 
 	return result, nil
 }
+
+// MapNamespace returns a Namespace for the static data in data, such as
+// data loaded from a file, so that it can be exposed by an import without
+// writing Get. Get returns the value for a key, where nested
+// map[string]interface{} values are returned as namespaces of their own,
+// so "import.a.b" walks data["a"]["b"]. Missing keys are undefined, and
+// nil values are null. Other values, including lists, are returned as-is
+// and converted with encoding.GoToValue when they are returned to the
+// policy, so they may be any type it supports.
+//
+// The namespaces also implement Map, so accessing data or a nested map as
+// a value returns the whole map. Nothing is copied or converted upfront,
+// so data must not be modified while the namespace is in use.
+func MapNamespace(data map[string]interface{}) Namespace {
+	return mapNamespace(data)
+}
+
+// mapNamespace is the Namespace returned by MapNamespace.
+type mapNamespace map[string]interface{}
+
+// Get implements Namespace.
+func (m mapNamespace) Get(key string) (interface{}, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, ErrNoKey
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return sdk.Null, nil
+
+	case map[string]interface{}:
+		return mapNamespace(x), nil
+
+	default:
+		return v, nil
+	}
+}
+
+// Map implements Map.
+func (m mapNamespace) Map() (map[string]interface{}, error) {
+	return m, nil
+}
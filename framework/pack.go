@@ -0,0 +1,59 @@
+package framework
+
+// packedFunc is a function whose results are packed into a single value
+// when it is called. It is created with PackList or PackMap.
+type packedFunc struct {
+	f interface{}
+
+	// keys are the map keys of the results, or nil to pack them into
+	// a list.
+	keys []string
+}
+
+// PackList wraps a function returned by Call.Func or stored in a FuncMap
+// so that it may return any number of values, which are packed into a
+// list in order. If the last return value is an error, it is returned as
+// the error of the call rather than packed.
+//
+// For example, with this FuncMap as the "model" key:
+//
+//	FuncMap{
+//		"classify": PackList(func(s string) (string, float64, error) {
+//			...
+//		}),
+//	}
+//
+// the policy accesses the results by index:
+//
+//	result = model.classify("text")
+//	label = result[0]
+//	confidence = result[1]
+func PackList(f interface{}) interface{} {
+	return &packedFunc{f: f}
+}
+
+// PackMap is like PackList, but packs the results into a map with the given
+// keys, in the order of the return values. There must be a key for every
+// return value other than a trailing error, or calling the function is an
+// error.
+//
+// For example, with this FuncMap as the "model" key:
+//
+//	FuncMap{
+//		"classify": PackMap(func(s string) (string, float64, error) {
+//			...
+//		}, "label", "confidence"),
+//	}
+//
+// the policy accesses the results by key:
+//
+//	result = model.classify("text")
+//	label = result.label
+//	confidence = result.confidence
+func PackMap(f interface{}, keys ...string) interface{} {
+	if keys == nil {
+		keys = []string{}
+	}
+
+	return &packedFunc{f: f, keys: keys}
+}
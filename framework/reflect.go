@@ -4,8 +4,16 @@ import (
 	"reflect"
 )
 
-// mapTyp is a reflect.Type for Map.
-var mapTyp = reflect.TypeOf((*Map)(nil)).Elem()
+var (
+	// mapTyp is a reflect.Type for Map.
+	mapTyp = reflect.TypeOf((*Map)(nil)).Elem()
+
+	// keyListerTyp is a reflect.Type for KeyLister.
+	keyListerTyp = reflect.TypeOf((*KeyLister)(nil)).Elem()
+
+	// errorTyp is a reflect.Type for error.
+	errorTyp = reflect.TypeOf((*error)(nil)).Elem()
+)
 
 // Reflect takes a value and uses reflection to traverse the value, finding
 // any further namespaces that need to be converted to types that can be
@@ -60,6 +68,12 @@ func (m *Import) reflectValue(v reflect.Value) (reflect.Value, error) {
 		v = reflect.ValueOf(m)
 	}
 
+	// A KeyLister, such as a FuncMap which contains functions that can't
+	// be sent across the plugin barrier, is represented by its keys.
+	if v.Type().Implements(keyListerTyp) {
+		return reflect.ValueOf(v.Interface().(KeyLister).Keys()), nil
+	}
+
 	switch v.Kind() {
 	case reflect.Map:
 		return m.reflectMap(v)
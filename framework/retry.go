@@ -0,0 +1,66 @@
+package framework
+
+import (
+	"time"
+)
+
+// RetryPolicy configures retrying the lookups of an Import that fail with
+// a retryable error, such as a transient error from a network backend.
+// Only errors for which Retryable returns true are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times to attempt a lookup,
+	// including the first attempt. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// Backoff is the time to wait before the first retry. The wait doubles
+	// for each further retry.
+	Backoff time.Duration
+}
+
+// Retryable returns true if err should be retried by a RetryPolicy. An
+// error is retryable if it implements a Retryable method that returns
+// true. Use RetryableError to mark an error as retryable.
+func Retryable(err error) bool {
+	r, ok := err.(interface {
+		Retryable() bool
+	})
+	return ok && r.Retryable()
+}
+
+// RetryableError returns err marked as retryable so that Retryable
+// returns true for it. The message of the returned error is the same as
+// err. If err is nil, nil is returned.
+func RetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &retryableError{err}
+}
+
+type retryableError struct {
+	error
+}
+
+func (e *retryableError) Retryable() bool { return true }
+
+// do calls f until it succeeds, returns an error that isn't retryable, or
+// the policy's attempts are exhausted. Retries stop early if waiting for
+// the backoff would pass deadline, so that retries don't exceed the time
+// the host allows for the request. A zero deadline is ignored.
+func (p *RetryPolicy) do(deadline time.Time, f func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.MaxAttempts || !Retryable(err) {
+			return err
+		}
+
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,147 @@
+package framework
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/sentinel-sdk"
+)
+
+func TestRetryable(t *testing.T) {
+	if Retryable(errors.New("foo")) {
+		t.Fatal("should not be retryable")
+	}
+	if Retryable(nil) {
+		t.Fatal("nil should not be retryable")
+	}
+
+	err := RetryableError(errors.New("foo"))
+	if !Retryable(err) {
+		t.Fatal("should be retryable")
+	}
+	if err.Error() != "foo" {
+		t.Fatalf("bad: %s", err)
+	}
+
+	if RetryableError(nil) != nil {
+		t.Fatal("should be nil")
+	}
+}
+
+func TestImportGet_retry(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Failures int
+		Err      error
+		Policy   RetryPolicy
+		Deadline time.Duration
+		Attempts int
+		Success  bool
+	}{
+		{
+			"no policy",
+			1,
+			RetryableError(errors.New("transient")),
+			RetryPolicy{},
+			time.Minute,
+			1,
+			false,
+		},
+
+		{
+			"retried until success",
+			2,
+			RetryableError(errors.New("transient")),
+			RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			time.Minute,
+			3,
+			true,
+		},
+
+		{
+			"attempts exhausted",
+			5,
+			RetryableError(errors.New("transient")),
+			RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			time.Minute,
+			3,
+			false,
+		},
+
+		{
+			"not retryable",
+			1,
+			errors.New("permanent"),
+			RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			time.Minute,
+			1,
+			false,
+		},
+
+		{
+			"deadline",
+			1,
+			RetryableError(errors.New("transient")),
+			RetryPolicy{MaxAttempts: 3, Backoff: time.Minute},
+			time.Second,
+			1,
+			false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ns := &nsFlaky{Failures: tc.Failures, Err: tc.Err}
+			impt := &Import{Root: &rootFlaky{ns}, Retry: tc.Policy}
+			if err := impt.Configure(map[string]interface{}{}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := impt.Get([]*sdk.GetReq{
+				{
+					ExecId:       1,
+					ExecDeadline: time.Now().Add(tc.Deadline),
+					Keys:         []string{"foo"},
+					KeyId:        1,
+				},
+			})
+			if (err == nil) != tc.Success {
+				t.Fatalf("err: %s", err)
+			}
+			if err != nil && !strings.Contains(err.Error(), tc.Err.Error()) {
+				t.Fatalf("bad: %s", err)
+			}
+			if tc.Success && actual[0].Value != "bar" {
+				t.Fatalf("bad: %#v", actual[0])
+			}
+
+			if ns.Attempts != tc.Attempts {
+				t.Fatalf("bad attempts: %d", ns.Attempts)
+			}
+		})
+	}
+}
+
+type rootFlaky struct {
+	*nsFlaky
+}
+
+func (r *rootFlaky) Configure(map[string]interface{}) error { return nil }
+
+// nsFlaky is a Namespace that fails the first Failures gets with Err.
+type nsFlaky struct {
+	Failures int
+	Err      error
+	Attempts int
+}
+
+func (n *nsFlaky) Get(key string) (interface{}, error) {
+	n.Attempts++
+	if n.Attempts <= n.Failures {
+		return nil, n.Err
+	}
+
+	return "bar", nil
+}
@@ -0,0 +1,76 @@
+package framework
+
+import (
+	"io"
+	"sync"
+)
+
+// State holds values for a single configured Import, such as a client
+// for a backend that is created in Configure and used by every Get. It is
+// given to a Root that implements StateSetter and is safe for concurrent
+// use.
+//
+// State is set before Configure is called and Get is never called before
+// Configure succeeds, so values stored in Configure are always seen by
+// namespaces. When the Import is closed, after Root is closed and every
+// in-flight Get has returned, values that implement io.Closer are closed in
+// the reverse order they were stored and the State is emptied.
+type State struct {
+	lock   sync.RWMutex
+	values map[interface{}]interface{}
+	keys   []interface{}
+}
+
+// StateSetter is implemented by a Root that keeps the state for its
+// configuration in the State of its Import rather than on itself.
+type StateSetter interface {
+	// SetState is called with the State of the Import before Configure.
+	SetState(*State)
+}
+
+// Load returns the value stored for key and whether it exists. Keys are
+// compared like map keys, so as with context values, an unexported key
+// type avoids collisions.
+func (s *State) Load(key interface{}) (interface{}, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Store stores value for key, replacing any existing value. A replaced
+// value isn't closed.
+func (s *State) Store(key, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.values == nil {
+		s.values = make(map[interface{}]interface{})
+	}
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+
+	s.values[key] = value
+}
+
+// close empties the state and closes the values that implement io.Closer.
+// The first error is returned, but every value is closed.
+func (s *State) close() error {
+	s.lock.Lock()
+	values, keys := s.values, s.keys
+	s.values, s.keys = nil, nil
+	s.lock.Unlock()
+
+	var result error
+	for i := len(keys) - 1; i >= 0; i-- {
+		if c, ok := values[keys[i]].(io.Closer); ok {
+			if err := c.Close(); err != nil && result == nil {
+				result = err
+			}
+		}
+	}
+
+	return result
+}
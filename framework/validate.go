@@ -0,0 +1,100 @@
+package framework
+
+import (
+	"fmt"
+)
+
+// Arg describes a parameter of a function wrapped with ValidateArgs.
+type Arg struct {
+	// Name is the name of the parameter, which is used in errors. It may
+	// be empty.
+	Name string
+
+	// Checks are the checks the argument must pass, in order. Only the
+	// first failed check is reported.
+	Checks []ArgCheck
+}
+
+// ArgCheck is a single check of an argument.
+type ArgCheck struct {
+	// Valid reports whether the argument is valid. It is called with the
+	// argument after it is converted to the parameter's type, so it may
+	// assert the type directly.
+	Valid func(v interface{}) bool
+
+	// Message describes a valid argument, such as "must be non-empty".
+	Message string
+}
+
+// ArgError is the error returned when an argument of a function wrapped
+// with ValidateArgs fails a check.
+type ArgError struct {
+	// Index is the index of the argument, starting at 1.
+	Index int
+
+	// Name is the name of the argument, if it has one.
+	Name string
+
+	// Message is the Message of the failed check.
+	Message string
+}
+
+func (e *ArgError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("argument %d: %s", e.Index, e.Message)
+	}
+
+	return fmt.Sprintf("argument %d (%s): %s", e.Index, e.Name, e.Message)
+}
+
+// validatedFunc is a function whose arguments are checked before it is
+// called. It is created with ValidateArgs.
+type validatedFunc struct {
+	f    interface{}
+	args []Arg
+}
+
+// ValidateArgs wraps a function returned by Call.Func or stored in a
+// FuncMap so that its arguments are checked before it is called. The
+// arguments are checked after they are converted to the parameter types,
+// and the first failed check is returned as an *ArgError so that every
+// function reports invalid arguments the same way. There is an Arg for
+// each parameter in order; parameters without one aren't checked, and
+// giving more Args than there are parameters is an error when called.
+// f may also be wrapped with PackList or PackMap, in either order.
+//
+// For example:
+//
+//	FuncMap{
+//		"user": ValidateArgs(func(id string) (*User, error) {
+//			...
+//		}, Arg{Name: "id", Checks: []ArgCheck{{
+//			Valid:   func(v interface{}) bool { return v.(string) != "" },
+//			Message: "must be non-empty",
+//		}}}),
+//	}
+//
+// makes calling user("") fail with the error "argument 1 (id): must be
+// non-empty" without calling the function.
+func ValidateArgs(f interface{}, args ...Arg) interface{} {
+	return &validatedFunc{f: f, args: args}
+}
+
+// validateArgs checks the arguments of a call against args.
+func validateArgs(args []Arg, values []interface{}) error {
+	if len(args) > len(values) {
+		return fmt.Errorf(
+			"internal error: %d arguments validated but function has %d parameters",
+			len(args), len(values))
+	}
+
+	for i, arg := range args {
+		for _, check := range arg.Checks {
+			if check.Valid != nil && !check.Valid(values[i]) {
+				return &ArgError{Index: i + 1, Name: arg.Name, Message: check.Message}
+			}
+		}
+	}
+
+	return nil
+}
@@ -51,6 +51,85 @@ type Import interface {
 	Get(reqs []*GetReq) ([]*GetResult, error)
 }
 
+// Invalidator is an optional interface that an Import can implement to
+// support invalidating cached data. The host calls Invalidate when it
+// knows that the data for the import has changed. Imports that don't
+// implement this are unaffected.
+type Invalidator interface {
+	// Invalidate clears any cached data for the given key path. For
+	// example for "a.b.c" where "a" is the import, path would be
+	// ["b", "c"]. Data for any keys within the path should be cleared
+	// as well. If path is empty, all cached data should be cleared.
+	Invalidate(path []string) error
+}
+
+// SchemaVersioner is an optional interface that an Import can implement to
+// declare the version of the schema of the values it returns. The version
+// is reported to the host when the import is configured, so that the host
+// can detect policies written for a different version.
+type SchemaVersioner interface {
+	// SchemaVersion is called after Configure and returns the schema
+	// version of the values returned by Get. requested is the version
+	// requested by the host, or empty if the host accepts any version.
+	// If the import can't return values for the requested version, this
+	// should return an error, which fails the configuration.
+	SchemaVersion(requested string) (string, error)
+}
+
+// FunctionLister is an optional interface that an Import can implement to
+// list the functions it provides, so that hosts and tools can discover
+// them without calling them.
+type FunctionLister interface {
+	// Functions returns the key paths of the callable functions, joined
+	// with ".". For example for "a.b.c()" where "a" is the import, the
+	// path would be "b.c".
+	Functions() ([]string, error)
+}
+
+// CapabilityNegotiator is an optional interface that an Import can
+// implement to support optional protocol features that the host may not
+// support, such as when a newer import runs with an older host. Imports
+// that don't implement this are configured with no capabilities.
+type CapabilityNegotiator interface {
+	// NegotiateCapabilities is called after Configure with the
+	// capabilities the host supports. It returns the capabilities the
+	// import enables, which are reported to the host. The import must
+	// only use features for the capabilities it returns. Capabilities
+	// that the host doesn't support are ignored.
+	NegotiateCapabilities(host []string) []string
+}
+
+// CapabilityPartialResults is the capability for reporting errors for
+// individual requests of a Get. Without it, an error for any request fails
+// the whole Get, and the host gets no results. With it, the import may set
+// GetResult.Err for the requests that failed and return the results of the
+// others, and the host uses the successful results and treats each failed
+// request as if that request alone had returned the error. Get may still
+// return an error for the whole Get, such as if the import isn't
+// configured, which the host treats as before. The framework package
+// supports this for every import.
+const CapabilityPartialResults = "partial-results"
+
+// NegotiateCapabilities returns the capabilities in supported that are
+// also in host, in the order of supported. This is a helper for
+// implementing CapabilityNegotiator.
+func NegotiateCapabilities(host, supported []string) []string {
+	hostSet := make(map[string]struct{}, len(host))
+	for _, c := range host {
+		hostSet[c] = struct{}{}
+	}
+
+	var result []string
+	for _, c := range supported {
+		if _, ok := hostSet[c]; ok {
+			result = append(result, c)
+			delete(hostSet, c)
+		}
+	}
+
+	return result
+}
+
 // GetReq are the arguments given to Get for an Import.
 type GetReq struct {
 	// ExecId is a unique ID representing the particular execution for this
@@ -88,6 +167,11 @@ type GetResult struct {
 	KeyId uint64      // KeyId matching GetReq.KeyId, or zero.
 	Keys  []string    // Keys structure from GetReq.Keys, or new key set.
 	Value interface{} // Value compatible with lang/object.ToObject
+
+	// Err is the error for this request alone, in which case Value is
+	// ignored. This may only be set if CapabilityPartialResults is
+	// enabled; otherwise Get must return the error for every request.
+	Err error
 }
 
 // GetResultList is a wrapper around a slice of GetResult structures
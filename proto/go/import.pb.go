@@ -12,6 +12,8 @@ It has these top-level messages:
 	Configure
 	Get
 	Close
+	Invalidate
+	Functions
 	Value
 */
 package proto
@@ -79,7 +81,7 @@ var Value_Type_value = map[string]int32{
 func (x Value_Type) String() string {
 	return proto1.EnumName(Value_Type_name, int32(x))
 }
-func (Value_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
+func (Value_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
 
 // Empty is just an empty message.
 type Empty struct {
@@ -101,6 +103,12 @@ func (*Configure) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1
 
 type Configure_Request struct {
 	Config *Value `protobuf:"bytes,3,opt,name=config" json:"config,omitempty"`
+	// schema_version is the schema version the host requests. This
+	// is empty if the host accepts any version.
+	SchemaVersion string `protobuf:"bytes,4,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// capabilities are the optional protocol features the host
+	// supports. Features are only used if both sides support them.
+	Capabilities []string `protobuf:"bytes,5,rep,name=capabilities" json:"capabilities,omitempty"`
 }
 
 func (m *Configure_Request) Reset()                    { *m = Configure_Request{} }
@@ -115,8 +123,29 @@ func (m *Configure_Request) GetConfig() *Value {
 	return nil
 }
 
+func (m *Configure_Request) GetSchemaVersion() string {
+	if m != nil {
+		return m.SchemaVersion
+	}
+	return ""
+}
+
+func (m *Configure_Request) GetCapabilities() []string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
 type Configure_Response struct {
 	InstanceId uint64 `protobuf:"varint,1,opt,name=instance_id,json=instanceId" json:"instance_id,omitempty"`
+	// schema_version is the schema version of the values returned
+	// by the import. This is empty if the import isn't versioned.
+	SchemaVersion string `protobuf:"bytes,2,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	// capabilities are the capabilities from the request that the
+	// import enabled. Older imports never set this, so the host must
+	// treat an empty list as no capabilities.
+	Capabilities []string `protobuf:"bytes,3,rep,name=capabilities" json:"capabilities,omitempty"`
 }
 
 func (m *Configure_Response) Reset()                    { *m = Configure_Response{} }
@@ -131,6 +160,20 @@ func (m *Configure_Response) GetInstanceId() uint64 {
 	return 0
 }
 
+func (m *Configure_Response) GetSchemaVersion() string {
+	if m != nil {
+		return m.SchemaVersion
+	}
+	return ""
+}
+
+func (m *Configure_Response) GetCapabilities() []string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return nil
+}
+
 // Get are the structures for an Import.Get.
 type Get struct {
 }
@@ -211,6 +254,11 @@ type Get_Response struct {
 	KeyId      uint64   `protobuf:"varint,2,opt,name=key_id,json=keyId" json:"key_id,omitempty"`
 	Keys       []string `protobuf:"bytes,3,rep,name=keys" json:"keys,omitempty"`
 	Value      *Value   `protobuf:"bytes,4,opt,name=value" json:"value,omitempty"`
+	// error is the error for this request alone, in which case value
+	// is unset. It is only set if the "partial-results" capability
+	// is enabled; otherwise an error fails the whole Get. The host
+	// uses the other responses and treats this request as failed.
+	Error string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
 }
 
 func (m *Get_Response) Reset()                    { *m = Get_Response{} }
@@ -246,6 +294,13 @@ func (m *Get_Response) GetValue() *Value {
 	return nil
 }
 
+func (m *Get_Response) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 // MultiRequest allows multiple requests in a single Get.
 type Get_MultiRequest struct {
 	Requests []*Get_Request `protobuf:"bytes,1,rep,name=requests" json:"requests,omitempty"`
@@ -305,6 +360,82 @@ func (m *Close_Request) GetInstanceId() uint64 {
 	return 0
 }
 
+// Invalidate contains the structures for Invalidate RPC calls.
+type Invalidate struct {
+}
+
+func (m *Invalidate) Reset()                    { *m = Invalidate{} }
+func (m *Invalidate) String() string            { return proto1.CompactTextString(m) }
+func (*Invalidate) ProtoMessage()               {}
+func (*Invalidate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type Invalidate_Request struct {
+	InstanceId uint64   `protobuf:"varint,1,opt,name=instance_id,json=instanceId" json:"instance_id,omitempty"`
+	Path       []string `protobuf:"bytes,2,rep,name=path" json:"path,omitempty"`
+}
+
+func (m *Invalidate_Request) Reset()                    { *m = Invalidate_Request{} }
+func (m *Invalidate_Request) String() string            { return proto1.CompactTextString(m) }
+func (*Invalidate_Request) ProtoMessage()               {}
+func (*Invalidate_Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
+
+func (m *Invalidate_Request) GetInstanceId() uint64 {
+	if m != nil {
+		return m.InstanceId
+	}
+	return 0
+}
+
+func (m *Invalidate_Request) GetPath() []string {
+	if m != nil {
+		return m.Path
+	}
+	return nil
+}
+
+// Functions contains the structures for Functions RPC calls.
+type Functions struct {
+}
+
+func (m *Functions) Reset()                    { *m = Functions{} }
+func (m *Functions) String() string            { return proto1.CompactTextString(m) }
+func (*Functions) ProtoMessage()               {}
+func (*Functions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type Functions_Request struct {
+	InstanceId uint64 `protobuf:"varint,1,opt,name=instance_id,json=instanceId" json:"instance_id,omitempty"`
+}
+
+func (m *Functions_Request) Reset()                    { *m = Functions_Request{} }
+func (m *Functions_Request) String() string            { return proto1.CompactTextString(m) }
+func (*Functions_Request) ProtoMessage()               {}
+func (*Functions_Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
+func (m *Functions_Request) GetInstanceId() uint64 {
+	if m != nil {
+		return m.InstanceId
+	}
+	return 0
+}
+
+type Functions_Response struct {
+	// names are the key paths of the callable functions, such as
+	// "math.add" for "import.math.add()".
+	Names []string `protobuf:"bytes,1,rep,name=names" json:"names,omitempty"`
+}
+
+func (m *Functions_Response) Reset()                    { *m = Functions_Response{} }
+func (m *Functions_Response) String() string            { return proto1.CompactTextString(m) }
+func (*Functions_Response) ProtoMessage()               {}
+func (*Functions_Response) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 1} }
+
+func (m *Functions_Response) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
 // Value represents a Sentinel value.
 type Value struct {
 	// type is the type of this value
@@ -325,7 +456,7 @@ type Value struct {
 func (m *Value) Reset()                    { *m = Value{} }
 func (m *Value) String() string            { return proto1.CompactTextString(m) }
 func (*Value) ProtoMessage()               {}
-func (*Value) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Value) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 type isValue_Value interface {
 	isValue_Value()
@@ -556,7 +687,7 @@ type Value_KV struct {
 func (m *Value_KV) Reset()                    { *m = Value_KV{} }
 func (m *Value_KV) String() string            { return proto1.CompactTextString(m) }
 func (*Value_KV) ProtoMessage()               {}
-func (*Value_KV) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
+func (*Value_KV) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
 
 func (m *Value_KV) GetKey() *Value {
 	if m != nil {
@@ -579,7 +710,7 @@ type Value_Map struct {
 func (m *Value_Map) Reset()                    { *m = Value_Map{} }
 func (m *Value_Map) String() string            { return proto1.CompactTextString(m) }
 func (*Value_Map) ProtoMessage()               {}
-func (*Value_Map) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 1} }
+func (*Value_Map) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 1} }
 
 func (m *Value_Map) GetElems() []*Value_KV {
 	if m != nil {
@@ -595,7 +726,7 @@ type Value_List struct {
 func (m *Value_List) Reset()                    { *m = Value_List{} }
 func (m *Value_List) String() string            { return proto1.CompactTextString(m) }
 func (*Value_List) ProtoMessage()               {}
-func (*Value_List) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 2} }
+func (*Value_List) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 2} }
 
 func (m *Value_List) GetElems() []*Value {
 	if m != nil {
@@ -616,6 +747,11 @@ func init() {
 	proto1.RegisterType((*Get_MultiResponse)(nil), "proto.Get.MultiResponse")
 	proto1.RegisterType((*Close)(nil), "proto.Close")
 	proto1.RegisterType((*Close_Request)(nil), "proto.Close.Request")
+	proto1.RegisterType((*Invalidate)(nil), "proto.Invalidate")
+	proto1.RegisterType((*Invalidate_Request)(nil), "proto.Invalidate.Request")
+	proto1.RegisterType((*Functions)(nil), "proto.Functions")
+	proto1.RegisterType((*Functions_Request)(nil), "proto.Functions.Request")
+	proto1.RegisterType((*Functions_Response)(nil), "proto.Functions.Response")
 	proto1.RegisterType((*Value)(nil), "proto.Value")
 	proto1.RegisterType((*Value_KV)(nil), "proto.Value.KV")
 	proto1.RegisterType((*Value_Map)(nil), "proto.Value.Map")
@@ -637,6 +773,8 @@ type ImportClient interface {
 	Configure(ctx context.Context, in *Configure_Request, opts ...grpc.CallOption) (*Configure_Response, error)
 	Get(ctx context.Context, in *Get_MultiRequest, opts ...grpc.CallOption) (*Get_MultiResponse, error)
 	Close(ctx context.Context, in *Close_Request, opts ...grpc.CallOption) (*Empty, error)
+	Invalidate(ctx context.Context, in *Invalidate_Request, opts ...grpc.CallOption) (*Empty, error)
+	Functions(ctx context.Context, in *Functions_Request, opts ...grpc.CallOption) (*Functions_Response, error)
 }
 
 type importClient struct {
@@ -674,12 +812,32 @@ func (c *importClient) Close(ctx context.Context, in *Close_Request, opts ...grp
 	return out, nil
 }
 
+func (c *importClient) Invalidate(ctx context.Context, in *Invalidate_Request, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/proto.Import/Invalidate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *importClient) Functions(ctx context.Context, in *Functions_Request, opts ...grpc.CallOption) (*Functions_Response, error) {
+	out := new(Functions_Response)
+	err := grpc.Invoke(ctx, "/proto.Import/Functions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Import service
 
 type ImportServer interface {
 	Configure(context.Context, *Configure_Request) (*Configure_Response, error)
 	Get(context.Context, *Get_MultiRequest) (*Get_MultiResponse, error)
 	Close(context.Context, *Close_Request) (*Empty, error)
+	Invalidate(context.Context, *Invalidate_Request) (*Empty, error)
+	Functions(context.Context, *Functions_Request) (*Functions_Response, error)
 }
 
 func RegisterImportServer(s *grpc.Server, srv ImportServer) {
@@ -740,6 +898,42 @@ func _Import_Close_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Import_Invalidate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Invalidate_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServer).Invalidate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Import/Invalidate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServer).Invalidate(ctx, req.(*Invalidate_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Import_Functions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Functions_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServer).Functions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Import/Functions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServer).Functions(ctx, req.(*Functions_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Import_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.Import",
 	HandlerType: (*ImportServer)(nil),
@@ -756,6 +950,14 @@ var _Import_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Close",
 			Handler:    _Import_Close_Handler,
 		},
+		{
+			MethodName: "Invalidate",
+			Handler:    _Import_Invalidate_Handler,
+		},
+		{
+			MethodName: "Functions",
+			Handler:    _Import_Functions_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "import.proto",
@@ -764,49 +966,58 @@ var _Import_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("import.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 692 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xdd, 0x6e, 0xda, 0x58,
-	0x10, 0xc6, 0xf8, 0x0f, 0x06, 0xb2, 0xeb, 0x9d, 0xdd, 0x55, 0xbc, 0x96, 0xb6, 0xa1, 0x4e, 0x23,
-	0xa1, 0xa4, 0x22, 0x2a, 0xb9, 0xe9, 0x55, 0xd5, 0x10, 0x92, 0x60, 0x05, 0x48, 0x75, 0x42, 0xb8,
-	0x8d, 0x1c, 0x38, 0x89, 0x2c, 0x8c, 0xed, 0xe2, 0x43, 0x55, 0xfa, 0x56, 0x55, 0x9f, 0xa2, 0x97,
-	0x7d, 0xa3, 0xca, 0x63, 0x9b, 0x00, 0x49, 0xd5, 0xf6, 0xca, 0x67, 0xe6, 0x9b, 0x9f, 0x6f, 0xe6,
-	0x7c, 0x3e, 0x50, 0xf5, 0xa6, 0x51, 0x38, 0x13, 0x8d, 0x68, 0x16, 0x8a, 0x10, 0x55, 0xfa, 0xd8,
-	0x3a, 0xa8, 0xa7, 0xd3, 0x48, 0x2c, 0x6c, 0x0f, 0xca, 0x27, 0x61, 0x70, 0xe7, 0xdd, 0xcf, 0x67,
-	0xdc, 0x3a, 0x04, 0x9d, 0xf1, 0xf7, 0x73, 0x1e, 0x0b, 0x7c, 0x01, 0xda, 0x88, 0xfc, 0xa6, 0x5c,
-	0x93, 0xea, 0x95, 0x66, 0x35, 0xcd, 0x6f, 0x0c, 0x5d, 0x7f, 0xce, 0x59, 0x86, 0x59, 0x07, 0x50,
-	0x62, 0x3c, 0x8e, 0xc2, 0x20, 0xe6, 0xb8, 0x03, 0x15, 0x2f, 0x88, 0x85, 0x1b, 0x8c, 0xf8, 0x8d,
-	0x37, 0x36, 0xa5, 0x9a, 0x54, 0x57, 0x18, 0xe4, 0x2e, 0x67, 0x6c, 0x7f, 0x93, 0x41, 0x3e, 0xe7,
-	0xc2, 0xfa, 0x2a, 0x3d, 0xb4, 0xf9, 0x59, 0x12, 0x6e, 0x83, 0xce, 0x3f, 0xf2, 0x51, 0x02, 0x16,
-	0x09, 0xd4, 0x12, 0xd3, 0x19, 0xe3, 0x2e, 0x6c, 0x11, 0x30, 0xe6, 0xee, 0xd8, 0xf7, 0x02, 0x4e,
-	0x3c, 0x15, 0x56, 0x4d, 0x9c, 0xed, 0xcc, 0x87, 0x08, 0xca, 0x84, 0x2f, 0x62, 0x53, 0xa9, 0xc9,
-	0xf5, 0x32, 0xa3, 0x33, 0xfe, 0x0b, 0xda, 0x84, 0x2f, 0x92, 0x82, 0x2a, 0x65, 0xa8, 0x13, 0xbe,
-	0x70, 0xc6, 0x49, 0xe8, 0xc8, 0xf5, 0x7d, 0x53, 0xab, 0x49, 0xf5, 0x12, 0xa3, 0x33, 0xd6, 0x40,
-	0x71, 0x67, 0xf7, 0xb1, 0xa9, 0xd7, 0xe4, 0x47, 0x2b, 0x20, 0xc4, 0xfa, 0xf4, 0x1b, 0x0b, 0x58,
-	0xe9, 0x5c, 0xdc, 0xe8, 0x4c, 0x24, 0xe5, 0x15, 0x92, 0x36, 0xa8, 0x1f, 0x92, 0x36, 0xa6, 0xf2,
-	0xc4, 0xf6, 0x53, 0xc8, 0x7a, 0x03, 0xd5, 0xde, 0xdc, 0x17, 0x5e, 0xbe, 0xcb, 0x06, 0x94, 0x66,
-	0xe9, 0x31, 0x36, 0x25, 0x62, 0x8c, 0x59, 0xda, 0x39, 0x17, 0x8d, 0x2c, 0x8a, 0x2d, 0x63, 0xac,
-	0x16, 0x6c, 0x65, 0xf9, 0xd9, 0x00, 0xaf, 0xa0, 0x3c, 0xcb, 0xce, 0x79, 0x85, 0xbf, 0xd7, 0x2a,
-	0xa4, 0x18, 0x7b, 0x88, 0xb2, 0x8f, 0x40, 0x3d, 0xf1, 0xc3, 0x98, 0x5b, 0xfb, 0xbf, 0x7e, 0xa7,
-	0xf6, 0x17, 0x05, 0x54, 0x9a, 0x04, 0xf7, 0x40, 0x11, 0x8b, 0x88, 0x53, 0xcc, 0x1f, 0xcd, 0xbf,
-	0x56, 0xa7, 0x6c, 0x0c, 0x16, 0x11, 0x67, 0x04, 0xe3, 0x0e, 0x00, 0x8d, 0x7c, 0x73, 0x1b, 0x86,
-	0x3e, 0x2d, 0xaf, 0xd4, 0x29, 0xb0, 0x32, 0xf9, 0x5a, 0x61, 0xe8, 0xe3, 0xff, 0x90, 0x1a, 0x37,
-	0x5e, 0x20, 0x48, 0x08, 0x72, 0xa7, 0xc0, 0x4a, 0xe4, 0x72, 0x02, 0x81, 0xcf, 0xa1, 0x92, 0xc2,
-	0x77, 0x7e, 0xe8, 0x0a, 0xda, 0xa9, 0xd4, 0x29, 0xb0, 0xb4, 0xe8, 0x59, 0xe2, 0xc3, 0x5d, 0xa8,
-	0xa6, 0x21, 0xb1, 0x98, 0x79, 0xc1, 0x3d, 0x69, 0xa3, 0xdc, 0x29, 0xb0, 0x34, 0xf1, 0x8a, 0x9c,
-	0xd8, 0xcc, 0x79, 0xf8, 0x5e, 0x2c, 0x48, 0x29, 0x95, 0x0d, 0xd2, 0x5d, 0x2f, 0x16, 0x4b, 0x6a,
-	0x89, 0x81, 0x87, 0x39, 0xb5, 0xa9, 0x1b, 0x99, 0x3a, 0xa5, 0x18, 0x6b, 0x29, 0x3d, 0x37, 0x5a,
-	0x92, 0xed, 0xb9, 0x91, 0xd5, 0x81, 0xe2, 0xc5, 0x10, 0x9f, 0x81, 0x3c, 0xe1, 0x0b, 0x5a, 0xcc,
-	0xe6, 0xf5, 0x27, 0xc0, 0x83, 0x40, 0x8a, 0x3f, 0x16, 0xc8, 0x4b, 0x90, 0x7b, 0x6e, 0x84, 0x7b,
-	0xa0, 0x72, 0x9f, 0x4f, 0xf3, 0x2b, 0xfd, 0x73, 0xad, 0xfb, 0xc5, 0x90, 0xa5, 0xa8, 0xb5, 0x0f,
-	0x0a, 0x11, 0xb6, 0xd7, 0xc3, 0x37, 0x2a, 0x13, 0x64, 0x7b, 0xa0, 0x24, 0xd7, 0x83, 0x15, 0xd0,
-	0x9d, 0xfe, 0xf0, 0xb8, 0xeb, 0xb4, 0x8d, 0x02, 0x6e, 0x41, 0xf9, 0xba, 0xdf, 0x3e, 0x3d, 0x73,
-	0xfa, 0xa7, 0x6d, 0x43, 0xc2, 0x12, 0x28, 0xfd, 0xeb, 0x6e, 0xd7, 0x28, 0x26, 0xa7, 0xd6, 0xe5,
-	0x65, 0xd7, 0x90, 0x51, 0x07, 0xd9, 0xe9, 0x0f, 0x0c, 0x05, 0xcb, 0xa0, 0x9e, 0x75, 0x2f, 0x8f,
-	0x07, 0x86, 0x8a, 0x00, 0xda, 0xd5, 0x80, 0x39, 0xfd, 0x73, 0x43, 0x4b, 0x22, 0xbb, 0xce, 0xd5,
-	0xc0, 0xd0, 0x93, 0xc8, 0xde, 0xf1, 0x3b, 0xa3, 0xd4, 0xd2, 0xb3, 0x41, 0x9b, 0x9f, 0x25, 0xd0,
-	0x1c, 0x7a, 0xca, 0xf0, 0xed, 0xca, 0xa3, 0x85, 0x66, 0x46, 0x70, 0xe9, 0xc9, 0xa5, 0x6e, 0xfd,
-	0xf7, 0x04, 0x92, 0x49, 0xfd, 0x35, 0x3d, 0x45, 0xb8, 0xbd, 0x22, 0xef, 0xd5, 0x7f, 0xc9, 0x32,
-	0x1f, 0x03, 0x59, 0xe6, 0x41, 0xa6, 0x78, 0xfc, 0x27, 0xaf, 0x9e, 0x58, 0xcb, 0x9e, 0xf9, 0xba,
-	0xe8, 0x75, 0xbd, 0xd5, 0xc8, 0x38, 0xfa, 0x1e, 0x00, 0x00, 0xff, 0xff, 0x24, 0x8f, 0x67, 0x0d,
-	0x84, 0x05, 0x00, 0x00,
+	// 839 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x8e, 0x63, 0x3b, 0x8e, 0x4f, 0xd2, 0xc5, 0x1c, 0x8a, 0xd6, 0x58, 0x82, 0x0d, 0x5e, 0x2a,
+	0x45, 0x0b, 0x0a, 0xa2, 0x2b, 0x24, 0xae, 0x56, 0xdb, 0x6e, 0x7f, 0x62, 0xad, 0x93, 0xa2, 0x69,
+	0x36, 0x12, 0x57, 0xd5, 0x34, 0x99, 0xed, 0x8e, 0xea, 0xd8, 0xc6, 0x9e, 0x54, 0x84, 0xa7, 0xe0,
+	0x39, 0xb8, 0xe5, 0x29, 0x78, 0x13, 0xde, 0x02, 0xe4, 0x19, 0x3b, 0x71, 0xda, 0xae, 0x68, 0xaf,
+	0x32, 0xf3, 0x7d, 0xe7, 0xe7, 0x3b, 0x73, 0xce, 0x89, 0xa1, 0xcb, 0x17, 0x69, 0x92, 0x89, 0x41,
+	0x9a, 0x25, 0x22, 0x41, 0x53, 0xfe, 0xf8, 0x16, 0x98, 0xc7, 0x8b, 0x54, 0xac, 0xfc, 0x7f, 0x35,
+	0xb0, 0xdf, 0x24, 0xf1, 0x7b, 0x7e, 0xb5, 0xcc, 0x98, 0xf7, 0x3b, 0x58, 0x84, 0xfd, 0xba, 0x64,
+	0xb9, 0xc0, 0x6f, 0xa0, 0x35, 0x93, 0xb8, 0xab, 0xf7, 0xb4, 0x7e, 0x67, 0xbf, 0xab, 0x02, 0x0c,
+	0xa6, 0x34, 0x5a, 0x32, 0x52, 0x72, 0xb8, 0x07, 0x4f, 0xf2, 0xd9, 0x07, 0xb6, 0xa0, 0x17, 0x37,
+	0x2c, 0xcb, 0x79, 0x12, 0xbb, 0x46, 0x4f, 0xeb, 0xdb, 0x64, 0x47, 0xa1, 0x53, 0x05, 0xa2, 0x0f,
+	0xdd, 0x19, 0x4d, 0xe9, 0x25, 0x8f, 0xb8, 0xe0, 0x2c, 0x77, 0xcd, 0x9e, 0xde, 0xb7, 0xc9, 0x16,
+	0xe6, 0xdd, 0x40, 0x9b, 0xb0, 0x3c, 0x4d, 0xe2, 0x9c, 0xe1, 0x33, 0xe8, 0xf0, 0x38, 0x17, 0x34,
+	0x9e, 0xb1, 0x0b, 0x3e, 0x77, 0xb5, 0x9e, 0xd6, 0x37, 0x08, 0x54, 0x50, 0x30, 0xbf, 0x27, 0x6f,
+	0xf3, 0x21, 0x79, 0xf5, 0xbb, 0x79, 0xfd, 0x7f, 0x74, 0xd0, 0x4f, 0x99, 0xf0, 0xfe, 0xd6, 0x36,
+	0xc5, 0xff, 0x6f, 0xfe, 0xa7, 0x60, 0xb1, 0xdf, 0xd8, 0xac, 0x20, 0x9b, 0x92, 0x6c, 0x15, 0xd7,
+	0x60, 0x8e, 0xcf, 0x61, 0x47, 0x12, 0x73, 0x46, 0xe7, 0x11, 0x8f, 0x99, 0x7c, 0x3d, 0x83, 0x74,
+	0x0b, 0xf0, 0xa8, 0xc4, 0x10, 0xc1, 0xb8, 0x66, 0xab, 0xdc, 0x35, 0xa4, 0x1c, 0x79, 0xc6, 0xcf,
+	0xa1, 0x75, 0xcd, 0x56, 0x45, 0x40, 0x53, 0x7a, 0x98, 0xd7, 0x6c, 0x15, 0xcc, 0x0b, 0xd3, 0x19,
+	0x8d, 0x22, 0xb7, 0xd5, 0xd3, 0xfa, 0x6d, 0x22, 0xcf, 0xd8, 0x03, 0x83, 0x66, 0x57, 0xb9, 0x6b,
+	0xf5, 0xf4, 0x3b, 0x8d, 0x91, 0x8c, 0xf7, 0x87, 0xf6, 0x98, 0xc7, 0xdc, 0xa4, 0x6e, 0xde, 0x4a,
+	0x2d, 0x55, 0xea, 0x35, 0x95, 0x3e, 0x98, 0x37, 0x45, 0x1e, 0xd9, 0xe6, 0xdb, 0xb9, 0x15, 0x85,
+	0xbb, 0x60, 0xb2, 0x2c, 0x4b, 0x32, 0x59, 0x88, 0x4d, 0xd4, 0xc5, 0x7b, 0x05, 0xdd, 0xd1, 0x32,
+	0x12, 0xbc, 0x7a, 0xe2, 0x01, 0xb4, 0x33, 0x75, 0xcc, 0x5d, 0x4d, 0x16, 0x82, 0x65, 0xb0, 0x53,
+	0x26, 0x06, 0xa5, 0x15, 0x59, 0xdb, 0x78, 0x87, 0xb0, 0x53, 0xfa, 0x97, 0x65, 0xfd, 0x00, 0x76,
+	0x56, 0x9e, 0xab, 0x08, 0x9f, 0x6d, 0x45, 0x50, 0x1c, 0xd9, 0x58, 0xf9, 0x2f, 0xc1, 0x7c, 0x13,
+	0x25, 0x39, 0xf3, 0x5e, 0x3c, 0xbc, 0xd5, 0x7e, 0x08, 0x10, 0xc4, 0x37, 0x34, 0xe2, 0x73, 0x2a,
+	0x98, 0xf7, 0xea, 0x11, 0x43, 0x82, 0x60, 0xa4, 0x54, 0x7c, 0x70, 0x9b, 0xea, 0x01, 0x8b, 0xb3,
+	0xff, 0x0b, 0xd8, 0x27, 0xcb, 0x78, 0x26, 0x78, 0x12, 0xe7, 0x8f, 0x91, 0xe1, 0xf5, 0x6a, 0x1d,
+	0xdd, 0x05, 0x33, 0xa6, 0x8b, 0xb2, 0x6c, 0x9b, 0xa8, 0x8b, 0xff, 0x97, 0x01, 0xa6, 0x6c, 0x04,
+	0xee, 0x81, 0x21, 0x56, 0x29, 0x93, 0x51, 0x9e, 0xec, 0x7f, 0x5a, 0x6f, 0xd2, 0x60, 0xb2, 0x4a,
+	0x19, 0x91, 0x34, 0x3e, 0x03, 0x90, 0x1d, 0xbb, 0xb8, 0x4c, 0x92, 0x48, 0xf6, 0xbe, 0x3d, 0x6c,
+	0x10, 0x5b, 0x62, 0x87, 0x49, 0x12, 0xe1, 0x97, 0xa0, 0x2e, 0x17, 0x3c, 0x16, 0x72, 0x90, 0xf5,
+	0x61, 0x83, 0xb4, 0x25, 0x14, 0xc4, 0x02, 0xbf, 0x86, 0x8e, 0xa2, 0xdf, 0x47, 0x09, 0x15, 0x72,
+	0x24, 0xb4, 0x61, 0x83, 0xa8, 0xa0, 0x27, 0x05, 0x86, 0xcf, 0xa1, 0xab, 0x4c, 0x72, 0x91, 0xf1,
+	0xf8, 0x4a, 0x8d, 0xc4, 0xb0, 0x41, 0x94, 0xe3, 0xb9, 0x04, 0x71, 0xbf, 0xd2, 0x11, 0xf1, 0x5c,
+	0xc8, 0x49, 0xef, 0xdc, 0x12, 0x1d, 0xf2, 0x5c, 0xac, 0xa5, 0x15, 0x17, 0xfc, 0xbe, 0x92, 0xb6,
+	0xa0, 0xa9, 0x6b, 0x49, 0x17, 0x67, 0xcb, 0x65, 0x44, 0xd3, 0xb5, 0xd8, 0x11, 0x4d, 0xbd, 0x21,
+	0x34, 0xdf, 0x4e, 0xf1, 0x2b, 0xd0, 0xaf, 0xd9, 0xca, 0xd5, 0xee, 0x99, 0xde, 0x82, 0xd8, 0xcc,
+	0x77, 0xf3, 0xa3, 0xf3, 0xed, 0x7d, 0x07, 0xfa, 0x88, 0xa6, 0xb8, 0x07, 0x26, 0x8b, 0xd8, 0xa2,
+	0x9a, 0xbd, 0x4f, 0xb6, 0xb2, 0xbf, 0x9d, 0x12, 0xc5, 0x7a, 0x2f, 0xc0, 0x90, 0x82, 0xfd, 0x6d,
+	0xf3, 0x5b, 0x91, 0x25, 0xe5, 0x73, 0x30, 0x8a, 0xf6, 0x60, 0x07, 0xac, 0x60, 0x3c, 0x3d, 0x08,
+	0x83, 0x23, 0xa7, 0x81, 0x3b, 0x60, 0xbf, 0x1b, 0x1f, 0x1d, 0x9f, 0x04, 0xe3, 0xe3, 0x23, 0x47,
+	0xc3, 0x36, 0x18, 0xe3, 0x77, 0x61, 0xe8, 0x34, 0x8b, 0xd3, 0xe1, 0xd9, 0x59, 0xe8, 0xe8, 0x68,
+	0x81, 0x1e, 0x8c, 0x27, 0x8e, 0x81, 0x36, 0x98, 0x27, 0xe1, 0xd9, 0xc1, 0xc4, 0x31, 0x11, 0xa0,
+	0x75, 0x3e, 0x21, 0xc1, 0xf8, 0xd4, 0x69, 0x15, 0x96, 0x61, 0x70, 0x3e, 0x71, 0xac, 0xc2, 0x72,
+	0x74, 0xf0, 0xb3, 0xd3, 0x3e, 0xb4, 0xca, 0x42, 0xf7, 0xff, 0x6c, 0x42, 0x2b, 0x90, 0x5f, 0x08,
+	0x7c, 0x5d, 0xfb, 0x14, 0xa0, 0x5b, 0x0a, 0x5c, 0x23, 0xd5, 0x4e, 0x7a, 0x5f, 0xdc, 0xc3, 0x94,
+	0x83, 0xf9, 0x93, 0xfc, 0x2b, 0xc5, 0xa7, 0xb5, 0x3d, 0xac, 0x2f, 0xbd, 0xe7, 0xde, 0x25, 0x4a,
+	0xcf, 0x6f, 0xcb, 0xd5, 0xc4, 0xdd, 0x2a, 0x7a, 0x71, 0x5b, 0xe7, 0xac, 0x9e, 0x4b, 0x7e, 0xb4,
+	0xf0, 0xc7, 0xfa, 0x4a, 0x62, 0xa5, 0x67, 0x03, 0x7d, 0xc4, 0xed, 0x75, 0x6d, 0xf7, 0xd6, 0xf5,
+	0xad, 0x91, 0x3b, 0xf5, 0xd5, 0x19, 0xa5, 0xf2, 0xb2, 0x25, 0x99, 0x97, 0xff, 0x0d, 0x00, 0xec,
+	0xd5, 0xfe, 0x12, 0x54, 0x07, 0x00, 0x00,
 }
@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadataKey is the gRPC metadata key for the ID of a request.
+// If the host sets it on a request, the plugin server uses that ID for the
+// request. Otherwise the server generates one. Either way, the server sends
+// the ID back in the response header with the same key. ImportGRPCClient
+// sets a new ID on every Get.
+const RequestIDMetadataKey = "sentinel-request-id"
+
+// RequestError is the error returned by the plugin server when a request
+// fails. It includes the ID of the request so that errors can be matched
+// with the logs for the request on the host. ImportGRPCClient also returns
+// a RequestError when a Get fails, with the ID sent back by the plugin,
+// since the structure of the server's error is lost over gRPC. In that
+// case Err is the gRPC error, without the ID the server added to it.
+type RequestError struct {
+	RequestID string // ID of the request
+	Op        string // operation that failed, such as "get"
+	Err       error  // underlying error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s (request %s): %s", e.Op, e.RequestID, e.Err)
+}
+
+// Unwrap returns Err.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the gRPC status for the error, with the code of Err
+// and a message that includes the request ID. This is the status the
+// server sends for the error, and it lets grpc.Code read the code of an
+// error returned by ImportGRPCClient.
+func (e *RequestError) GRPCStatus() *status.Status {
+	s := status.Convert(e.Err)
+	return status.New(s.Code(), fmt.Sprintf(
+		"%s (request %s): %s", e.Op, e.RequestID, s.Message()))
+}
+
+// requestID returns the request ID for the request with the given context,
+// generating one if the host didn't set one.
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md[RequestIDMetadataKey]; len(vs) > 0 && vs[0] != "" {
+			return vs[0]
+		}
+	}
+
+	return newRequestID()
+}
+
+// newRequestID generates a new request ID.
+func newRequestID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		// This should never happen, but the ID is only used for
+		// correlation so it isn't worth failing the request.
+		return "unknown"
+	}
+
+	return hex.EncodeToString(buf[:])
+}
+
+// clientRequestError returns the RequestError for the gRPC error err of a
+// request the client sent with the ID id. If the plugin sent back the ID it
+// used in header, that ID is used instead.
+func clientRequestError(op, id string, header metadata.MD, err error) error {
+	if vs := header[RequestIDMetadataKey]; len(vs) > 0 && vs[0] != "" {
+		id = vs[0]
+	}
+
+	// The server's RequestError is flattened into the description, so the
+	// ID it added is removed to avoid repeating it.
+	prefix := fmt.Sprintf("%s (request %s): ", op, id)
+	if desc := grpc.ErrorDesc(err); strings.HasPrefix(desc, prefix) {
+		err = grpc.Errorf(grpc.Code(err), "%s", strings.TrimPrefix(desc, prefix))
+	}
+
+	return &RequestError{RequestID: id, Op: op, Err: err}
+}
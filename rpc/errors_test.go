@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestImportGRPCServer_requestError(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Ctx      context.Context
+		Expected string
+	}{
+		{
+			"propagated ID",
+			metadata.NewIncomingContext(context.Background(),
+				metadata.Pairs(RequestIDMetadataKey, "abc123")),
+			"abc123",
+		},
+
+		{
+			"generated ID",
+			context.Background(),
+			"",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := &ImportGRPCServer{}
+			_, err := s.Get(tc.Ctx, &proto.Get_MultiRequest{
+				Requests: []*proto.Get_Request{
+					&proto.Get_Request{InstanceId: 42, Keys: []string{"key"}},
+				},
+			})
+
+			rerr, ok := err.(*RequestError)
+			if !ok {
+				t.Fatalf("bad: %#v", err)
+			}
+			if rerr.Op != "get" {
+				t.Fatalf("bad: %#v", rerr)
+			}
+			if rerr.RequestID == "" {
+				t.Fatal("request ID should be set")
+			}
+			if tc.Expected != "" && rerr.RequestID != tc.Expected {
+				t.Fatalf("bad: %#v", rerr)
+			}
+		})
+	}
+}
+
+func TestImportGRPCClient_requestError(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+	importMock.On("Get", mock.Anything).Return(nil, errors.New("backend down"))
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		_, err := obj.Get([]*sdk.GetReq{{KeyId: 1, Keys: []string{"key"}}})
+		rerr, ok := err.(*RequestError)
+		if !ok {
+			t.Fatalf("bad: %#v", err)
+		}
+		if rerr.Op != "get" || rerr.RequestID == "" {
+			t.Fatalf("bad: %#v", rerr)
+		}
+
+		// The ID added by the server isn't repeated
+		if desc := grpc.ErrorDesc(rerr.Err); desc != "backend down" {
+			t.Fatalf("bad: %s", desc)
+		}
+		if grpc.Code(rerr.Err) != codes.Unknown {
+			t.Fatalf("bad: %s", grpc.Code(rerr.Err))
+		}
+
+		ids = append(ids, rerr.RequestID)
+	}
+
+	// Every Get has its own ID
+	if ids[0] == ids[1] {
+		t.Fatalf("bad: %#v", ids)
+	}
+}
+
+func TestImportGRPCClient_requestErrorHeader(t *testing.T) {
+	err := clientRequestError("get", "sent",
+		metadata.Pairs(RequestIDMetadataKey, "used"),
+		grpc.Errorf(codes.Unknown, "get (request used): failed"))
+	rerr := err.(*RequestError)
+	if rerr.RequestID != "used" || grpc.ErrorDesc(rerr.Err) != "failed" {
+		t.Fatalf("bad: %#v", rerr)
+	}
+	if rerr.Error() != "get (request used): rpc error: code = Unknown desc = failed" {
+		t.Fatalf("bad: %s", rerr)
+	}
+}
+
+func TestRequestError_grpcStatus(t *testing.T) {
+	err := &RequestError{
+		RequestID: "abc",
+		Op:        "get",
+		Err:       grpc.Errorf(codes.ResourceExhausted, "too large"),
+	}
+	if grpc.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("bad: %s", grpc.Code(err))
+	}
+	if desc := grpc.ErrorDesc(err); desc != "get (request abc): too large" {
+		t.Fatalf("bad: %s", desc)
+	}
+
+	// Errors without a status are Unknown, with the same message as Error
+	err = &RequestError{RequestID: "abc", Op: "get", Err: errors.New("failed")}
+	if grpc.Code(err) != codes.Unknown || grpc.ErrorDesc(err) != err.Error() {
+		t.Fatalf("bad: %s %s", grpc.Code(err), grpc.ErrorDesc(err))
+	}
+}
@@ -1,19 +1,35 @@
 package rpc
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hashicorp/sentinel-sdk"
 	"github.com/hashicorp/sentinel-sdk/encoding"
 	"github.com/hashicorp/sentinel-sdk/proto/go"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // ImportGRPCClient is a gRPC server for Imports.
 type ImportGRPCClient struct {
 	Client proto.ImportClient
 
-	instanceId uint64
+	// RequestSchemaVersion is the schema version to request from the
+	// import when it is configured. If this is empty, any version is
+	// accepted. After Configure, SchemaVersion returns the version the
+	// import reported.
+	RequestSchemaVersion string
+
+	// HostCapabilities are the capabilities the host supports, which are
+	// offered to the import when it is configured. After Configure,
+	// Capabilities returns the capabilities the import enabled.
+	HostCapabilities []string
+
+	instanceId    uint64
+	schemaVersion string
+	capabilities  []string
 }
 
 func (m *ImportGRPCClient) Close() error {
@@ -27,6 +43,31 @@ func (m *ImportGRPCClient) Close() error {
 	return nil
 }
 
+// Invalidate requests that the import clear any cached data for the given
+// key path, or all cached data if path is empty. This does nothing if the
+// import doesn't implement sdk.Invalidator.
+func (m *ImportGRPCClient) Invalidate(path []string) error {
+	_, err := m.Client.Invalidate(context.Background(), &proto.Invalidate_Request{
+		InstanceId: m.instanceId,
+		Path:       path,
+	})
+	return err
+}
+
+// Functions returns the key paths of the functions the import provides.
+// This is empty if the import doesn't implement sdk.FunctionLister. Imports
+// built with an older version of this SDK return an Unimplemented error.
+func (m *ImportGRPCClient) Functions() ([]string, error) {
+	resp, err := m.Client.Functions(context.Background(), &proto.Functions_Request{
+		InstanceId: m.instanceId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Names, nil
+}
+
 func (m *ImportGRPCClient) Configure(config map[string]interface{}) error {
 	v, err := encoding.GoToValue(config)
 	if err != nil {
@@ -34,16 +75,41 @@ func (m *ImportGRPCClient) Configure(config map[string]interface{}) error {
 	}
 
 	resp, err := m.Client.Configure(context.Background(), &proto.Configure_Request{
-		Config: v,
+		Config:        v,
+		SchemaVersion: m.RequestSchemaVersion,
+		Capabilities:  m.HostCapabilities,
 	})
 	if err != nil {
 		return err
 	}
 
 	m.instanceId = resp.InstanceId
+	m.schemaVersion = resp.SchemaVersion
+
+	// Only keep capabilities that were offered, in case of a misbehaving
+	// import.
+	m.capabilities = sdk.NegotiateCapabilities(m.HostCapabilities, resp.Capabilities)
 	return nil
 }
 
+// SchemaVersion returns the schema version reported by the import when it
+// was configured. This is empty if the import isn't versioned or hasn't
+// been configured.
+func (m *ImportGRPCClient) SchemaVersion() string {
+	return m.schemaVersion
+}
+
+// Capabilities returns the capabilities the import enabled when it was
+// configured. These are always a subset of HostCapabilities. This is empty
+// if the import doesn't support capability negotiation or hasn't been
+// configured.
+func (m *ImportGRPCClient) Capabilities() []string {
+	return m.capabilities
+}
+
+// Get performs the requests with the import. If the Get fails in the
+// plugin, the error is a *RequestError with the ID of the request, which
+// the plugin also uses for the request in its logs.
 func (m *ImportGRPCClient) Get(rawReqs []*sdk.GetReq) ([]*sdk.GetResult, error) {
 	reqs := make([]*proto.Get_Request, 0, len(rawReqs))
 	for _, req := range rawReqs {
@@ -71,15 +137,31 @@ func (m *ImportGRPCClient) Get(rawReqs []*sdk.GetReq) ([]*sdk.GetResult, error)
 		})
 	}
 
-	resp, err := m.Client.Get(context.Background(), &proto.Get_MultiRequest{
+	// Send a request ID so that an error can be matched with the logs for
+	// the request in the plugin.
+	id := newRequestID()
+	ctx := metadata.NewOutgoingContext(
+		context.Background(), metadata.Pairs(RequestIDMetadataKey, id))
+	var header metadata.MD
+	resp, err := m.Client.Get(ctx, &proto.Get_MultiRequest{
 		Requests: reqs,
-	})
+	}, grpc.Header(&header))
 	if err != nil {
-		return nil, err
+		return nil, clientRequestError("get", id, header, err)
 	}
 
 	results := make([]*sdk.GetResult, 0, len(resp.Responses))
 	for _, resp := range resp.Responses {
+		if resp.Error != "" {
+			results = append(results, &sdk.GetResult{
+				KeyId: resp.KeyId,
+				Keys:  resp.Keys,
+				Err:   errors.New(resp.Error),
+			})
+
+			continue
+		}
+
 		v, err := encoding.ValueToGo(resp.Value, nil)
 		if err != nil {
 			return nil, err
@@ -10,4 +10,5 @@ import (
 func TestImportGRPCClient_impl(t *testing.T) {
 	var _ sdk.Import = new(ImportGRPCClient)
 	var _ io.Closer = new(ImportGRPCClient)
+	var _ sdk.Invalidator = new(ImportGRPCClient)
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/hashicorp/sentinel-sdk/encoding"
 	"github.com/hashicorp/sentinel-sdk/proto/go"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // ImportGRPCServer is a gRPC server for Imports.
@@ -23,6 +25,12 @@ type ImportGRPCServer struct {
 	instanceId    uint64
 	instances     map[uint64]sdk.Import
 	instancesLock sync.RWMutex
+
+	// partialResults is the set of instances that negotiated
+	// sdk.CapabilityPartialResults, so that errors for a single request
+	// are returned in its response rather than failing the whole Get.
+	// This is protected by instancesLock.
+	partialResults map[uint64]bool
 }
 
 func (m *ImportGRPCServer) Close(
@@ -31,6 +39,7 @@ func (m *ImportGRPCServer) Close(
 	m.instancesLock.Lock()
 	impt, ok := m.instances[v.InstanceId]
 	delete(m.instances, v.InstanceId)
+	delete(m.partialResults, v.InstanceId)
 	m.instancesLock.Unlock()
 
 	// If we have it, attempt to call Close on the import if it is
@@ -44,6 +53,47 @@ func (m *ImportGRPCServer) Close(
 	return &proto.Empty{}, nil
 }
 
+func (m *ImportGRPCServer) Invalidate(
+	ctx context.Context, v *proto.Invalidate_Request) (*proto.Empty, error) {
+	m.instancesLock.RLock()
+	impt, ok := m.instances[v.InstanceId]
+	m.instancesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown instance ID given: %d", v.InstanceId)
+	}
+
+	// Imports that don't cache data have nothing to invalidate
+	if i, ok := impt.(sdk.Invalidator); ok {
+		if err := i.Invalidate(v.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &proto.Empty{}, nil
+}
+
+func (m *ImportGRPCServer) Functions(
+	ctx context.Context, v *proto.Functions_Request) (*proto.Functions_Response, error) {
+	m.instancesLock.RLock()
+	impt, ok := m.instances[v.InstanceId]
+	m.instancesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown instance ID given: %d", v.InstanceId)
+	}
+
+	// Imports that don't list their functions report none
+	var names []string
+	if fl, ok := impt.(sdk.FunctionLister); ok {
+		var err error
+		names, err = fl.Functions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &proto.Functions_Response{Names: names}, nil
+}
+
 func (m *ImportGRPCServer) Configure(
 	ctx context.Context, v *proto.Configure_Request) (*proto.Configure_Response, error) {
 	// Build the configuration
@@ -57,11 +107,32 @@ func (m *ImportGRPCServer) Configure(
 	// Configure is called once to configure a new import. Allocate the import.
 	impt := m.F()
 
-	// Call configure
+	// Call configure. If it fails, the host never gets an instance ID to
+	// close the import with, so it is closed here in case it created any
+	// state before failing.
 	if err := impt.Configure(config); err != nil {
+		closeImport(impt)
 		return nil, err
 	}
 
+	// Determine the schema version if the import is versioned
+	var version string
+	if sv, ok := impt.(sdk.SchemaVersioner); ok {
+		version, err = sv.SchemaVersion(v.SchemaVersion)
+		if err != nil {
+			closeImport(impt)
+			return nil, err
+		}
+	}
+
+	// Negotiate capabilities if the import supports any. Capabilities the
+	// host didn't offer are dropped so the host never sees them.
+	var capabilities []string
+	if cn, ok := impt.(sdk.CapabilityNegotiator); ok {
+		capabilities = sdk.NegotiateCapabilities(
+			v.Capabilities, cn.NegotiateCapabilities(v.Capabilities))
+	}
+
 	// We have to allocate a new instance ID.
 	id := atomic.AddUint64(&m.instanceId, 1)
 
@@ -71,16 +142,48 @@ func (m *ImportGRPCServer) Configure(
 		m.instances = make(map[uint64]sdk.Import)
 	}
 	m.instances[id] = impt
+	for _, c := range capabilities {
+		if c == sdk.CapabilityPartialResults {
+			if m.partialResults == nil {
+				m.partialResults = make(map[uint64]bool)
+			}
+			m.partialResults[id] = true
+		}
+	}
 	m.instancesLock.Unlock()
 
 	// Configure the import
 	return &proto.Configure_Response{
-		InstanceId: id,
+		InstanceId:    id,
+		SchemaVersion: version,
+		Capabilities:  capabilities,
 	}, nil
 }
 
+// closeImport closes an import that failed to configure, if it is a closer.
+func closeImport(impt sdk.Import) {
+	if c, ok := impt.(io.Closer); ok {
+		c.Close()
+	}
+}
+
 func (m *ImportGRPCServer) Get(
 	ctx context.Context, v *proto.Get_MultiRequest) (*proto.Get_MultiResponse, error) {
+	// Send the request ID to the host so errors can be correlated with the
+	// request even if the host didn't set the ID. This can only fail if
+	// the context isn't for a gRPC request, such as in tests.
+	id := requestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, id))
+
+	resp, err := m.get(v)
+	if err != nil {
+		return nil, &RequestError{RequestID: id, Op: "get", Err: err}
+	}
+
+	return resp, nil
+}
+
+func (m *ImportGRPCServer) get(v *proto.Get_MultiRequest) (*proto.Get_MultiResponse, error) {
 	// Build the mapping of requests by instance ID. Then we can make the
 	// calls for each proper instance easily.
 	requestsById := make(map[uint64][]*sdk.GetReq)
@@ -113,6 +216,7 @@ func (m *ImportGRPCServer) Get(
 	for id, reqs := range requestsById {
 		m.instancesLock.RLock()
 		impt, ok := m.instances[id]
+		partial := m.partialResults[id]
 		m.instancesLock.RUnlock()
 		if !ok {
 			return nil, fmt.Errorf("unknown instance ID given: %d", id)
@@ -124,9 +228,25 @@ func (m *ImportGRPCServer) Get(
 		}
 
 		for _, result := range results {
-			v, err := encoding.GoToValue(result.Value)
+			err := result.Err
+			var v *proto.Value
+			if err == nil {
+				v, err = encoding.GoToValue(result.Value)
+			}
 			if err != nil {
-				return nil, err
+				// Without partial results the host can't tell which
+				// request failed, so the whole Get fails.
+				if !partial {
+					return nil, err
+				}
+
+				responses = append(responses, &proto.Get_Response{
+					InstanceId: id,
+					KeyId:      result.KeyId,
+					Keys:       result.Keys,
+					Error:      err.Error(),
+				})
+				continue
 			}
 
 			responses = append(responses, &proto.Get_Response{
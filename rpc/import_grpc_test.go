@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -75,3 +77,358 @@ func TestImport_gRPC_get(t *testing.T) {
 		}
 	}
 }
+
+func TestImport_gRPC_invalidate(t *testing.T) {
+	importMock := &testInvalidatorImport{MockImport: new(sdk.MockImport)}
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+	importMock.On("Invalidate", []string{"key"}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err := obj.(sdk.Invalidator).Invalidate([]string{"key"})
+	importMock.AssertExpectations(t)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestImport_gRPC_invalidateUnsupported(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Imports that don't implement Invalidator ignore invalidation
+	if err := obj.(sdk.Invalidator).Invalidate(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testInvalidatorImport is a mock import that implements sdk.Invalidator.
+type testInvalidatorImport struct {
+	*sdk.MockImport
+}
+
+func (m *testInvalidatorImport) Invalidate(path []string) error {
+	return m.Called(path).Error(0)
+}
+
+func TestImport_gRPC_schemaVersion(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Requested string
+		Expected  string
+		Err       bool
+	}{
+		{"any version", "", "2", false},
+		{"supported version", "1", "1", false},
+		{"unsupported version", "3", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			importMock := &testVersionedImport{MockImport: new(sdk.MockImport)}
+			importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+			obj, closer := testImportServeGRPC(t, importMock)
+			defer closer()
+
+			client := obj.(*ImportGRPCClient)
+			client.RequestSchemaVersion = tc.Requested
+			err := client.Configure(nil)
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %s", err)
+			}
+
+			if actual := client.SchemaVersion(); actual != tc.Expected {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestImport_gRPC_schemaVersionUnversioned(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	client := obj.(*ImportGRPCClient)
+	client.RequestSchemaVersion = "1"
+	if err := client.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if actual := client.SchemaVersion(); actual != "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestImport_gRPC_failedConfigureCloses(t *testing.T) {
+	importMock := &testClosingVersionedImport{
+		testVersionedImport: testVersionedImport{MockImport: new(sdk.MockImport)},
+	}
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	// An unsupported version closes the configured import
+	client := obj.(*ImportGRPCClient)
+	client.RequestSchemaVersion = "3"
+	if err := client.Configure(nil); err == nil {
+		t.Fatal("should error")
+	}
+	if importMock.Closed != 1 {
+		t.Fatalf("bad: %d", importMock.Closed)
+	}
+
+	// So does a failed Configure
+	importMock = &testClosingVersionedImport{
+		testVersionedImport: testVersionedImport{MockImport: new(sdk.MockImport)},
+	}
+	importMock.On("Configure", map[string]interface{}{}).Return(errors.New("bad config"))
+
+	obj, closer = testImportServeGRPC(t, importMock)
+	defer closer()
+	if err := obj.Configure(nil); err == nil {
+		t.Fatal("should error")
+	}
+	if importMock.Closed != 1 {
+		t.Fatalf("bad: %d", importMock.Closed)
+	}
+}
+
+// testVersionedImport is a mock import that supports schema versions 1
+// and 2, defaulting to 2.
+type testVersionedImport struct {
+	*sdk.MockImport
+}
+
+func (m *testVersionedImport) SchemaVersion(requested string) (string, error) {
+	switch requested {
+	case "":
+		return "2", nil
+
+	case "1", "2":
+		return requested, nil
+
+	default:
+		return "", fmt.Errorf("unsupported schema version %q", requested)
+	}
+}
+
+// testClosingVersionedImport is a testVersionedImport that records Close.
+type testClosingVersionedImport struct {
+	testVersionedImport
+
+	Closed int
+}
+
+func (m *testClosingVersionedImport) Close() error {
+	m.Closed++
+	return nil
+}
+
+func TestImport_gRPC_capabilities(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Import   sdk.Import
+		Host     []string
+		Expected []string
+	}{
+		{
+			"supported by both",
+			&testCapableImport{MockImport: new(sdk.MockImport)},
+			[]string{"gzip", "batch", "cancel"},
+			[]string{"batch", "gzip"},
+		},
+
+		{
+			"unsupported by host",
+			&testCapableImport{MockImport: new(sdk.MockImport)},
+			[]string{"cancel"},
+			nil,
+		},
+
+		{
+			"import without negotiation",
+			new(sdk.MockImport),
+			[]string{"gzip"},
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			switch impt := tc.Import.(type) {
+			case *testCapableImport:
+				impt.On("Configure", map[string]interface{}{}).Return(nil)
+			case *sdk.MockImport:
+				impt.On("Configure", map[string]interface{}{}).Return(nil)
+			}
+
+			obj, closer := testImportServeGRPC(t, tc.Import)
+			defer closer()
+
+			client := obj.(*ImportGRPCClient)
+			client.HostCapabilities = tc.Host
+			if err := client.Configure(nil); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if actual := client.Capabilities(); !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+// testCapableImport is a mock import that supports the "batch" and "gzip"
+// capabilities. It also claims "unknown" to check that capabilities the
+// host didn't offer are dropped.
+type testCapableImport struct {
+	*sdk.MockImport
+}
+
+func (m *testCapableImport) NegotiateCapabilities(host []string) []string {
+	return []string{"batch", "unknown", "gzip"}
+}
+
+func TestImport_gRPC_partialResults(t *testing.T) {
+	cases := []struct {
+		Name string
+		Host []string
+		Err  bool
+	}{
+		{"enabled", []string{sdk.CapabilityPartialResults}, false},
+		{"disabled", nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			importMock := &testPartialImport{MockImport: new(sdk.MockImport)}
+			importMock.On("Configure", map[string]interface{}{}).Return(nil)
+			importMock.On("Get", mock.Anything).Return([]*sdk.GetResult{
+				&sdk.GetResult{
+					KeyId: 1,
+					Keys:  []string{"ok"},
+					Value: "value",
+				},
+				&sdk.GetResult{
+					KeyId: 2,
+					Keys:  []string{"bad"},
+					Err:   errors.New("not found"),
+				},
+			}, nil)
+
+			obj, closer := testImportServeGRPC(t, importMock)
+			defer closer()
+
+			client := obj.(*ImportGRPCClient)
+			client.HostCapabilities = tc.Host
+			if err := client.Configure(nil); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			actual, err := client.Get([]*sdk.GetReq{
+				{KeyId: 1, Keys: []string{"ok"}},
+				{KeyId: 2, Keys: []string{"bad"}},
+			})
+			if (err != nil) != tc.Err {
+				t.Fatalf("err: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			expected := []*sdk.GetResult{
+				&sdk.GetResult{
+					KeyId: 1,
+					Keys:  []string{"ok"},
+					Value: "value",
+				},
+				&sdk.GetResult{
+					KeyId: 2,
+					Keys:  []string{"bad"},
+					Err:   errors.New("not found"),
+				},
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+// testPartialImport is a mock import that supports
+// sdk.CapabilityPartialResults.
+type testPartialImport struct {
+	*sdk.MockImport
+}
+
+func (m *testPartialImport) NegotiateCapabilities(host []string) []string {
+	return []string{sdk.CapabilityPartialResults}
+}
+
+func TestImport_gRPC_functions(t *testing.T) {
+	importMock := &testFunctionsImport{MockImport: new(sdk.MockImport)}
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	client := obj.(*ImportGRPCClient)
+	if err := client.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := client.Functions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, []string{"math.add", "now"}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestImport_gRPC_functionsUnsupported(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeGRPC(t, importMock)
+	defer closer()
+
+	client := obj.(*ImportGRPCClient)
+	if err := client.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := client.Functions()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// testFunctionsImport is a mock import that implements sdk.FunctionLister.
+type testFunctionsImport struct {
+	*sdk.MockImport
+}
+
+func (m *testFunctionsImport) Functions() ([]string, error) {
+	return []string{"math.add", "now"}, nil
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/sentinel-sdk"
+)
+
+// LoggingMiddleware returns middleware that logs every call to the import
+// with its duration and any error. If logger is nil, logs are written to
+// stderr, which the plugin host collects.
+func LoggingMiddleware(logger *log.Logger) ImportMiddleware {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return func(impt sdk.Import) sdk.Import {
+		return &loggingImport{Import: impt, logger: logger}
+	}
+}
+
+// loggingImport is the sdk.Import implementation for LoggingMiddleware.
+type loggingImport struct {
+	sdk.Import
+
+	logger *log.Logger
+}
+
+func (m *loggingImport) log(op string, start time.Time, err error) {
+	if err != nil {
+		m.logger.Printf("[ERROR] import: %s failed after %s: %s", op, time.Since(start), err)
+		return
+	}
+
+	m.logger.Printf("[DEBUG] import: %s completed in %s", op, time.Since(start))
+}
+
+func (m *loggingImport) Configure(config map[string]interface{}) error {
+	start := time.Now()
+	err := m.Import.Configure(config)
+	m.log("Configure", start, err)
+	return err
+}
+
+func (m *loggingImport) Get(reqs []*sdk.GetReq) ([]*sdk.GetResult, error) {
+	keys := make([]string, len(reqs))
+	for i, req := range reqs {
+		keys[i] = strings.Join(req.Keys, ".")
+		if req.Call() {
+			keys[i] += "()"
+		}
+	}
+
+	start := time.Now()
+	results, err := m.Import.Get(reqs)
+	m.log("Get "+strings.Join(keys, ", "), start, err)
+	return results, err
+}
+
+func (m *loggingImport) Close() error {
+	c, ok := m.Import.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	err := c.Close()
+	m.log("Close", start, err)
+	return err
+}
+
+func (m *loggingImport) Invalidate(path []string) error {
+	i, ok := m.Import.(sdk.Invalidator)
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	err := i.Invalidate(path)
+	m.log("Invalidate "+strings.Join(path, "."), start, err)
+	return err
+}
+
+func (m *loggingImport) SchemaVersion(requested string) (string, error) {
+	sv, ok := m.Import.(sdk.SchemaVersioner)
+	if !ok {
+		return "", nil
+	}
+
+	return sv.SchemaVersion(requested)
+}
+
+func (m *loggingImport) Functions() ([]string, error) {
+	fl, ok := m.Import.(sdk.FunctionLister)
+	if !ok {
+		return nil, nil
+	}
+
+	return fl.Functions()
+}
+
+func (m *loggingImport) NegotiateCapabilities(host []string) []string {
+	cn, ok := m.Import.(sdk.CapabilityNegotiator)
+	if !ok {
+		return nil
+	}
+
+	return cn.NegotiateCapabilities(host)
+}
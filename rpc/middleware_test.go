@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoggingMiddleware_impl(t *testing.T) {
+	impt := LoggingMiddleware(nil)(new(sdk.MockImport))
+	if _, ok := impt.(io.Closer); !ok {
+		t.Fatal("should be io.Closer")
+	}
+	if _, ok := impt.(sdk.Invalidator); !ok {
+		t.Fatal("should be sdk.Invalidator")
+	}
+	if _, ok := impt.(sdk.SchemaVersioner); !ok {
+		t.Fatal("should be sdk.SchemaVersioner")
+	}
+}
+
+func TestServeOpts_middleware(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+	importMock.On("Get", mock.Anything).Return([]*sdk.GetResult{
+		&sdk.GetResult{KeyId: 1, Keys: []string{"key"}, Value: "bar"},
+	}, nil)
+
+	// Record the order middleware is called in
+	var order []string
+	record := func(name string) ImportMiddleware {
+		return func(impt sdk.Import) sdk.Import {
+			return &testRecordImport{Import: impt, name: name, order: &order}
+		}
+	}
+
+	var buf bytes.Buffer
+	obj, closer := testImportServeOpts(t, &ServeOpts{
+		ImportFunc: testImportFixed(importMock),
+		Middleware: []ImportMiddleware{
+			record("first"),
+			LoggingMiddleware(log.New(&buf, "", 0)),
+			record("second"),
+		},
+	})
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	results, err := obj.Get([]*sdk.GetReq{
+		&sdk.GetReq{KeyId: 1, Keys: []string{"key"}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := sdk.GetResultList(results).KeyId(1).Value; v != "bar" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	expected := "first Configure,second Configure,first Get,second Get"
+	if actual := strings.Join(order, ","); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+
+	logs := buf.String()
+	if !strings.Contains(logs, "import: Configure completed") ||
+		!strings.Contains(logs, "import: Get key completed") {
+		t.Fatalf("bad: %s", logs)
+	}
+}
+
+// testRecordImport records the calls to Configure and Get.
+type testRecordImport struct {
+	sdk.Import
+
+	name  string
+	order *[]string
+}
+
+func (m *testRecordImport) Configure(config map[string]interface{}) error {
+	*m.order = append(*m.order, m.name+" Configure")
+	return m.Import.Configure(config)
+}
+
+func (m *testRecordImport) Get(reqs []*sdk.GetReq) ([]*sdk.GetResult, error) {
+	*m.order = append(*m.order, m.name+" Get")
+	return m.Import.Get(reqs)
+}
@@ -1,8 +1,15 @@
 package rpc
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	protobuf "github.com/golang/protobuf/proto"
 	goplugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/sentinel-sdk"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
 // The constants below are the names of the plugins that can be dispensed
@@ -32,25 +39,144 @@ var PluginMap = map[string]goplugin.Plugin{
 
 type ImportFunc func() sdk.Import
 
+// ImportMiddleware wraps an import to add behavior around its methods,
+// such as logging or metrics, without changing the import itself. The
+// returned import should also implement io.Closer, sdk.Invalidator,
+// sdk.SchemaVersioner, sdk.FunctionLister and sdk.CapabilityNegotiator by
+// forwarding to the wrapped import if it does, or those features will be
+// unavailable for the wrapped import. See LoggingMiddleware for an example.
+type ImportMiddleware func(sdk.Import) sdk.Import
+
 // ServeOpts are the configurations to serve a plugin.
 type ServeOpts struct {
 	ImportFunc ImportFunc
+
+	// Init, if set, is called exactly once when the plugin starts, before
+	// it is served and so before any import is created or configured. It
+	// is the place for process-wide setup shared by every configuration,
+	// such as loading static data or warming caches, while Configure is
+	// for setup specific to a configuration. If Init returns an error,
+	// the plugin exits without completing the handshake with the host, so
+	// the host never sees it as ready and reports that it failed to start.
+	Init func() error
+
+	// Middleware wraps each import returned by ImportFunc. The first
+	// middleware is the outermost, so it is called first.
+	Middleware []ImportMiddleware
+
+	// MaxRecvMsgSize is the maximum size in bytes of a request the plugin
+	// will accept from the host. Larger requests are rejected with a
+	// ResourceExhausted error before they are decoded. If this is zero,
+	// the gRPC default of 4 MB is used.
+	MaxRecvMsgSize int
+
+	// MaxSendMsgSize is the maximum size in bytes of a response the plugin
+	// will send to the host. Larger responses fail with a ResourceExhausted
+	// error instead of being sent. If this is zero, there is no limit.
+	MaxSendMsgSize int
+
+	// Tap, if set, is called after every RPC from the host with the raw
+	// request and response messages, to debug the messages exchanged with
+	// the host. It is only installed if set, so it costs nothing otherwise.
+	Tap RPCTap
 }
 
+// RPCTap observes an RPC after it is handled. method is the name of the
+// RPC, such as "Get". resp is nil if err isn't. The messages must not be
+// modified, and the tap may be called concurrently for different RPCs.
+type RPCTap func(method string, req, resp protobuf.Message, err error)
+
 // Serve serves a plugin. This function never returns and should be the final
 // function called in the main function of the plugin.
 func Serve(opts *ServeOpts) {
+	if err := opts.init(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
 	goplugin.Serve(&goplugin.ServeConfig{
 		HandshakeConfig: Handshake,
 		Plugins:         pluginMap(opts),
-		GRPCServer:      goplugin.DefaultGRPCServer,
+		GRPCServer:      grpcServer(opts),
 	})
 }
 
+// init calls the Init function of opts, if set.
+func (opts *ServeOpts) init() error {
+	if opts.Init == nil {
+		return nil
+	}
+
+	if err := opts.Init(); err != nil {
+		return fmt.Errorf("error initializing plugin: %s", err)
+	}
+
+	return nil
+}
+
+// grpcServer returns the function to create the gRPC server for the
+// plugin, applying the message size limits in opts.
+func grpcServer(opts *ServeOpts) func([]grpc.ServerOption) *grpc.Server {
+	return func(serverOpts []grpc.ServerOption) *grpc.Server {
+		if opts.MaxRecvMsgSize > 0 {
+			serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(opts.MaxRecvMsgSize))
+		}
+		if opts.MaxSendMsgSize > 0 {
+			serverOpts = append(serverOpts, grpc.MaxSendMsgSize(opts.MaxSendMsgSize))
+		}
+		if opts.Tap != nil {
+			serverOpts = append(serverOpts, grpc.UnaryInterceptor(tapInterceptor(opts.Tap)))
+		}
+
+		return goplugin.DefaultGRPCServer(serverOpts)
+	}
+}
+
+// tapInterceptor returns a gRPC interceptor that calls tap after each RPC.
+func tapInterceptor(tap RPCTap) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+
+		// The method is the full name, such as "/proto.Import/Get"
+		method := info.FullMethod
+		if i := strings.LastIndex(method, "/"); i >= 0 {
+			method = method[i+1:]
+		}
+
+		reqMsg, _ := req.(protobuf.Message)
+		var respMsg protobuf.Message
+		if err == nil {
+			respMsg, _ = resp.(protobuf.Message)
+		}
+
+		tap(method, reqMsg, respMsg, err)
+		return resp, err
+	}
+}
+
 // pluginMap returns the map[string]goplugin.Plugin to use for configuring a plugin
 // server or client.
 func pluginMap(opts *ServeOpts) map[string]goplugin.Plugin {
 	return map[string]goplugin.Plugin{
-		ImportPluginName: &ImportPlugin{F: opts.ImportFunc},
+		ImportPluginName: &ImportPlugin{F: opts.importFunc()},
+	}
+}
+
+// importFunc returns the function to create an import with the middleware
+// applied.
+func (opts *ServeOpts) importFunc() ImportFunc {
+	if len(opts.Middleware) == 0 {
+		return opts.ImportFunc
+	}
+
+	return func() sdk.Import {
+		impt := opts.ImportFunc()
+		for i := len(opts.Middleware) - 1; i >= 0; i-- {
+			impt = opts.Middleware[i](impt)
+		}
+
+		return impt
 	}
 }
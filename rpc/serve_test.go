@@ -0,0 +1,184 @@
+package rpc
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestServe_maxRecvMsgSize(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	obj, closer := testImportServeOpts(t, &ServeOpts{
+		ImportFunc:     testImportFixed(importMock),
+		MaxRecvMsgSize: 1024,
+	})
+	defer closer()
+
+	// Small requests are accepted
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Large requests are rejected
+	_, err := obj.Get([]*sdk.GetReq{
+		&sdk.GetReq{
+			KeyId: 1,
+			Keys:  []string{strings.Repeat("a", 2048)},
+		},
+	})
+	if grpc.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestServe_maxSendMsgSize(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+	importMock.On("Get", mock.Anything).Return([]*sdk.GetResult{
+		&sdk.GetResult{
+			KeyId: 1,
+			Keys:  []string{"key"},
+			Value: strings.Repeat("a", 2048),
+		},
+	}, nil)
+
+	obj, closer := testImportServeOpts(t, &ServeOpts{
+		ImportFunc:     testImportFixed(importMock),
+		MaxSendMsgSize: 1024,
+	})
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err := obj.Get([]*sdk.GetReq{
+		&sdk.GetReq{
+			KeyId: 1,
+			Keys:  []string{"key"},
+		},
+	})
+	if grpc.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestServe_tap(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	type tapCall struct {
+		Method string
+		Req    protobuf.Message
+		Resp   protobuf.Message
+		Err    error
+	}
+
+	var lock sync.Mutex
+	var calls []tapCall
+	obj, closer := testImportServeOpts(t, &ServeOpts{
+		ImportFunc: testImportFixed(importMock),
+		Tap: func(method string, req, resp protobuf.Message, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			calls = append(calls, tapCall{method, req, resp, err})
+		},
+	})
+	defer closer()
+
+	if err := obj.Configure(nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Unknown instances are an error
+	client := obj.(*ImportGRPCClient)
+	client.instanceId = 42
+	if _, err := obj.Get([]*sdk.GetReq{{KeyId: 1, Keys: []string{"key"}}}); err == nil {
+		t.Fatal("should error")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("bad: %#v", calls)
+	}
+
+	if calls[0].Method != "Configure" || calls[0].Err != nil {
+		t.Fatalf("bad: %#v", calls[0])
+	}
+	if _, ok := calls[0].Req.(*proto.Configure_Request); !ok {
+		t.Fatalf("bad: %#v", calls[0].Req)
+	}
+	if resp, ok := calls[0].Resp.(*proto.Configure_Response); !ok || resp.InstanceId != 1 {
+		t.Fatalf("bad: %#v", calls[0].Resp)
+	}
+
+	if calls[1].Method != "Get" || calls[1].Err == nil || calls[1].Resp != nil {
+		t.Fatalf("bad: %#v", calls[1])
+	}
+	if req, ok := calls[1].Req.(*proto.Get_MultiRequest); !ok || req.Requests[0].InstanceId != 42 {
+		t.Fatalf("bad: %#v", calls[1].Req)
+	}
+}
+
+func TestServe_init(t *testing.T) {
+	// No Init does nothing
+	if err := (&ServeOpts{}).init(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	calls := 0
+	opts := &ServeOpts{Init: func() error {
+		calls++
+		return nil
+	}}
+	if err := opts.init(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+
+	opts = &ServeOpts{Init: func() error {
+		return errors.New("no data")
+	}}
+	err := opts.init()
+	if err == nil || err.Error() != "error initializing plugin: no data" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// testImportServeOpts serves an import over gRPC using a server created
+// the same way as Serve creates it.
+func testImportServeOpts(t *testing.T, opts *ServeOpts) (sdk.Import, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	server := grpcServer(opts)(nil)
+	proto.RegisterImportServer(server, &ImportGRPCServer{F: opts.importFunc()})
+	go server.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		server.Stop()
+		t.Fatalf("err: %s", err)
+	}
+
+	return &ImportGRPCClient{Client: proto.NewImportClient(conn)}, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
@@ -0,0 +1,231 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk/encoding"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+	"golang.org/x/net/context"
+)
+
+// stdioMaxRecvMsgSize is the default maximum size of a request message
+// read by ServeStdio, which is the same as the gRPC default.
+const stdioMaxRecvMsgSize = 4 << 20
+
+// ServeStdio serves the import in opts over stdin and stdout rather than
+// the go-plugin gRPC transport, so that an import can be driven by a shell
+// script or a simple test harness without the plugin handshake. Sentinel
+// itself can't use this; plugins it runs must call Serve.
+//
+// Every frame is a message length encoded as a protobuf varint followed by
+// the message. A request is two frames: the name of the method, which is
+// one of "Configure", "Get", "Close", "Invalidate" or "Functions", followed
+// by the request message for the method as defined in proto/import.proto.
+// Calls are Get requests with call set, as they are over gRPC. A response
+// is also two frames: an error message, followed by the response message
+// for the method. If the error message is empty, the request succeeded;
+// otherwise the response message is empty. Close and Invalidate respond
+// with an empty message. A Configure request without a config is
+// configured with an empty one.
+//
+// Requests are handled one at a time, in order. Init, Middleware and Tap
+// in opts are applied as they are by Serve. MaxRecvMsgSize limits the size
+// of request messages, defaulting to 4 MB; MaxSendMsgSize is ignored.
+//
+// A request whose message can't be decoded or whose method is unknown is
+// answered with an error message, and serving continues. ServeStdio returns
+// nil when stdin is closed between requests. It returns an error if Init
+// fails, if a frame is malformed, such as an invalid length, a message over
+// the maximum size or input that ends within a request, or if reading stdin
+// or writing stdout fails.
+func ServeStdio(opts *ServeOpts) error {
+	return ServeStream(opts, os.Stdin, os.Stdout)
+}
+
+// ServeStream is like ServeStdio, but reads requests from r and writes
+// responses to w.
+func ServeStream(opts *ServeOpts, r io.Reader, w io.Writer) error {
+	if err := opts.init(); err != nil {
+		return err
+	}
+
+	s := &streamServer{
+		server:  &ImportGRPCServer{F: opts.importFunc()},
+		r:       bufio.NewReader(r),
+		w:       bufio.NewWriter(w),
+		maxSize: stdioMaxRecvMsgSize,
+	}
+	if opts.MaxRecvMsgSize > 0 {
+		s.maxSize = opts.MaxRecvMsgSize
+	}
+
+	for {
+		method, err := s.readFrame()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading request: %s", err)
+		}
+
+		data, err := s.readFrame()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return fmt.Errorf("error reading request: %s", err)
+		}
+
+		req, resp, err := s.handle(string(method), data)
+		if err != nil {
+			resp = nil
+		}
+		if opts.Tap != nil {
+			opts.Tap(string(method), req, resp, err)
+		}
+		if err := s.writeResponse(resp, err); err != nil {
+			return fmt.Errorf("error writing response: %s", err)
+		}
+	}
+}
+
+// streamServer serves an import for ServeStream.
+type streamServer struct {
+	server  *ImportGRPCServer
+	r       *bufio.Reader
+	w       *bufio.Writer
+	maxSize int
+}
+
+// handle decodes the request message in data for method and calls the
+// method on the server. It returns the decoded request, which is nil if
+// it couldn't be decoded, and the response.
+func (s *streamServer) handle(method string, data []byte) (protobuf.Message, protobuf.Message, error) {
+	ctx := context.Background()
+	switch method {
+	case "Configure":
+		req := new(proto.Configure_Request)
+		if err := protobuf.Unmarshal(data, req); err != nil {
+			return nil, nil, fmt.Errorf("error decoding request: %s", err)
+		}
+
+		// The host always sends a config, but a request written by hand
+		// may leave it out to mean an empty one.
+		if req.Config == nil {
+			config, err := encoding.GoToValue(map[string]interface{}{})
+			if err != nil {
+				return nil, nil, err
+			}
+
+			req.Config = config
+		}
+
+		resp, err := s.server.Configure(ctx, req)
+		return req, resp, err
+
+	case "Get":
+		req := new(proto.Get_MultiRequest)
+		if err := protobuf.Unmarshal(data, req); err != nil {
+			return nil, nil, fmt.Errorf("error decoding request: %s", err)
+		}
+
+		resp, err := s.server.Get(ctx, req)
+		return req, resp, err
+
+	case "Close":
+		req := new(proto.Close_Request)
+		if err := protobuf.Unmarshal(data, req); err != nil {
+			return nil, nil, fmt.Errorf("error decoding request: %s", err)
+		}
+
+		resp, err := s.server.Close(ctx, req)
+		return req, resp, err
+
+	case "Invalidate":
+		req := new(proto.Invalidate_Request)
+		if err := protobuf.Unmarshal(data, req); err != nil {
+			return nil, nil, fmt.Errorf("error decoding request: %s", err)
+		}
+
+		resp, err := s.server.Invalidate(ctx, req)
+		return req, resp, err
+
+	case "Functions":
+		req := new(proto.Functions_Request)
+		if err := protobuf.Unmarshal(data, req); err != nil {
+			return nil, nil, fmt.Errorf("error decoding request: %s", err)
+		}
+
+		resp, err := s.server.Functions(ctx, req)
+		return req, resp, err
+
+	default:
+		return nil, nil, fmt.Errorf("unknown method: %q", method)
+	}
+}
+
+// readFrame reads a single frame. It returns io.EOF only if there is no
+// data before the end of the input.
+func (s *streamServer) readFrame() ([]byte, error) {
+	n, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(s.maxSize) {
+		return nil, fmt.Errorf(
+			"message of %d bytes exceeds maximum size of %d", n, s.maxSize)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// writeResponse writes the response frames for the result of a request
+// and flushes them.
+func (s *streamServer) writeResponse(resp protobuf.Message, err error) error {
+	var errMsg string
+	var data []byte
+	if err != nil {
+		errMsg = err.Error()
+	} else {
+		data, err = protobuf.Marshal(resp)
+		if err != nil {
+			errMsg = fmt.Sprintf("error encoding response: %s", err)
+			data = nil
+		}
+	}
+
+	if err := s.writeFrame([]byte(errMsg)); err != nil {
+		return err
+	}
+	if err := s.writeFrame(data); err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
+
+// writeFrame writes a single frame.
+func (s *streamServer) writeFrame(data []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	if _, err := s.w.Write(buf[:n]); err != nil {
+		return err
+	}
+
+	_, err := s.w.Write(data)
+	return err
+}
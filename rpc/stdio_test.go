@@ -0,0 +1,215 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/sentinel-sdk"
+	"github.com/hashicorp/sentinel-sdk/encoding"
+	"github.com/hashicorp/sentinel-sdk/proto/go"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServeStream(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+	importMock.On("Get", mock.MatchedBy(func(reqs []*sdk.GetReq) bool {
+		return len(reqs) == 1 && reqs[0].Call()
+	})).Return([]*sdk.GetResult{
+		&sdk.GetResult{
+			KeyId: 2,
+			Keys:  []string{"add"},
+			Value: int64(3),
+		},
+	}, nil)
+	importMock.On("Get", mock.Anything).Return([]*sdk.GetResult{
+		&sdk.GetResult{
+			KeyId: 1,
+			Keys:  []string{"key"},
+			Value: "value",
+		},
+	}, nil)
+
+	one, err := encoding.GoToValue(1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	two, err := encoding.GoToValue(2)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var in bytes.Buffer
+	testWriteRequest(t, &in, "Configure", &proto.Configure_Request{})
+	testWriteRequest(t, &in, "Get", &proto.Get_MultiRequest{
+		Requests: []*proto.Get_Request{
+			{InstanceId: 1, KeyId: 1, Keys: []string{"key"}},
+		},
+	})
+	testWriteRequest(t, &in, "Get", &proto.Get_MultiRequest{
+		Requests: []*proto.Get_Request{
+			{
+				InstanceId: 1,
+				KeyId:      2,
+				Keys:       []string{"add"},
+				Call:       true,
+				Args:       []*proto.Value{one, two},
+			},
+		},
+	})
+	testWriteRequest(t, &in, "Get", &proto.Get_MultiRequest{
+		Requests: []*proto.Get_Request{{InstanceId: 2}},
+	})
+	testWriteRequest(t, &in, "Nope", &proto.Empty{})
+	testWriteRequest(t, &in, "Close", &proto.Close_Request{InstanceId: 1})
+
+	var out bytes.Buffer
+	err = ServeStream(&ServeOpts{ImportFunc: testImportFixed(importMock)}, &in, &out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := bufio.NewReader(&out)
+
+	var configResp proto.Configure_Response
+	testReadResponse(t, r, &configResp)
+	if configResp.InstanceId != 1 {
+		t.Fatalf("bad: %#v", configResp)
+	}
+
+	var getResp proto.Get_MultiResponse
+	testReadResponse(t, r, &getResp)
+	if len(getResp.Responses) != 1 || getResp.Responses[0].Value.GetValueString() != "value" {
+		t.Fatalf("bad: %#v", getResp)
+	}
+
+	testReadResponse(t, r, &getResp)
+	if len(getResp.Responses) != 1 || getResp.Responses[0].Value.GetValueInt() != 3 {
+		t.Fatalf("bad: %#v", getResp)
+	}
+
+	if msg := testReadError(t, r); msg == "" {
+		t.Fatal("unknown instance should error")
+	}
+	if msg := testReadError(t, r); msg != `unknown method: "Nope"` {
+		t.Fatalf("bad: %s", msg)
+	}
+
+	testReadResponse(t, r, &proto.Empty{})
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Fatal("should be no more responses")
+	}
+}
+
+func TestServeStream_tap(t *testing.T) {
+	importMock := new(sdk.MockImport)
+	importMock.On("Configure", map[string]interface{}{}).Return(nil)
+
+	var in bytes.Buffer
+	testWriteRequest(t, &in, "Configure", &proto.Configure_Request{})
+	testWriteRequest(t, &in, "Nope", &proto.Empty{})
+
+	var methods []string
+	opts := &ServeOpts{
+		ImportFunc: testImportFixed(importMock),
+		Tap: func(method string, req, resp protobuf.Message, err error) {
+			methods = append(methods, method)
+			if (err == nil) != (req != nil && resp != nil) {
+				t.Fatalf("bad: %s %#v %#v %v", method, req, resp, err)
+			}
+		},
+	}
+	if err := ServeStream(opts, &in, &bytes.Buffer{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(methods) != 2 || methods[0] != "Configure" || methods[1] != "Nope" {
+		t.Fatalf("bad: %#v", methods)
+	}
+}
+
+func TestServeStream_init(t *testing.T) {
+	err := ServeStream(&ServeOpts{Init: func() error {
+		return errors.New("no data")
+	}}, &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || err.Error() != "error initializing plugin: no data" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestServeStream_malformed(t *testing.T) {
+	cases := map[string][]byte{
+		"truncated method":  {0x05, 'G', 'e'},
+		"missing message":   {0x03, 'G', 'e', 't'},
+		"truncated message": {0x03, 'G', 'e', 't', 0x02, 0x00},
+		"too large":         {0x03, 'G', 'e', 't', 0xff, 0xff, 0x7f},
+	}
+
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			opts := &ServeOpts{
+				ImportFunc:     testImportFixed(new(sdk.MockImport)),
+				MaxRecvMsgSize: 1024,
+			}
+			if err := ServeStream(opts, bytes.NewReader(in), &bytes.Buffer{}); err == nil {
+				t.Fatal("should error")
+			}
+		})
+	}
+}
+
+// testWriteRequest writes a request for ServeStream to w.
+func testWriteRequest(t *testing.T, w io.Writer, method string, msg protobuf.Message) {
+	data, err := protobuf.Marshal(msg)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, frame := range [][]byte{[]byte(method), data} {
+		var buf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(buf[:], uint64(len(frame)))
+		w.Write(buf[:n])
+		w.Write(frame)
+	}
+}
+
+// testReadFrame reads a single frame written by ServeStream.
+func testReadFrame(t *testing.T, r *bufio.Reader) []byte {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return data
+}
+
+// testReadResponse reads a successful response into msg.
+func testReadResponse(t *testing.T, r *bufio.Reader, msg protobuf.Message) {
+	if errMsg := testReadFrame(t, r); len(errMsg) > 0 {
+		t.Fatalf("err: %s", errMsg)
+	}
+
+	if err := protobuf.Unmarshal(testReadFrame(t, r), msg); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// testReadError reads a response and returns its error message.
+func testReadError(t *testing.T, r *bufio.Reader) string {
+	errMsg := testReadFrame(t, r)
+	if data := testReadFrame(t, r); len(data) > 0 {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	return string(errMsg)
+}